@@ -0,0 +1,260 @@
+package sequence
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+)
+
+//The types of alert events.
+const (
+	AlertNewPattern = "new_pattern"
+	AlertRateSpike  = "rate_spike"
+)
+
+//An alert raised by a batch, sent as a JSON object to the sinks in the alerting config.
+//For a rate spike the count is the matches of the pattern in the current interval and
+//the baseline is the average matches of the intervals it was matched in before it.
+type AlertEvent struct {
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	Service   string    `json:"service"`
+	PatternId string    `json:"pattern_id"`
+	Pattern   string    `json:"pattern"`
+	Count     int64     `json:"count"`
+	Batch     int64     `json:"batch"`
+	Baseline  float64   `json:"baseline,omitempty"`
+	Example   string    `json:"example,omitempty"`
+}
+
+var alertClient = &http.Client{}
+
+//the time allowed to post all the alerts of a batch to the webhook, so a slow webhook
+//does not hold up the saving of the batch for each of its alerts
+var alertTimeout = 10 * time.Second
+
+func validateAlertingConfig() error {
+	if alerting.newPatternCount < 0 {
+		return fmt.Errorf("the alerting newPatternCount must be 0 or more, not %d", alerting.newPatternCount)
+	}
+	if alerting.spikeFactor != 0 && alerting.spikeFactor <= 1 {
+		return fmt.Errorf("the alerting spikeFactor must be 0 to turn it off or greater than 1, not %v", alerting.spikeFactor)
+	}
+	if alerting.spikeHistory < 0 {
+		return fmt.Errorf("the alerting spikeHistory must be 1 or more intervals, not %d", alerting.spikeHistory)
+	}
+	if alerting.webhook != "" {
+		u, err := url.Parse(alerting.webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("the alerting webhook must be an http or https url, not %s", alerting.webhook)
+		}
+	}
+	return nil
+}
+
+//Returns true if alerting has a sink and at least one of the checks turned on.
+func AlertingEnabled() bool {
+	return (alerting.file != "" || alerting.webhook != "" || alerting.socket != "") && (alerting.newPatternCount > 0 || alerting.spikeFactor > 0)
+}
+
+//Returns an event for each result that is not in the known patterns and matched at
+//least the threshold of messages in the batch.
+func newPatternEvents(amap map[string]AnalyzerResult, known map[string]string, threshold int, now time.Time) []AlertEvent {
+	var events []AlertEvent
+	if threshold <= 0 {
+		return events
+	}
+	for _, r := range amap {
+		if _, ok := known[r.PatternId]; ok || r.ExampleCount < threshold {
+			continue
+		}
+		ev := AlertEvent{Type: AlertNewPattern, Time: now, Service: r.Service.Name, PatternId: r.PatternId, Pattern: r.Pattern,
+			Count: int64(r.ExampleCount), Batch: int64(r.ExampleCount)}
+		if len(r.Examples) > 0 {
+			ev.Example = r.Examples[0].Message
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+//Returns true if the count of the interval has reached the factor times the baseline
+//with this batch, so the spike is only raised by the batch that crosses the threshold.
+func isSpike(count int64, batch int64, baseline float64, factor float64, minCount int64) bool {
+	if baseline <= 0 || factor <= 0 || count < minCount {
+		return false
+	}
+	limit := factor * baseline
+	return float64(count) >= limit && float64(count-batch) < limit
+}
+
+//Returns the average match count of each pattern for the intervals it was matched in,
+//for the intervals that start from the start time and before the end time.
+func getMatchCountBaselines(db *sql.DB, ctx context.Context, from time.Time, to time.Time) (map[string]float64, error) {
+	rows, err := db.QueryContext(ctx, rebind(`SELECT pattern_id, SUM(match_count), COUNT(*) FROM "PatternMatchCounts" WHERE interval_start >= ? AND interval_start < ? GROUP BY pattern_id`), from, to)
+	if err != nil {
+		logger.DatabaseSelectFailed("pattern match counts", "interval_start", err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+	baselines := make(map[string]float64)
+	for rows.Next() {
+		var (
+			id         string
+			sum, count int64
+		)
+		if err = rows.Scan(&id, &sum, &count); err != nil {
+			logger.DatabaseSelectFailed("pattern match counts", "interval_start", err.Error())
+			return nil, err
+		}
+		if count > 0 {
+			baselines[id] = float64(sum) / float64(count)
+		}
+	}
+	return baselines, rows.Err()
+}
+
+//Returns the alerts for a batch, it is called after the matched patterns (pmap) are
+//saved and before the new patterns (amap) are saved. A result of amap is new when its
+//pattern is not in the database, and a pattern of pmap spikes when its count for the
+//current interval reaches spikeFactor times its baseline over the spikeHistory intervals
+//before. Without the database all the results of amap are new and there are no spikes.
+func DetectAlerts(amap map[string]AnalyzerResult, pmap map[string]AnalyzerResult) []AlertEvent {
+	if !AlertingEnabled() {
+		return nil
+	}
+	now := time.Now()
+	if !config.useDatabase {
+		return newPatternEvents(amap, nil, alerting.newPatternCount, now)
+	}
+	db, ctx := OpenDbandSetContext()
+	defer db.Close()
+	events := newPatternEvents(amap, getPatternsFromDatabase(db, ctx), alerting.newPatternCount, now)
+	if alerting.spikeFactor <= 0 || len(pmap) == 0 {
+		return events
+	}
+	start := intervalStart(now, config.matchCountInterval)
+	current, err := getMatchCounts(db, ctx, start, nextIntervalStart(now, config.matchCountInterval))
+	if err != nil {
+		return events
+	}
+	histStart := start
+	for i := 0; i < alerting.spikeHistory; i++ {
+		histStart = intervalStart(histStart.Add(-time.Second), config.matchCountInterval)
+	}
+	baselines, err := getMatchCountBaselines(db, ctx, histStart, start)
+	if err != nil {
+		return events
+	}
+	for _, r := range pmap {
+		if !isSpike(current[r.PatternId], int64(r.ExampleCount), baselines[r.PatternId], alerting.spikeFactor, alerting.spikeMinCount) {
+			continue
+		}
+		ev := AlertEvent{Type: AlertRateSpike, Time: now, Service: r.Service.Name, PatternId: r.PatternId, Pattern: r.Pattern,
+			Count: current[r.PatternId], Batch: int64(r.ExampleCount), Baseline: baselines[r.PatternId]}
+		if len(r.Examples) > 0 {
+			ev.Example = r.Examples[0].Message
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+//Sends the events to each of the sinks in the alerting config, the events are written
+//as JSON lines to the file and the Unix socket and posted one at a time to the webhook
+//within alertTimeout for the whole batch. A failed sink is logged and does not stop the others.
+func SendAlerts(events []AlertEvent) {
+	if len(events) == 0 {
+		return
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Type != events[j].Type {
+			return events[i].Type < events[j].Type
+		}
+		return events[i].Count > events[j].Count
+	})
+	var lines bytes.Buffer
+	enc := json.NewEncoder(&lines)
+	for _, ev := range events {
+		if err := enc.Encode(ev); err != nil {
+			logger.HandleError(fmt.Sprintf("Unable to encode the alert for the pattern %s: %s", ev.PatternId, err.Error()))
+			return
+		}
+	}
+	if alerting.file != "" {
+		if err := writeAlertFile(alerting.file, lines.Bytes()); err != nil {
+			logger.HandleError(fmt.Sprintf("Unable to write the alerts to %s: %s", alerting.file, err.Error()))
+		}
+	}
+	if alerting.socket != "" {
+		if err := writeAlertSocket(alerting.socket, lines.Bytes()); err != nil {
+			logger.HandleError(fmt.Sprintf("Unable to send the alerts to the socket %s: %s", alerting.socket, err.Error()))
+		}
+	}
+	if alerting.webhook != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+		defer cancel()
+		for i, ev := range events {
+			if ctx.Err() != nil {
+				logger.HandleError(fmt.Sprintf("Unable to post %d alerts to the webhook, it took longer than %s", len(events)-i, alertTimeout))
+				break
+			}
+			if err := postAlert(ctx, alerting.webhook, ev); err != nil {
+				logger.HandleError(fmt.Sprintf("Unable to post the alert for the pattern %s to the webhook: %s", ev.PatternId, err.Error()))
+			}
+		}
+	}
+	logger.HandleInfo(fmt.Sprintf("Sent %d alerts.", len(events)))
+}
+
+func writeAlertFile(path string, lines []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(lines); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeAlertSocket(path string, lines []byte) error {
+	conn, err := net.DialTimeout("unix", path, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Write(lines)
+	return err
+}
+
+func postAlert(ctx context.Context, webhook string, ev AlertEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := alertClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("the webhook returned %s", resp.Status)
+	}
+	return nil
+}
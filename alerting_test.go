@@ -0,0 +1,141 @@
+package sequence
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPatternEvents(t *testing.T) {
+	now := time.Now()
+	amap := map[string]AnalyzerResult{
+		"a": {PatternId: "a", Pattern: "error %string%", ExampleCount: 12, Service: models.Service{Name: "app"}, Examples: []LogRecord{{Message: "error disk"}}},
+		"b": {PatternId: "b", Pattern: "ok %string%", ExampleCount: 2, Service: models.Service{Name: "app"}},
+		"c": {PatternId: "c", Pattern: "known %string%", ExampleCount: 50, Service: models.Service{Name: "app"}},
+	}
+	events := newPatternEvents(amap, map[string]string{"c": "app"}, 5, now)
+	require.Len(t, events, 1)
+	require.Equal(t, AlertEvent{Type: AlertNewPattern, Time: now, Service: "app", PatternId: "a", Pattern: "error %string%", Count: 12, Batch: 12, Example: "error disk"}, events[0])
+	require.Empty(t, newPatternEvents(amap, nil, 0, now))
+}
+
+func TestIsSpike(t *testing.T) {
+	tests := []struct {
+		count, batch int64
+		baseline     float64
+		spike        bool
+	}{
+		{100, 60, 10, true},
+		{100, 100, 10, true},
+		{150, 40, 10, false}, //the threshold was crossed by an earlier batch
+		{90, 50, 10, false},
+		{100, 60, 0, false}, //no history
+		{8, 8, 0.5, false},  //below the minimum count
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.spike, isSpike(tc.count, tc.batch, tc.baseline, 10, 10), "%d %d %v", tc.count, tc.batch, tc.baseline)
+	}
+}
+
+func TestSendAlerts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alerts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	posted := make(chan AlertEvent, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev AlertEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&ev))
+		posted <- ev
+	}))
+	defer srv.Close()
+
+	sock := filepath.Join(dir, "alerts.sock")
+	l, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	defer l.Close()
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var lines []string
+		s := bufio.NewScanner(conn)
+		for s.Scan() {
+			lines = append(lines, s.Text())
+		}
+		received <- lines
+	}()
+
+	saved := alerting
+	defer func() { alerting = saved }()
+	alerting.file = filepath.Join(dir, "alerts.jsonl")
+	alerting.webhook = srv.URL
+	alerting.socket = sock
+	SetLogger(NewLogger(filepath.Join(dir, "log.txt"), "error"))
+
+	events := []AlertEvent{
+		{Type: AlertRateSpike, Service: "app", PatternId: "b", Count: 100, Baseline: 5},
+		{Type: AlertNewPattern, Service: "app", PatternId: "a", Count: 12},
+	}
+	SendAlerts(events)
+
+	data, err := ioutil.ReadFile(alerting.file)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var ev AlertEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &ev))
+	require.Equal(t, AlertNewPattern, ev.Type)
+	require.Equal(t, "a", ev.PatternId)
+
+	require.Equal(t, lines, <-received)
+	require.Equal(t, "a", (<-posted).PatternId)
+	require.Equal(t, "b", (<-posted).PatternId)
+}
+
+func TestSendAlertsWebhookDeadline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alerts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	saved, savedTimeout := alerting, alertTimeout
+	defer func() { alerting, alertTimeout = saved, savedTimeout }()
+	alerting.file, alerting.socket = "", ""
+	alerting.webhook = srv.URL
+	alertTimeout = 200 * time.Millisecond
+	SetLogger(NewLogger(filepath.Join(dir, "log.txt"), "error"))
+
+	var events []AlertEvent
+	for i := 0; i < 5; i++ {
+		events = append(events, AlertEvent{Type: AlertNewPattern, Service: "app", PatternId: strconv.Itoa(i), Count: 12})
+	}
+	//the slow webhook holds up the batch for the one deadline, not for each alert
+	start := time.Now()
+	SendAlerts(events)
+	require.Less(t, time.Since(start), time.Second)
+}
@@ -78,9 +78,29 @@ type Analyzer struct {
 	litmaps   []map[string]int
 	nodeCount []int
 
+	// finalized is set once Finalize has been called, after that sequences can
+	// be absorbed into the tree with Update.
+	finalized bool
+
+	// dirty tracks the levels changed by Add since the last merge, so Update only
+	// needs to merge and compact those levels.
+	dirty map[int]bool
+
 	mu sync.RWMutex
 }
 
+// PatternChangeEvent is returned by Update when absorbing a new sequence into a
+// finalized tree turns a literal into a variable, which means any pattern that
+// contained the literal at that position has changed.
+type PatternChangeEvent struct {
+	// Level is the position of the token in the sequence
+	Level int
+	// Literal is the value that was previously part of the pattern
+	Literal string
+	// Merged are the other literals that share a parent and child with it
+	Merged []string
+}
+
 // pattern to be used as the starting block for
 // all conversions
 type AnalyzerResult struct {
@@ -93,6 +113,10 @@ type AnalyzerResult struct {
 	DateCreated     time.Time
 	DateLastMatched time.Time
 	ComplexityScore float64
+	//field name overrides keyed by the tag position in the pattern
+	FieldNames map[int]string
+	//the scanned values of the examples keyed by the message, for the variety strategy
+	exampleValues map[string][]string
 }
 
 type analyzerNode struct {
@@ -145,25 +169,6 @@ func SplitToInt(s string, sep string) []int {
 	return b
 }
 
-// Tests the passed log record to see if it should be added to the examples collection for the Analyzer Result.
-func AddExampleToAnalyzerResult(this *AnalyzerResult, lr LogRecord) {
-	if len(this.Examples) >= 3 {
-		//nothing to here
-		return
-	}
-	var found = false
-	//check it is unique and add, else don't
-	for _, ex := range this.Examples {
-		if lr.Message == ex.Message {
-			found = true
-			break
-		}
-	}
-	if !found {
-		this.Examples = append(this.Examples, lr)
-	}
-}
-
 // This ensures that the same pattern will always have the same id, returns a sha1 hash of the pattern + service name.
 func GenerateIDFromString(pattern string, service string) string {
 	h := sha1.New()
@@ -213,6 +218,11 @@ func CalculatePatternComplexity(seq Sequence, lgt int) float64 {
 	return 0.05
 }
 
+// Returns the least number of messages a new pattern needs to be saved to the database.
+func GetSaveThreshold() int {
+	return getSaveThreshold()
+}
+
 // The save threshold prevents messages with a low number of examples from saving to the database
 // as they are likely to be poorly formed.
 func getSaveThreshold() int {
@@ -231,8 +241,9 @@ func (this *stackAnalyzerNode) String() string {
 
 func NewAnalyzer() *Analyzer {
 	tree := &Analyzer{
-		root: newAnalyzerNode(),
-		leaf: newAnalyzerNode(),
+		root:  newAnalyzerNode(),
+		leaf:  newAnalyzerNode(),
+		dirty: make(map[int]bool),
 	}
 
 	tree.root.level = -1
@@ -349,16 +360,11 @@ func (this *Analyzer) Add(seq Sequence) error {
 		case token.Tag == TagUnknown && token.Type == TokenLiteral:
 			// if the tag type is unknown, and the token type is literal or plain text, that
 			// means this is some type of string we parsed from the message.
-			//if we are marking spaces then " literal" and "literal" need to be stored as different tokens
-			space := ""
-			if token.IsSpaceBefore {
-				space = " "
-			}
 			// If we have gotten here, it means we found a string that we cannot
 			// determine if it's a fixed literal, or a changing variable. So we have
 			// to keep this in the literal map to track it.
 			// If we have seen this literal before, then there's already a node
-			if j, ok := this.litmaps[i][space+token.Value]; ok {
+			if j, ok := this.litmaps[i][literalKey(token.Value, token.IsSpaceBefore)]; ok {
 				foundNode = this.levels[i][j]
 			} else {
 				// Otherwise we create a new node for this first time literal,
@@ -373,7 +379,7 @@ func (this *Analyzer) Add(seq Sequence) error {
 				foundNode.Tag = TagUnknown
 				//when adding to this map we must add the space before if it is marked true,
 				// or we get incorrect patterns
-				this.litmaps[i][space+foundNode.Value] = foundNode.index
+				this.litmaps[i][literalKey(foundNode.Value, token.IsSpaceBefore)] = foundNode.index
 				foundNode.isKey = token.isKey
 				foundNode.isSpaceBefore = token.IsSpaceBefore
 			}
@@ -383,6 +389,14 @@ func (this *Analyzer) Add(seq Sequence) error {
 		// we set the parent bit for the index of the current node, and set the
 		// child bit for the index of the parent node.
 		if parent != nil {
+			// Any new relationship can change which nodes share a parent and a
+			// child, so the levels on both sides of it need to be merged again.
+			if !foundNode.parents.Test(uint(parent.index)) {
+				this.dirty[i] = true
+			}
+			if i > 0 && !parent.children.Test(uint(foundNode.index)) {
+				this.dirty[i-1] = true
+			}
 			foundNode.parents.Set(uint(parent.index))
 			parent.children.Set(uint(foundNode.index))
 		}
@@ -392,6 +406,9 @@ func (this *Analyzer) Add(seq Sequence) error {
 
 	// If we are finished with all the tokens, then the current parent node is the
 	// last node we created, which means it's a leaf node.
+	if !parent.leaf && len(seq) > 0 {
+		this.dirty[len(seq)-1] = true
+	}
 	parent.leaf = true
 
 	// We set the 0th bit of the children bitset ...
@@ -412,124 +429,237 @@ func (this *Analyzer) Finalize() error {
 		return err
 	}
 
-	return this.compact()
+	if err := this.compact(); err != nil {
+		return err
+	}
+
+	this.finalized = true
+	this.dirty = make(map[int]bool)
+
+	return nil
+}
+
+// Update absorbs a single message sequence into a tree that has already been
+// finalized, then merges and compacts only the levels that the sequence changed.
+// A PatternChangeEvent is returned for each literal of the existing tree that
+// became a variable as a result. If the tree has not been finalized yet, the
+// sequence is added and the whole tree is finalized.
+func (this *Analyzer) Update(seq Sequence) ([]PatternChangeEvent, error) {
+	if err := this.Add(seq); err != nil {
+		return nil, err
+	}
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	if !this.finalized {
+		if err := this.merge(); err != nil {
+			return nil, err
+		}
+		if err := this.compact(); err != nil {
+			return nil, err
+		}
+		this.finalized = true
+		this.dirty = make(map[int]bool)
+		return nil, nil
+	}
+
+	var (
+		events  []PatternChangeEvent
+		changed []int
+	)
+
+	// Levels are merged top down, as a merge on one level changes the parents of
+	// the level below, that level has to be checked as well.
+	for i := 0; i < len(this.levels); i++ {
+		if !this.dirty[i] {
+			continue
+		}
+		merged, ev, err := this.mergeLevel(i)
+		if err != nil {
+			return nil, err
+		}
+		if merged && i < len(this.levels)-1 {
+			this.dirty[i+1] = true
+		}
+		events = append(events, ev...)
+		changed = append(changed, i)
+	}
+
+	for _, i := range changed {
+		this.compactLevel(i)
+	}
+
+	this.dirty = make(map[int]bool)
+
+	return events, nil
 }
 
 // merge merges trie[i][k] into trie[i][j] and updates all parents and children
 // appropriately
 func (this *Analyzer) merge() error {
 	// For every level of this tree ...
-	for i, level := range this.levels {
-		// And for every literal child of this level ...
-		// remember literal children starts after all the types, thus j := allTypesCount
-		for j := allTypesCount; j < len(level); j++ {
-			cur := level[j]
-
-			// - If the node is nil, then most likely it's been merged, so let's move on.
-			// - If the node is a key (isKey == true), then it's a literal that shouldn't
-			//   be merged, so let's move on.
-			// - If the node is a single character literal, and it's not a character in
-			//   a-zA-Z, then it shouldn't be merged, so let's move on.
-			if cur == nil || cur.isKey ||
-				(cur.Type == TokenLiteral && len(cur.Value) == 1 &&
-					!((cur.Value[0] >= 'a' && cur.Value[0] <= 'z') || (cur.Value[0] >= 'A' && cur.Value[0] <= 'Z'))) {
-				continue
-			}
+	for i := range this.levels {
+		if _, _, err := this.mergeLevel(i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mergeLevel merges the nodes of a single level that share at least 1 parent and
+// 1 child. It returns whether any nodes were merged, and if the tree was already
+// finalized, an event for each literal that became a variable.
+func (this *Analyzer) mergeLevel(i int) (bool, []PatternChangeEvent, error) {
+	var (
+		level  = this.levels[i]
+		merged bool
+		events []PatternChangeEvent
+
+		// redirect keeps track of which node each merged node went into
+		redirect = make(map[int]int)
+	)
+
+	// And for every literal child of this level ...
+	// remember literal children starts after all the types, thus j := allTypesCount
+	for j := allTypesCount; j < len(level); j++ {
+		cur := level[j]
+
+		// - If the node is nil, then most likely it's been merged, so let's move on.
+		// - If the node is a key (isKey == true), then it's a literal that shouldn't
+		//   be merged, so let's move on.
+		// - If the node is a single character literal, and it's not a character in
+		//   a-zA-Z, then it shouldn't be merged, so let's move on.
+		if cur == nil || cur.isKey ||
+			(cur.Type == TokenLiteral && len(cur.Value) == 1 &&
+				!((cur.Value[0] >= 'a' && cur.Value[0] <= 'z') || (cur.Value[0] >= 'A' && cur.Value[0] <= 'Z'))) {
+			continue
+		}
+
+		// Finds the nodes that share at least 1 parent and 1 child with trie[i][j]
+		// These will be the nodes that get merged into j
+		mergeSet, err := this.getMergeSet(i, j, cur)
+		if err != nil {
+			return merged, events, err
+		}
 
-			// Finds the nodes that share at least 1 parent and 1 child with trie[i][j]
-			// These will be the nodes that get merged into j
-			mergeSet, err := this.getMergeSet(i, j, cur)
-			if err != nil {
-				return err
+		// if the number of nodes share at least 1 parent and 1 child is only 1, then
+		// it means it's only the current node left. In other words, no other nodes share
+		// at least 1 parent and 1 child with the current node. If so, move on.
+		if mergeSet.Count() > 1 {
+			// Otherwise, we want to merge the nodes that are in the mergeSet
+			merged = true
+
+			// Only a literal that was part of the finalized tree changes a pattern,
+			// new literals merging into an existing string do not.
+			var event *PatternChangeEvent
+			if this.finalized && cur.Type == TokenLiteral {
+				event = &PatternChangeEvent{Level: i, Literal: cur.Value}
 			}
 
-			// if the number of nodes share at least 1 parent and 1 child is only 1, then
-			// it means it's only the current node left. In other words, no other nodes share
-			// at least 1 parent and 1 child with the current node. If so, move on.
-			if mergeSet.Count() > 1 {
-				// Otherwise, we want to merge the nodes that are in the mergeSet
+			// parents is the new parent bitset after the merging of all relevant nodes
+			parents := cur.parents
 
-				// parents is the new parent bitset after the merging of all relevant nodes
-				parents := cur.parents
+			// children is the new children bitset after merging all relevant nodes
+			children := cur.children
 
-				// children is the new children bitset after merging all relevant nodes
-				children := cur.children
+			leaf := cur.leaf
 
-				leaf := cur.leaf
+			// For every node aside from the current node, let's merge their info
+			// into the current node (cur)
+			//
+			// Check to see if the kth bit is set, if so, then we merge the kth node
+			// into current node
 
-				// For every node aside from the current node, let's merge their info
-				// into the current node (cur)
-				//
-				// Check to see if the kth bit is set, if so, then we merge the kth node
-				// into current node
+			for k, e := mergeSet.NextSet(uint(j) + 1); e; k, e = mergeSet.NextSet(uint(k) + 1) {
 
-				for k, e := mergeSet.NextSet(uint(j) + 1); e; k, e = mergeSet.NextSet(uint(k) + 1) {
+				// A variable from an earlier merge has no value, it is not a literal of
+				// a pattern
+				if event != nil && level[k].Type == TokenLiteral && level[k].Value != "" {
+					event.Merged = append(event.Merged, level[k].Value)
+				}
 
-					// The parents of the final merged node is the combination of all
-					// parents from all the merge nodes
-					parents.InPlaceUnion(level[k].parents)
+				// The parents of the final merged node is the combination of all
+				// parents from all the merge nodes
+				parents.InPlaceUnion(level[k].parents)
 
-					// The children of the final merged node is the combination of all
-					// children from all the merge nodes
-					children.InPlaceUnion(level[k].children)
+				// The children of the final merged node is the combination of all
+				// children from all the merge nodes
+				children.InPlaceUnion(level[k].children)
 
-					if leaf || level[k].leaf {
-						leaf = true
-					}
+				if leaf || level[k].leaf {
+					leaf = true
+				}
 
-					// Once we merge the parent and children bitset, we need to make sure
-					// all the parents of the merged node no longer points to the merged
-					// node, so we go through each parent and clear the kth child bit
-					//
-					// Make sure we are not at the top level since there's no more levels
-					// above it
-					if i > 0 {
-						plen := int(level[k].parents.Len())
-
-						for l := 0; l < plen; l++ {
-							// For each of the set parent bit of the kth node, we clear
-							// the kth child bit in the parent's children bitset
-							//
-							// Also, we set the parent's jth child bit since the parent
-							// needs to point to the new merged node
-							if level[k].parents.Test(uint(l)) {
-								this.levels[i-1][l].children.Clear(uint(k))
-								this.levels[i-1][l].children.Set(uint(j))
-							}
+				// Once we merge the parent and children bitset, we need to make sure
+				// all the parents of the merged node no longer points to the merged
+				// node, so we go through each parent and clear the kth child bit
+				//
+				// Make sure we are not at the top level since there's no more levels
+				// above it
+				if i > 0 {
+					plen := int(level[k].parents.Len())
+
+					for l := 0; l < plen; l++ {
+						// For each of the set parent bit of the kth node, we clear
+						// the kth child bit in the parent's children bitset
+						//
+						// Also, we set the parent's jth child bit since the parent
+						// needs to point to the new merged node
+						if level[k].parents.Test(uint(l)) {
+							this.levels[i-1][l].children.Clear(uint(k))
+							this.levels[i-1][l].children.Set(uint(j))
 						}
 					}
+				}
 
-					// Same for all the children of the merged node. For each of the
-					// children, we clear the kth parent bit
-					//
-					// Make sure we are not at the bottom level since there's no more
-					// levels below
-					if i < len(this.levels)-1 {
-						for l := 0; l < int(level[k].children.Len()); l++ {
-							// For each of the set child bit of the kth node, we clear
-							// the kth parent bit in the child's parents bitset
-							//
-							// Also, we set the child's jth parent bit since the parent
-							// needs to point to the new merged node
-							if level[k].children.Test(uint(l)) {
-								this.levels[i+1][l].parents.Clear(uint(k))
-								this.levels[i+1][l].parents.Set(uint(j))
-							}
+				// Same for all the children of the merged node. For each of the
+				// children, we clear the kth parent bit
+				//
+				// Make sure we are not at the bottom level since there's no more
+				// levels below
+				if i < len(this.levels)-1 {
+					for l := 0; l < int(level[k].children.Len()); l++ {
+						// For each of the set child bit of the kth node, we clear
+						// the kth parent bit in the child's parents bitset
+						//
+						// Also, we set the child's jth parent bit since the parent
+						// needs to point to the new merged node
+						if level[k].children.Test(uint(l)) {
+							this.levels[i+1][l].parents.Clear(uint(k))
+							this.levels[i+1][l].parents.Set(uint(j))
 						}
 					}
-
-					level[k] = nil
 				}
 
-				cur.parents = parents
-				cur.children = children
-				cur.leaf = leaf
-				cur.Type = TokenString
+				redirect[int(k)] = j
+				level[k] = nil
+			}
+
+			cur.parents = parents
+			cur.children = children
+			cur.leaf = leaf
+			cur.Type = TokenString
+
+			if event != nil {
+				events = append(events, *event)
 			}
 		}
 	}
 
-	return nil
+	// The literals of the merged nodes are kept in the literal map, pointing to the
+	// node they were merged into, so when the same literal is added to a finalized
+	// tree it joins the existing variable rather than starting a new literal.
+	if len(redirect) > 0 {
+		for key, idx := range this.litmaps[i] {
+			if j, ok := redirect[idx]; ok {
+				this.litmaps[i][key] = j
+			}
+		}
+	}
+
+	return merged, events, nil
 }
 
 // getMergeSet finds the nodes that share at least 1 parent and 1 child with trie[i][j]
@@ -590,80 +720,109 @@ func (this *Analyzer) getMergeSet(i, j int, cur *analyzerNode) (*bitset.BitSet,
 	return mergeSet, nil
 }
 
+// compact removes all the dead (merged) nodes from the tree, one level at a time.
 func (this *Analyzer) compact() error {
-	// Build a complete new trie
-	newLevels := make([][]*analyzerNode, len(this.levels))
+	this.nodeCount = make([]int, len(this.levels))
+
+	for i := range this.levels {
+		this.compactLevel(i)
+	}
+
+	return nil
+}
+
+// compactLevel removes the dead nodes from a single level and fixes the index for
+// all the remaining nodes. Since the parents of the level below and the children
+// of the level above (or the root) point to these indexes, they are remapped too.
+func (this *Analyzer) compactLevel(i int) {
+	level := this.levels[i]
+
+	// remap holds the new index of every old index, -1 if the node was removed
+	remap := make([]int, len(level))
+	newLevel := make([]*analyzerNode, 0, len(level))
 
 	// Each level has a hash map of literals that points to the literal's
 	// index position in the level slice
-	newmaps := make([]map[string]int, len(this.litmaps))
-	for i := 0; i < len(newmaps); i++ {
-		newmaps[i] = make(map[string]int)
-	}
+	newmap := make(map[string]int, len(this.litmaps[i]))
 
-	this.nodeCount = make([]int, len(this.levels))
+	if len(this.nodeCount) < len(this.levels) {
+		this.nodeCount = append(this.nodeCount, make([]int, len(this.levels)-len(this.nodeCount))...)
+	}
+	this.nodeCount[i] = 0
 
 	// Copy all the fixed children (leaf, TokenNames, TagTokenMap) into the slice
 	// Copy any non-nil children into the slice
 	// Fix the index for all the children
 	// Add any literals to the hash
-	for i, level := range this.levels {
-		for j, cur := range level {
-			if j < allTypesCount || cur != nil {
-				newLevels[i] = append(newLevels[i], cur)
-
-				if cur != nil {
-					this.nodeCount[i]++
-					cur.index = len(newLevels[i]) - 1
-
-					if cur.Type == TokenLiteral {
-						newmaps[i][cur.Value] = cur.index
-					}
+	for j, cur := range level {
+		if j < allTypesCount || cur != nil {
+			newLevel = append(newLevel, cur)
+			remap[j] = len(newLevel) - 1
+
+			if cur != nil {
+				this.nodeCount[i]++
+				cur.index = len(newLevel) - 1
+
+				if cur.Type == TokenLiteral {
+					newmap[literalKey(cur.Value, cur.isSpaceBefore)] = cur.index
+				} else {
+					cur.Value = ""
 				}
 			}
+		} else {
+			remap[j] = -1
 		}
-
 	}
 
-	// Reset all the parents and children relationship for each node
-	for i, level := range newLevels {
-		for _, cur := range level {
-			if cur == nil {
-				continue
-			}
-
-			newParents := bitset.New(1)
+	// Literals that were merged into a variable keep pointing at it
+	for key, idx := range this.litmaps[i] {
+		if _, ok := newmap[key]; !ok && idx < len(remap) && remap[idx] >= 0 {
+			newmap[key] = remap[idx]
+		}
+	}
 
-			if i > 0 {
-				for k, e := cur.parents.NextSet(0); e; k, e = cur.parents.NextSet(k + 1) {
-					// recall that index is already set to the index of the newLevels
-					newParents.Set(uint(this.levels[i-1][k].index))
-				}
-			} else {
-				newParents.Set(0)
+	// Reset the relationships that point into this level
+	if i == 0 {
+		this.root.children = remapBitSet(this.root.children, remap)
+	} else {
+		for _, n := range this.levels[i-1] {
+			if n != nil && n != this.leaf {
+				n.children = remapBitSet(n.children, remap)
 			}
+		}
+	}
 
-			newChildren := bitset.New(1)
-
-			if i < len(newLevels)-1 {
-				for k, e := cur.children.NextSet(0); e; k, e = cur.children.NextSet(k + 1) {
-					newChildren.Set(uint(this.levels[i+1][k].index))
-				}
+	if i < len(this.levels)-1 {
+		for _, n := range this.levels[i+1] {
+			if n != nil && n != this.leaf {
+				n.parents = remapBitSet(n.parents, remap)
 			}
+		}
+	}
 
-			cur.parents = newParents
-			cur.children = newChildren
+	this.levels[i] = newLevel
+	this.litmaps[i] = newmap
+}
 
-			if cur.Type != TokenLiteral {
-				cur.Value = ""
-			}
+// remapBitSet returns a new bitset with each of the set bits moved to its new
+// index, bits for removed nodes (-1) and out of range bits are dropped.
+func remapBitSet(b *bitset.BitSet, remap []int) *bitset.BitSet {
+	nb := bitset.New(1)
+	for k, e := b.NextSet(0); e; k, e = b.NextSet(k + 1) {
+		if int(k) < len(remap) && remap[k] >= 0 {
+			nb.Set(uint(remap[k]))
 		}
 	}
+	return nb
+}
 
-	this.levels = newLevels
-	this.litmaps = newmaps
-
-	return nil
+// literalKey is the key for a literal in the level's literal map, if we are marking
+// spaces then " literal" and "literal" need to be stored as different tokens
+func literalKey(value string, spaceBefore bool) string {
+	if spaceBefore {
+		return " " + value
+	}
+	return value
 }
 
 func (this *Analyzer) analyzeMessage(seq Sequence) ([]*analyzerNode, error) {
@@ -848,7 +1007,26 @@ func markSequenceKV(seq Sequence) Sequence {
 	return seq
 }
 
+// portIndex returns the index of the port that follows the address at i, as in 10.1.2.3:22,
+// 10.1.2.3/22, db.example.com:22 or [fe80::1]:22, or 0 if there is none.
+func portIndex(seq Sequence, i int) int {
+	l := len(seq)
+	switch {
+	case seq[i].Type == TokenIPv6 && i > 0 && i < l-3 && seq[i-1].Value == "[" && seq[i+1].Value == "]" &&
+		seq[i+2].Value == ":" && seq[i+3].Type == TokenInteger:
+		return i + 3
+
+	case i < l-2 && (seq[i+1].Value == ":" || (seq[i+1].Value == "/" && seq[i].Type == TokenIPv4)) &&
+		seq[i+2].Type == TokenInteger:
+		return i + 2
+	}
+	return 0
+}
+
 func analyzeSequence(seq Sequence) Sequence {
+	// Step 0: split the host:port tokens, so the address and the port can be tagged
+	seq, _ = splitAddresses(seq, false)
+
 	l := len(seq)
 	var fexists = make([]bool, TagTypesCount)
 
@@ -859,31 +1037,28 @@ func analyzeSequence(seq Sequence) Sequence {
 				seq[i].Type = TokenString
 			}
 
-			if i < l-2 && tok.Type == TokenIPv4 && (seq[i+1].Value == "/" || seq[i+1].Value == ":") &&
-				seq[i+2].Type == TokenInteger {
+			if p := portIndex(seq, i); p > 0 {
+				var tag TagType
 
 				switch tok.Tag {
-				case TagSrcIP:
-					seq[i+2].Tag = TagSrcPort
-					seq[i+2].Type = seq[i+2].Tag.TokenType()
-					fexists[seq[i+2].Tag] = true
+				case TagSrcIP, TagSrcHost:
+					tag = TagSrcPort
 
-				case TagDstIP:
-					seq[i+2].Tag = TagDstPort
-					seq[i+2].Type = seq[i+2].Tag.TokenType()
-					fexists[seq[i+2].Tag] = true
+				case TagDstIP, TagDstHost:
+					tag = TagDstPort
 
 				case TagSrcIPNAT:
-					seq[i+2].Tag = TagSrcPortNAT
-					seq[i+2].Type = seq[i+2].Tag.TokenType()
-					fexists[seq[i+2].Tag] = true
+					tag = TagSrcPortNAT
 
 				case TagDstIPNAT:
-					seq[i+2].Tag = TagDstPortNAT
-					seq[i+2].Type = seq[i+2].Tag.TokenType()
-					fexists[seq[i+2].Tag] = true
+					tag = TagDstPortNAT
 				}
 
+				if tag != TagUnknown {
+					seq[p].Tag = tag
+					seq[p].Type = seq[p].Tag.TokenType()
+					fexists[seq[p].Tag] = true
+				}
 			}
 
 			//last of all set any marked literals containing percent values to strings
@@ -252,3 +252,107 @@ func TestAnalyzerMatchPatterns(t *testing.T) {
 		}
 	}
 }
+
+func TestAnalyzerAddresses(t *testing.T) {
+	atree := NewAnalyzer()
+	scanner := NewScanner()
+	var tests = []struct {
+		msgs []string
+		pat  string
+	}{
+		{[]string{"conn from 10.1.2.3:22 to 10.9.8.7:443 route 10.0.0.0/8", "conn from 10.1.2.4:23 to 10.9.8.6:80 route 192.168.0.0/16"},
+			"conn from %srcip%:%srcport% to %dstip%:%dstport% route %ipv4cidr%"},
+		{[]string{"accept [fe80::1%eth0]:443 from db.example.com:5432 net 2001:db8::/32", "accept [2001:db8::1]:8443 from web.example.org:80 net fd00::/8"},
+			"%status% [%ipv6%]:%integer% from %srchost%:%srcport% net %ipv6cidr%"},
+	}
+	for _, tc := range tests {
+		for _, msg := range tc.msgs {
+			seq, _, err := scanner.Scan(msg, false, nil)
+			require.NoError(t, err)
+			require.NoError(t, atree.Add(seq))
+		}
+	}
+	require.NoError(t, atree.Finalize())
+	for _, tc := range tests {
+		for _, msg := range tc.msgs {
+			seq, _, err := scanner.Scan(msg, false, nil)
+			require.NoError(t, err)
+			seq, err = atree.Analyze(seq)
+			require.NoError(t, err, msg)
+			p, _ := seq.String()
+			require.Equal(t, tc.pat, p, msg)
+		}
+	}
+}
+
+func TestAnalyzerUpdate(t *testing.T) {
+	scanner := NewScanner()
+	var pos []int
+
+	// the batch analyzer sees all the samples before it is finalized
+	batch := NewAnalyzer()
+	for _, data := range analyzerSshdSamples {
+		seq, _, err := scanner.Scan(data, false, pos)
+		require.NoError(t, err)
+		require.NoError(t, batch.Add(seq))
+	}
+	require.NoError(t, batch.Finalize())
+
+	// the incremental analyzer is finalized without the Failed message
+	atree := NewAnalyzer()
+	for _, data := range analyzerSshdSamples[1:] {
+		seq, _, err := scanner.Scan(data, false, pos)
+		require.NoError(t, err)
+		require.NoError(t, atree.Add(seq))
+	}
+	require.NoError(t, atree.Finalize())
+
+	seq, _, err := scanner.Scan(analyzerSshdSamples[1], false, pos)
+	require.NoError(t, err)
+
+	// adding a message already covered by the tree changes nothing
+	events, err := atree.Update(seq)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	seq, _, err = scanner.Scan(analyzerSshdSamples[0], false, pos)
+	require.NoError(t, err)
+	events, err = atree.Update(seq)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, "Accepted", events[0].Literal)
+	require.Equal(t, []string{"Failed"}, events[0].Merged)
+	require.Equal(t, "Failed", seq[events[0].Level].Value)
+
+	for _, data := range analyzerSshdSamples {
+		seq, _, err := scanner.Scan(data, false, pos)
+		require.NoError(t, err)
+		aseq, err := atree.Analyze(seq)
+		require.NoError(t, err, data)
+		bseq, err := batch.Analyze(seq)
+		require.NoError(t, err, data)
+		a, _ := aseq.String()
+		b, _ := bseq.String()
+		require.Equal(t, b, a, data)
+	}
+}
+
+func TestAnalyzerUpdateMergedVariable(t *testing.T) {
+	scanner := NewScanner()
+	atree := NewAnalyzer()
+	for _, data := range []string{"user a Accepted y", "user a Failed x", "user a Rejected x"} {
+		seq, _, err := scanner.Scan(data, false, nil)
+		require.NoError(t, err)
+		require.NoError(t, atree.Add(seq))
+	}
+	require.NoError(t, atree.Finalize())
+
+	// Accepted merges into the variable of Failed and Rejected, which has no literal
+	seq, _, err := scanner.Scan("user a Accepted x", false, nil)
+	require.NoError(t, err)
+	events, err := atree.Update(seq)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, "Accepted", events[0].Literal)
+	require.Empty(t, events[0].Merged)
+}
@@ -0,0 +1,315 @@
+package sequence
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence/models"
+	"github.com/volatiletech/null"
+	"github.com/volatiletech/sqlboiler/boil"
+	"gopkg.in/yaml.v3"
+)
+
+//The version of the bundle written by dump, load reads the bundles up to this version.
+const BundleVersion = 1
+
+//A portable copy of the services, patterns and examples of a pattern database, written by
+//dump and merged into a database by load. The services are ordered by name and the patterns
+//by id so a bundle that is checked into git diffs cleanly.
+type Bundle struct {
+	Version  int             `json:"version" yaml:"version"`
+	Created  time.Time       `json:"created" yaml:"created"`
+	Services []BundleService `json:"services" yaml:"services"`
+}
+
+type BundleService struct {
+	ID          string          `json:"id" yaml:"id"`
+	Name        string          `json:"name" yaml:"name"`
+	DateCreated time.Time       `json:"date_created" yaml:"date_created"`
+	Patterns    []BundlePattern `json:"patterns" yaml:"patterns"`
+}
+
+type BundlePattern struct {
+	ID                   string    `json:"id" yaml:"id"`
+	Pattern              string    `json:"pattern" yaml:"pattern"`
+	TagPositions         string    `json:"tag_positions,omitempty" yaml:"tag_positions,omitempty"`
+	DateCreated          time.Time `json:"date_created" yaml:"date_created"`
+	DateLastMatched      time.Time `json:"date_last_matched" yaml:"date_last_matched"`
+	OriginalMatchCount   int64     `json:"original_match_count" yaml:"original_match_count"`
+	CumulativeMatchCount int64     `json:"cumulative_match_count" yaml:"cumulative_match_count"`
+	IgnorePattern        bool      `json:"ignore_pattern" yaml:"ignore_pattern"`
+	ComplexityScore      float64   `json:"complexity_score" yaml:"complexity_score"`
+	Examples             []string  `json:"examples,omitempty" yaml:"examples,omitempty"`
+}
+
+//The changes load made to the database.
+type BundleLoadResult struct {
+	Services int
+	Added    int
+	Merged   int
+	Examples int
+}
+
+//Returns the format of a bundle, the format passed or else json for a .json file and yaml for anything else.
+func BundleFormat(fname string, format string) string {
+	if format != "" {
+		return format
+	}
+	if strings.EqualFold(filepath.Ext(fname), ".json") {
+		return "json"
+	}
+	return "yaml"
+}
+
+//Returns the bundle of the patterns in the database, when the state is set only the
+//patterns in that review state are included.
+func DumpBundle(state string) (Bundle, error) {
+	db, ctx := OpenDbandSetContext()
+	defer db.Close()
+	return dumpBundle(db, ctx, state)
+}
+
+func dumpBundle(db *sql.DB, ctx context.Context, state string) (Bundle, error) {
+	b := Bundle{Version: BundleVersion, Created: time.Now().UTC()}
+	services, err := models.Services().All(ctx, db)
+	if err != nil {
+		logger.DatabaseSelectFailed("services", "All", err.Error())
+		return b, err
+	}
+	patterns, err := models.Patterns().All(ctx, db)
+	if err != nil {
+		logger.DatabaseSelectFailed("patterns", "All", err.Error())
+		return b, err
+	}
+	saved, err := models.Examples().All(ctx, db)
+	if err != nil {
+		logger.DatabaseSelectFailed("examples", "All", err.Error())
+		return b, err
+	}
+	var states map[string]string
+	if state != "" {
+		if states, err = GetPatternReviewStates(db, ctx); err != nil {
+			return b, err
+		}
+	}
+	emap := make(map[string][]string)
+	for _, e := range saved {
+		emap[e.PatternID] = append(emap[e.PatternID], e.ExampleDetail)
+	}
+	pmap := make(map[string][]BundlePattern)
+	for _, p := range patterns {
+		if states != nil && states[p.ID] != state {
+			continue
+		}
+		ex := redactExampleDetails(p.SequencePattern, p.TagPositions.String, emap[p.ID])
+		sort.Strings(ex)
+		pmap[p.ServiceID] = append(pmap[p.ServiceID], BundlePattern{ID: p.ID, Pattern: p.SequencePattern, TagPositions: p.TagPositions.String,
+			DateCreated: p.DateCreated.UTC(), DateLastMatched: p.DateLastMatched.UTC(), OriginalMatchCount: p.OriginalMatchCount,
+			CumulativeMatchCount: p.CumulativeMatchCount, IgnorePattern: p.IgnorePattern, ComplexityScore: p.ComplexityScore, Examples: ex})
+	}
+	for _, s := range services {
+		ps := pmap[s.ID]
+		//with a state only the services that have patterns in it are dumped
+		if states != nil && len(ps) == 0 {
+			continue
+		}
+		sort.Slice(ps, func(i, j int) bool { return ps[i].ID < ps[j].ID })
+		b.Services = append(b.Services, BundleService{ID: s.ID, Name: s.Name, DateCreated: s.DateCreated.UTC(), Patterns: ps})
+	}
+	sort.Slice(b.Services, func(i, j int) bool {
+		if b.Services[i].Name != b.Services[j].Name {
+			return b.Services[i].Name < b.Services[j].Name
+		}
+		return b.Services[i].ID < b.Services[j].ID
+	})
+	return b, nil
+}
+
+//Writes the bundle as json or yaml.
+func WriteBundle(w io.Writer, b Bundle, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(b)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(b); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+//Reads a bundle written by dump, the format is json when the bundle starts with a brace
+//and yaml otherwise.
+func ReadBundle(r io.Reader) (Bundle, error) {
+	var b Bundle
+	data, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return b, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		err = json.Unmarshal(data, &b)
+	} else {
+		err = yaml.Unmarshal(data, &b)
+	}
+	if err != nil {
+		return b, fmt.Errorf("Unable to read the bundle: %s", err.Error())
+	}
+	if b.Version < 1 || b.Version > BundleVersion {
+		return b, fmt.Errorf("The bundle is version %d, this version of sequence loads bundles up to version %d", b.Version, BundleVersion)
+	}
+	for _, s := range b.Services {
+		if s.ID == "" || s.Name == "" {
+			return b, fmt.Errorf("A service in the bundle is missing its id or name")
+		}
+		for _, p := range s.Patterns {
+			if p.ID == "" || p.Pattern == "" {
+				return b, fmt.Errorf("A pattern of the service %s in the bundle is missing its id or pattern", s.Name)
+			}
+		}
+	}
+	return b, nil
+}
+
+//Merges the bundle into the database keyed on the pattern id. A pattern that is already in
+//the database has the counts of the bundle added to its counts and keeps the earliest date
+//created and the latest date last matched, its examples are merged with the examples of the
+//bundle within the example budget.
+func LoadBundle(b Bundle) (BundleLoadResult, error) {
+	db, ctx := OpenDbandSetContext()
+	defer db.Close()
+	res, err := loadBundle(db, ctx, b)
+	if err == nil {
+		var sids []string
+		for _, s := range b.Services {
+			sids = append(sids, s.ID)
+		}
+		InvalidateParserCache(sids...)
+	}
+	return res, err
+}
+
+func loadBundle(db *sql.DB, ctx context.Context, b Bundle) (BundleLoadResult, error) {
+	var res BundleLoadResult
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return res, err
+	}
+	for _, bs := range b.Services {
+		s, err := models.FindService(ctx, tx, bs.ID)
+		if err == sql.ErrNoRows {
+			s = &models.Service{ID: bs.ID, Name: bs.Name, DateCreated: bs.DateCreated}
+			if err = s.Insert(ctx, tx, boil.Whitelist("id", "name", "date_created")); err != nil {
+				tx.Rollback()
+				logger.DatabaseInsertFailed("service", bs.ID, err.Error())
+				return res, err
+			}
+			res.Services++
+		} else if err != nil {
+			tx.Rollback()
+			logger.DatabaseSelectFailed("service", bs.ID, err.Error())
+			return res, err
+		} else if bs.DateCreated.Before(s.DateCreated) {
+			s.DateCreated = bs.DateCreated
+			if _, err = s.Update(ctx, tx, boil.Whitelist("date_created")); err != nil {
+				tx.Rollback()
+				logger.DatabaseUpdateFailed("service", bs.ID, err.Error())
+				return res, err
+			}
+		}
+		for _, bp := range bs.Patterns {
+			n, err := loadPattern(ctx, tx, bs.ID, bp, &res)
+			if err != nil {
+				tx.Rollback()
+				return res, err
+			}
+			res.Examples += n
+		}
+	}
+	return res, tx.Commit()
+}
+
+//Adds or merges a pattern of the bundle and returns the number of examples added.
+func loadPattern(ctx context.Context, tx *sql.Tx, sid string, bp BundlePattern, res *BundleLoadResult) (int, error) {
+	var (
+		ex      models.ExampleSlice
+		matched int64
+	)
+	p, err := models.FindPattern(ctx, tx, bp.ID)
+	switch {
+	case err == sql.ErrNoRows:
+		p = &models.Pattern{ID: bp.ID, ServiceID: sid, SequencePattern: bp.Pattern, TagPositions: null.String{String: bp.TagPositions, Valid: true},
+			DateCreated: bp.DateCreated, DateLastMatched: bp.DateLastMatched, OriginalMatchCount: bp.OriginalMatchCount,
+			CumulativeMatchCount: bp.CumulativeMatchCount, IgnorePattern: bp.IgnorePattern, ComplexityScore: bp.ComplexityScore}
+		err = p.Insert(ctx, tx, boil.Whitelist("id", "service_id", "sequence_pattern", "date_created", "date_last_matched", "original_match_count", "cumulative_match_count", "ignore_pattern", "tag_positions", "complexity_score"))
+		if err != nil {
+			logger.DatabaseInsertFailed("pattern", bp.ID, err.Error())
+			return 0, err
+		}
+		res.Added++
+	case err != nil:
+		logger.DatabaseSelectFailed("pattern", bp.ID, err.Error())
+		return 0, err
+	default:
+		matched = p.CumulativeMatchCount
+		mergePattern(p, bp)
+		if _, err = p.Update(ctx, tx, boil.Infer()); err != nil {
+			logger.DatabaseUpdateFailed("pattern", bp.ID, err.Error())
+			return 0, err
+		}
+		if ex, err = p.PatternExamples().All(ctx, tx); err != nil {
+			logger.DatabaseSelectFailed("examples", bp.ID, err.Error())
+			return 0, err
+		}
+		res.Merged++
+	}
+	//the examples of the bundle are merged with the saved ones within the example budget
+	var loaded []LogRecord
+	for _, e := range redactExampleDetails(bp.Pattern, bp.TagPositions, bp.Examples) {
+		loaded = append(loaded, LogRecord{Message: e})
+	}
+	keep := MergeExamples(exampleRecords(ex), int(matched), loaded, int(bp.CumulativeMatchCount))
+	return saveExamples(ctx, tx, ex, keep, bp.ID, p.ServiceID), nil
+}
+
+//Merges a pattern of a bundle into the pattern from the database, the counts are summed,
+//the earliest date created and latest date last matched are kept and the pattern is
+//ignored if either is ignored.
+func mergePattern(p *models.Pattern, bp BundlePattern) {
+	p.OriginalMatchCount += bp.OriginalMatchCount
+	p.CumulativeMatchCount += bp.CumulativeMatchCount
+	if bp.DateCreated.Before(p.DateCreated) {
+		p.DateCreated = bp.DateCreated
+	}
+	if bp.DateLastMatched.After(p.DateLastMatched) {
+		p.DateLastMatched = bp.DateLastMatched
+	}
+	p.IgnorePattern = p.IgnorePattern || bp.IgnorePattern
+}
+
+//Returns the examples with the values of the redacted tags replaced, so the examples
+//in a bundle are redacted like the saved and exported ones.
+func redactExampleDetails(pattern string, tagPositions string, examples []string) []string {
+	if !RedactionEnabled() {
+		return examples
+	}
+	list := make([]LogRecord, len(examples))
+	for i, e := range examples {
+		list[i] = LogRecord{Message: e}
+	}
+	var out []string
+	for _, lr := range RedactExamples(pattern, tagPositions, list) {
+		out = append(out, lr.Message)
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package sequence
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence/models"
+	"github.com/stretchr/testify/require"
+	"github.com/volatiletech/sqlboiler/boil"
+)
+
+func TestMergePattern(t *testing.T) {
+	d1 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	d2 := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	p := models.Pattern{ID: "p", DateCreated: d2, DateLastMatched: d2, OriginalMatchCount: 5, CumulativeMatchCount: 20}
+	mergePattern(&p, BundlePattern{ID: "p", DateCreated: d1, DateLastMatched: d1, OriginalMatchCount: 3, CumulativeMatchCount: 7, IgnorePattern: true})
+	require.Equal(t, models.Pattern{ID: "p", DateCreated: d1, DateLastMatched: d2, OriginalMatchCount: 8, CumulativeMatchCount: 27, IgnorePattern: true}, p)
+}
+
+func TestBundleFormat(t *testing.T) {
+	require.Equal(t, "json", BundleFormat("patterns.json", ""))
+	require.Equal(t, "yaml", BundleFormat("patterns.yaml", ""))
+	require.Equal(t, "yaml", BundleFormat("", ""))
+	require.Equal(t, "json", BundleFormat("patterns.yaml", "json"))
+}
+
+func TestReadBundle(t *testing.T) {
+	_, err := ReadBundle(strings.NewReader("version: 2\nservices: []\n"))
+	require.Error(t, err)
+	_, err = ReadBundle(strings.NewReader(`{"services": []}`))
+	require.Error(t, err)
+	_, err = ReadBundle(strings.NewReader("version: 1\nservices:\n  - id: s1\n    name: app\n    patterns:\n      - id: p1\n"))
+	require.Error(t, err)
+	b, err := ReadBundle(strings.NewReader(`{"version": 1, "services": [{"id": "s1", "name": "app", "patterns": [{"id": "p1", "pattern": "%string%"}]}]}`))
+	require.NoError(t, err)
+	require.Equal(t, "p1", b.Services[0].Patterns[0].ID)
+}
+
+func TestDumpAndLoadBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	SetLogger(NewLogger(filepath.Join(dir, "log.txt"), "error"))
+	ctx := context.Background()
+	d1 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	d2 := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	src := newBundleTestDatabase(t, filepath.Join(dir, "src.sdb"))
+	tx, err := src.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, (&models.Service{ID: "s1", Name: "app", DateCreated: d1}).Insert(ctx, tx, bundleServiceColumns))
+	require.NoError(t, (&models.Pattern{ID: "p1", ServiceID: "s1", SequencePattern: "user %string% logged in", DateCreated: d1, DateLastMatched: d2,
+		OriginalMatchCount: 3, CumulativeMatchCount: 10, ComplexityScore: 0.2}).Insert(ctx, tx, bundlePatternColumns))
+	require.NoError(t, (&models.Pattern{ID: "p2", ServiceID: "s1", SequencePattern: "disk %string% full", DateCreated: d1, DateLastMatched: d1,
+		OriginalMatchCount: 1, CumulativeMatchCount: 1, IgnorePattern: true}).Insert(ctx, tx, bundlePatternColumns))
+	insertExample(ctx, tx, LogRecord{Message: "user bob logged in"}, "p1", "s1")
+	require.NoError(t, tx.Commit())
+
+	b, err := dumpBundle(src, ctx, "")
+	require.NoError(t, err)
+	require.Len(t, b.Services, 1)
+	require.Len(t, b.Services[0].Patterns, 2)
+	require.Equal(t, []string{"user bob logged in"}, b.Services[0].Patterns[0].Examples)
+	//only the ignored pattern is in the ignored state
+	ignored, err := dumpBundle(src, ctx, ReviewStateIgnored)
+	require.NoError(t, err)
+	require.Len(t, ignored.Services[0].Patterns, 1)
+	require.Equal(t, "p2", ignored.Services[0].Patterns[0].ID)
+
+	for _, format := range []string{"json", "yaml"} {
+		var buf bytes.Buffer
+		require.NoError(t, WriteBundle(&buf, b, format))
+		read, err := ReadBundle(&buf)
+		require.NoError(t, err, format)
+		require.Equal(t, b.Services, read.Services, format)
+	}
+
+	//loading into an empty database copies it and loading again merges the counts
+	dst := newBundleTestDatabase(t, filepath.Join(dir, "dst.sdb"))
+	res, err := loadBundle(dst, ctx, b)
+	require.NoError(t, err)
+	require.Equal(t, BundleLoadResult{Services: 1, Added: 2, Examples: 1}, res)
+	copied, err := dumpBundle(dst, ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, b.Services, copied.Services)
+
+	b.Services[0].Patterns[0].DateCreated = d1.Add(-time.Hour)
+	b.Services[0].Patterns[0].Examples = append(b.Services[0].Patterns[0].Examples, "user amy logged in")
+	res, err = loadBundle(dst, ctx, b)
+	require.NoError(t, err)
+	require.Equal(t, BundleLoadResult{Merged: 2, Examples: 1}, res)
+	p, err := models.FindPattern(ctx, dst, "p1")
+	require.NoError(t, err)
+	require.Equal(t, int64(20), p.CumulativeMatchCount)
+	require.Equal(t, int64(6), p.OriginalMatchCount)
+	require.True(t, p.DateCreated.Equal(d1.Add(-time.Hour)))
+	require.True(t, p.DateLastMatched.Equal(d2))
+	n, err := p.PatternExamples().Count(ctx, dst)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+}
+
+var (
+	bundleServiceColumns = boil.Whitelist("id", "name", "date_created")
+	bundlePatternColumns = boil.Whitelist("id", "service_id", "sequence_pattern", "date_created", "date_last_matched", "original_match_count", "cumulative_match_count", "ignore_pattern", "tag_positions", "complexity_score")
+)
+
+func newBundleTestDatabase(t *testing.T, fname string) *sql.DB {
+	require.NoError(t, CreateDatabase(fname, "sqlite3", ""))
+	db, err := sql.Open("sqlite3", fname)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
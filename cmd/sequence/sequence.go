@@ -29,6 +29,7 @@ import (
 	"os"
 	"os/signal"
 	"runtime/pprof"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -265,7 +266,7 @@ func parse(cmd *cobra.Command, args []string) {
 		if err != nil {
 			log.Printf("Error (%s) parsing: %s", err, line)
 		} else {
-			fmt.Fprintf(ofile, "%s\n%s\n\n", line, seq.PrintTokens())
+			fmt.Fprintf(ofile, "%s\n%s\n%s\n", line, seq.PrintTokens(), printTimes(seq))
 		}
 	}
 
@@ -275,6 +276,21 @@ func parse(cmd *cobra.Command, args []string) {
 	<-done
 }
 
+// printTimes returns the times of the parsed sequence in RFC 3339, one line for each
+func printTimes(seq sequence.Sequence) string {
+	times := seq.Times()
+	names := make([]string, 0, len(times))
+	for name := range times {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var str string
+	for _, name := range names {
+		str += fmt.Sprintf("# time: %s=%s\n", name, times[name])
+	}
+	return str
+}
+
 func benchScan(cmd *cobra.Command, args []string) {
 	readConfig()
 
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/spf13/cobra"
+)
+
+//Looks for the times in the messages that the formats of the config do not match and
+//proposes a Go layout with the PCRE and grok regexes for each, with --update-config the
+//formats are added to the config file.
+func analyzetimes(cmd *cobra.Command, args []string) {
+	start("analyzetimes")
+	iscan, ifile, err := sequence.OpenInputFile(infile)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	defer ifile.Close()
+
+	ta := sequence.NewTimeAnalyzer()
+	//We load the file completely
+	total, lrMap, _ := sequence.ReadLogRecordAsMap(iscan, informat, make(map[string]sequence.LogRecordCollection), 0)
+	for _, lrc := range lrMap {
+		for _, l := range lrc.Records {
+			ta.Add(l.Message)
+		}
+	}
+	list := ta.Proposals(minCount)
+
+	ofile, err := sequence.OpenOutputFile(outfile)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	defer ofile.Close()
+	for _, p := range list {
+		fmt.Fprintf(ofile, "%d\t%s\t%s\t%s\t%s\t%s\n", p.Count, p.Layout, p.RegexType, p.Regex, p.Grok, p.Example)
+	}
+	standardLogger.HandleInfo(fmt.Sprintf("Found %d new time formats in %d messages.", len(list), total))
+	if updateConfig && len(list) > 0 {
+		if err = sequence.WriteTimeFormats(cfgfile, list); err != nil {
+			standardLogger.HandleFatal(err.Error())
+		}
+		standardLogger.HandleInfo(fmt.Sprintf("Added the time formats to %s.", cfgfile))
+	}
+}
+
+//Returns the analyzetimes command, which grows the time formats of the config from the data.
+func newAnalyzeTimesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "analyzetimes",
+		Short: "finds the times in a log file that the time formats of the config do not match and proposes formats for them, --update-config adds them to the config",
+		Run:   analyzetimes,
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/spf13/cobra"
+)
+
+//Writes the services, patterns and examples of the database to a bundle, only the
+//patterns in --state if passed.
+func dump(cmd *cobra.Command, args []string) {
+	start("dump")
+	b, err := sequence.DumpBundle(reviewState)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	ofile, err := sequence.OpenOutputFile(outfile)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	defer ofile.Close()
+	if err = sequence.WriteBundle(ofile, b, sequence.BundleFormat(outfile, outformat)); err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	patterns := 0
+	for _, s := range b.Services {
+		patterns += len(s.Patterns)
+	}
+	standardLogger.HandleInfo(fmt.Sprintf("Dumped %d services and %d patterns.", len(b.Services), patterns))
+}
+
+//Merges a bundle written by dump into the database.
+func load(cmd *cobra.Command, args []string) {
+	start("load")
+	f := os.Stdin
+	if infile != "-" {
+		var err error
+		if f, err = os.Open(infile); err != nil {
+			standardLogger.HandleFatal(err.Error())
+		}
+		defer f.Close()
+	}
+	b, err := sequence.ReadBundle(f)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	res, err := sequence.LoadBundle(b)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	standardLogger.HandleInfo(fmt.Sprintf("Loaded the bundle: %d services and %d patterns added, %d patterns merged and %d examples added.",
+		res.Services, res.Added, res.Merged, res.Examples))
+}
+
+//Returns the dump command, which writes the pattern database to a json or yaml bundle.
+func newDumpCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "dump",
+		Short: "writes the services, patterns and examples of the database to a versioned json or yaml bundle, use --state to only dump the patterns in a review state",
+		Run:   dump,
+	}
+}
+
+//Returns the load command, which merges a bundle into the pattern database.
+func newLoadCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "load",
+		Short: "merges a bundle written by dump into the database, the counts of the patterns already in it are summed",
+		Run:   load,
+	}
+}
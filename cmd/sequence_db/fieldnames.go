@@ -0,0 +1,84 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/spf13/cobra"
+)
+
+//Lists the tags of a pattern with their positions and field names.
+func fieldNamesList(cmd *cobra.Command, args []string) {
+	start("fieldnames")
+	ar, tags, err := sequence.GetPatternFieldNames(args[0])
+	if err == sql.ErrNoRows {
+		standardLogger.HandleFatal(fmt.Sprintf("The pattern %s does not exist", args[0]))
+	} else if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	ofile, err := sequence.OpenOutputFile(outfile)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	defer ofile.Close()
+	fmt.Fprintf(ofile, "# %s\n %s\n", ar.PatternId, ar.Pattern)
+	for i, pos := range sequence.SplitToInt(ar.TagPositions, ",") {
+		if i >= len(tags) {
+			break
+		}
+		fmt.Fprintf(ofile, "%d\t%s\t%s\n", pos, tags[i], ar.FieldNames[pos])
+	}
+}
+
+//Sets the field name of the tag at the position, or removes it when no name is passed.
+func fieldNamesSet(cmd *cobra.Command, args []string) {
+	start("fieldnames")
+	pos, err := strconv.Atoi(args[1])
+	if err != nil {
+		standardLogger.HandleFatal(fmt.Sprintf("The position must be a number, not %s", args[1]))
+	}
+	name := ""
+	if len(args) > 2 {
+		name = args[2]
+		if msg := sequence.ValidateFieldName(name); msg != "" {
+			standardLogger.HandleFatal(msg)
+		}
+	}
+	err = sequence.SetPatternFieldName(args[0], pos, name)
+	if err == sql.ErrNoRows {
+		standardLogger.HandleFatal(fmt.Sprintf("The pattern %s does not exist", args[0]))
+	} else if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	if name == "" {
+		standardLogger.HandleInfo(fmt.Sprintf("Field name removed from position %d of the pattern %s.", pos, args[0]))
+	} else {
+		standardLogger.HandleInfo(fmt.Sprintf("Field name %s set for position %d of the pattern %s.", name, pos, args[0]))
+	}
+}
+
+//Returns the field name commands, the names replace the generated field names,
+//eg string1, in the patterndb and grok exports and are kept by the new patterns
+//that have the same tags.
+func newFieldNamesCmd() *cobra.Command {
+	fieldNamesCmd := &cobra.Command{
+		Use:   "fieldnames",
+		Short: "lists and sets the field names of the tags of a pattern, used by the patterndb and grok exports",
+	}
+	listCmd := &cobra.Command{
+		Use:   "list [pattern id]",
+		Short: "lists the position, tag and field name of each tag of the pattern",
+		Args:  cobra.ExactArgs(1),
+		Run:   fieldNamesList,
+	}
+	setCmd := &cobra.Command{
+		Use:   "set [pattern id] [position] [field name]",
+		Short: "sets the field name of the tag at the position, without a name the field name is removed",
+		Args:  cobra.RangeArgs(2, 3),
+		Run:   fieldNamesSet,
+	}
+	fieldNamesCmd.AddCommand(listCmd, setCmd)
+	return fieldNamesCmd
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+)
+
+const (
+	//the analyzers of a service that has had no new messages for this long are dropped
+	analyzerIdleTime = 24 * time.Hour
+	//the most services analyzers are kept for, the least recently used are dropped first
+	maxAnalyzedServices = 10000
+)
+
+//Keeps an analyzer per service and message length alive between batches, so
+//new messages are absorbed into the existing trees instead of being held in
+//memory until the whole batch can be analyzed.
+type incrementalSession struct {
+	scanner     *sequence.Scanner
+	jsonParsers map[string]*sequence.Parser
+	analyzers   map[string]map[int]*sequence.Analyzer
+	//the results of the earlier batches by service, so they can be saved or exported
+	//again when a pattern change merges them into a new pattern
+	earlier  map[string]map[string]sequence.AnalyzerResult
+	lastSeen map[string]time.Time
+
+	amap      map[string]sequence.AnalyzerResult
+	pmap      map[string]sequence.AnalyzerResult
+	processed int
+	errCount  int
+	startTime time.Time
+	anTime    time.Duration
+}
+
+func newIncrementalSession(scanner *sequence.Scanner) *incrementalSession {
+	this := &incrementalSession{
+		scanner:     scanner,
+		jsonParsers: make(map[string]*sequence.Parser),
+		analyzers:   make(map[string]map[int]*sequence.Analyzer),
+		earlier:     make(map[string]map[string]sequence.AnalyzerResult),
+		lastSeen:    make(map[string]time.Time),
+	}
+	this.reset()
+	return this
+}
+
+//Clears the results after they have been saved, the analyzers are kept.
+func (this *incrementalSession) reset() {
+	this.amap = make(map[string]sequence.AnalyzerResult)
+	this.pmap = make(map[string]sequence.AnalyzerResult)
+	this.processed = 0
+	this.errCount = 0
+	this.startTime = time.Now()
+	this.anTime = 0
+}
+
+//Matches the record against the existing patterns for the service, if there is
+//no match the record is absorbed into the analyzer for its service and length.
+func (this *incrementalSession) add(l sequence.LogRecord) {
+	anStartTime := time.Now()
+	defer func() { this.anTime += time.Since(anStartTime) }()
+
+	svc := l.Service
+	sid := sequence.GenerateIDFromString("", svc)
+	//the parser is cached until new patterns are saved for the service
+	parser := sequence.BuildParserFromDb(sid)
+
+	seq, isJson, _ := sequence.ScanMessage(this.scanner, l.Message, format)
+	if pseq, err := parser.Parse(seq); err == nil {
+		addToResults(this.pmap, pseq, l, sid, svc, false)
+		this.processed++
+		return
+	}
+
+	this.lastSeen[svc] = time.Now()
+	if isJson {
+		jsonParser, ok := this.jsonParsers[svc]
+		if !ok {
+			jsonParser = sequence.NewParser()
+			this.jsonParsers[svc] = jsonParser
+		}
+		jsonParser.Add(seq)
+		aseq, err := jsonParser.Parse(seq)
+		if err != nil {
+			standardLogger.LogAnalysisFailed(l, "json")
+			this.errCount++
+			return
+		}
+		addToResults(this.amap, aseq, l, sid, svc, true)
+		this.processed++
+		return
+	}
+
+	analyzers, ok := this.analyzers[svc]
+	if !ok {
+		analyzers = make(map[int]*sequence.Analyzer)
+		this.analyzers[svc] = analyzers
+	}
+	analyzer, ok := analyzers[len(seq)]
+	if !ok {
+		analyzer = sequence.NewAnalyzer()
+		analyzers[len(seq)] = analyzer
+	}
+	events, err := analyzer.Update(seq)
+	if err != nil {
+		standardLogger.LogAnalysisFailed(l, "general")
+		this.errCount++
+		return
+	}
+	if len(events) > 0 {
+		this.patternsChanged(svc, len(seq), analyzer, events)
+	}
+
+	aseq, err := analyzer.Analyze(seq)
+	if err != nil {
+		standardLogger.LogAnalysisFailed(l, "general")
+		this.errCount++
+		return
+	}
+	addToResults(this.amap, aseq, l, sid, svc, true)
+	this.processed++
+}
+
+//When a literal becomes a variable, the results collected for the service with the
+//same length in this batch and the earlier batches are moved to the pattern their
+//examples now produce, so the new pattern is saved or exported with all of them.
+func (this *incrementalSession) patternsChanged(svc string, length int, analyzer *sequence.Analyzer, events []sequence.PatternChangeEvent) {
+	for _, ev := range events {
+		standardLogger.HandleInfo(fmt.Sprintf("Pattern change for service %s: the literal %q at position %d is now a variable, merged with %s",
+			svc, ev.Literal, ev.Level, strings.Join(ev.Merged, ", ")))
+	}
+	for _, results := range []map[string]sequence.AnalyzerResult{this.amap, this.earlier[svc]} {
+		for pat, ar := range results {
+			if ar.Service.Name != svc || len(ar.Examples) == 0 {
+				continue
+			}
+			seq, _, _ := sequence.ScanMessage(this.scanner, ar.Examples[0].Message, format)
+			if len(seq) != length {
+				continue
+			}
+			aseq, err := analyzer.Analyze(seq)
+			if err != nil {
+				continue
+			}
+			npat, pos := aseq.String()
+			if npat == pat {
+				continue
+			}
+			delete(results, pat)
+			this.moveResult(ar, aseq, npat, pos)
+		}
+	}
+}
+
+//Adds the result to the new pattern in the results of this batch.
+func (this *incrementalSession) moveResult(ar sequence.AnalyzerResult, aseq sequence.Sequence, npat string, pos []int) {
+	svc := ar.Service.Name
+	sid := sequence.GenerateIDFromString("", svc)
+	nar, ok := this.amap[npat]
+	if !ok {
+		nar = ar
+		nar.Examples = nil
+		nar.ExampleCount = 0
+		nar.Pattern = npat
+		nar.PatternId = sequence.GenerateIDFromString(npat, svc)
+		nar.TagPositions = sequence.SplitToString(pos, ",")
+		nar.Service.ID = sid
+		nar.ComplexityScore = sequence.CalculatePatternComplexity(aseq, len(ar.Examples[0].Message))
+	}
+	nar.Examples = sequence.MergeExamples(nar.Examples, nar.ExampleCount, ar.Examples, ar.ExampleCount)
+	nar.ExampleCount += ar.ExampleCount
+	this.amap[npat] = nar
+}
+
+//Saves the collected results and starts collecting again.
+func (this *incrementalSession) flush() {
+	this.remember()
+	if this.processed > 0 || this.errCount > 0 {
+		saveResults(this.amap, this.pmap, this.processed, this.errCount, this.startTime, this.anTime)
+	}
+	this.evict(time.Now())
+	this.reset()
+}
+
+//Keeps the new patterns of the batch for the pattern changes of the later batches.
+//The count of a pattern that is saved to the database is not kept, it stays with the
+//saved pattern and is moved to the pattern that replaces it by lineage migrate.
+func (this *incrementalSession) remember() {
+	saving := sequence.GetUseDatabase() && !allinone
+	tr := sequence.GetSaveThreshold()
+	for pat, ar := range this.amap {
+		if saving && ar.ExampleCount >= tr {
+			ar.ExampleCount = 0
+		}
+		earlier, ok := this.earlier[ar.Service.Name]
+		if !ok {
+			earlier = make(map[string]sequence.AnalyzerResult)
+			this.earlier[ar.Service.Name] = earlier
+		}
+		if prev, ok := earlier[pat]; ok {
+			ar.Examples = sequence.MergeExamples(prev.Examples, prev.ExampleCount, ar.Examples, ar.ExampleCount)
+			ar.ExampleCount += prev.ExampleCount
+		}
+		earlier[pat] = ar
+	}
+}
+
+//Drops the analyzers and earlier results of the services that have had no new
+//messages for the idle time, and of the least recently seen services over the limit.
+func (this *incrementalSession) evict(now time.Time) {
+	var services []string
+	for svc, seen := range this.lastSeen {
+		if now.Sub(seen) > analyzerIdleTime {
+			this.drop(svc)
+			continue
+		}
+		services = append(services, svc)
+	}
+	if len(services) <= maxAnalyzedServices {
+		return
+	}
+	sort.Slice(services, func(i, j int) bool { return this.lastSeen[services[i]].Before(this.lastSeen[services[j]]) })
+	for _, svc := range services[:len(services)-maxAnalyzedServices] {
+		this.drop(svc)
+	}
+}
+
+func (this *incrementalSession) drop(svc string) {
+	delete(this.analyzers, svc)
+	delete(this.jsonParsers, svc)
+	delete(this.earlier, svc)
+	delete(this.lastSeen, svc)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/spf13/cobra"
+)
+
+//Looks for the patterns that supersede older patterns and saves the links.
+func lineageDetect(cmd *cobra.Command, args []string) {
+	start("lineage")
+	links, err := sequence.DetectLineage(minScore)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	writeLinks(links)
+	standardLogger.HandleInfo(fmt.Sprintf("Found %d patterns that supersede older patterns.", len(links)))
+}
+
+//Lists the saved links between the patterns.
+func lineageList(cmd *cobra.Command, args []string) {
+	start("lineage")
+	links, err := sequence.GetPatternLineage()
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	writeLinks(links)
+}
+
+//Migrates the review state, field names and match counts over the links of the
+//patterns passed, or over all the links that have not been migrated.
+func lineageMigrate(cmd *cobra.Command, args []string) {
+	start("lineage")
+	links, err := sequence.GetPatternLineage()
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	ids := make(map[string]bool)
+	for _, id := range args {
+		ids[id] = true
+	}
+	count := 0
+	for _, l := range links {
+		if l.Migrated || (len(ids) > 0 && !ids[l.PatternId]) {
+			continue
+		}
+		if err = sequence.MigrateLineage(l.PatternId, l.SupersedesId, reviewer); err != nil {
+			standardLogger.HandleError(err.Error())
+			continue
+		}
+		standardLogger.HandleInfo(fmt.Sprintf("Migrated the pattern %s to the pattern %s.", l.SupersedesId, l.PatternId))
+		count++
+	}
+	standardLogger.HandleInfo(fmt.Sprintf("%d links migrated.", count))
+}
+
+func writeLinks(links []sequence.PatternLink) {
+	ofile, err := sequence.OpenOutputFile(outfile)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	defer ofile.Close()
+	for _, l := range links {
+		migrated := ""
+		if l.Migrated {
+			migrated = "migrated"
+		}
+		fmt.Fprintf(ofile, "%s\tsupersedes\t%s\t%s\t%.2f\t%s\n", l.PatternId, l.SupersedesId, l.Service, l.Score, migrated)
+	}
+}
+
+//Returns the lineage commands, which link the patterns that replace older patterns
+//when a change to the messages or to sequence gives a pattern a new id.
+func newLineageCmd() *cobra.Command {
+	lineageCmd := &cobra.Command{
+		Use:   "lineage",
+		Short: "links the patterns that supersede older patterns and migrates the review state, field names and match counts to them",
+	}
+	detectCmd := &cobra.Command{
+		Use:   "detect",
+		Short: "finds the patterns that supersede older patterns of the same service and saves the links, use --min-score to set how well the tokens must line up",
+		Run:   lineageDetect,
+	}
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "lists the links between the patterns",
+		Run:   lineageList,
+	}
+	migrateCmd := &cobra.Command{
+		Use:   "migrate [pattern ids]",
+		Short: "moves the review state, field names and match counts of the superseded patterns to the new patterns, all links that have not been migrated if no ids are passed",
+		Run:   lineageMigrate,
+	}
+	lineageCmd.AddCommand(detectCmd, listCmd, migrateCmd)
+	return lineageCmd
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/spf13/cobra"
+)
+
+//Applies the migrations that are not in the database.
+func migrateUp(cmd *cobra.Command, args []string) {
+	start("migrate")
+	done, err := sequence.MigrateUp()
+	for _, m := range done {
+		standardLogger.HandleInfo(fmt.Sprintf("Migrated the database to version %d %s.", m.Version, m.Name))
+	}
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	standardLogger.HandleInfo(fmt.Sprintf("The database schema is at version %d.", sequence.LatestSchemaVersion()))
+}
+
+//Lists the migrations and whether they have been applied to the database.
+func migrateStatus(cmd *cobra.Command, args []string) {
+	start("migrate")
+	status, err := sequence.GetMigrationStatus()
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	ofile, err := sequence.OpenOutputFile(outfile)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	defer ofile.Close()
+	pending := 0
+	for _, m := range status {
+		applied := "pending"
+		if m.Applied {
+			applied = "applied " + m.DateApplied.Format("2006-01-02 15:04:05")
+		} else {
+			pending++
+		}
+		fmt.Fprintf(ofile, "%04d\t%s\t%s\n", m.Version, m.Name, applied)
+	}
+	standardLogger.HandleInfo(fmt.Sprintf("%d of %d migrations pending.", pending, len(status)))
+}
+
+//Returns the migrate commands, which bring the schema of the database in the config
+//up to the version this build of sequence needs.
+func newMigrateCmd() *cobra.Command {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "applies the schema migrations to the database, the other commands refuse to run against an older schema",
+	}
+	upCmd := &cobra.Command{
+		Use:   "up",
+		Short: "applies the migrations that are not in the database, a database created before the migrations is checked and the changes it has are recorded",
+		Run:   migrateUp,
+	}
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "lists the migrations and when they were applied",
+		Run:   migrateStatus,
+	}
+	migrateCmd.AddCommand(upCmd, statusCmd)
+	return migrateCmd
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os/user"
+	"strings"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/spf13/cobra"
+)
+
+//Lists the patterns with their review state, filtered by --state if passed.
+func reviewList(cmd *cobra.Command, args []string) {
+	start("review")
+	db, ctx := sequence.OpenDbandSetContext()
+	defer db.Close()
+	reviews, err := sequence.GetPatternReviews(db, ctx)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	ofile, err := sequence.OpenOutputFile(outfile)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	defer ofile.Close()
+	count := 0
+	for _, pr := range reviews {
+		if reviewState != "" && pr.State != reviewState {
+			continue
+		}
+		fmt.Fprintf(ofile, "%s\t%s\t%s\t%s\n", pr.PatternId, pr.Service, pr.State, pr.Pattern)
+		if pr.Reviewer != "" {
+			fmt.Fprintf(ofile, "# %s by %s on %s", pr.State, pr.Reviewer, pr.DateUpdated.Format("2006-01-02 15:04:05"))
+			if pr.Comment != "" {
+				fmt.Fprintf(ofile, ": %s", pr.Comment)
+			}
+			fmt.Fprintln(ofile)
+		}
+		count++
+	}
+	standardLogger.HandleInfo(fmt.Sprintf("Listed %d patterns.", count))
+}
+
+//Prints the review history of the patterns.
+func reviewHistory(cmd *cobra.Command, args []string) {
+	start("review")
+	ofile, err := sequence.OpenOutputFile(outfile)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	defer ofile.Close()
+	for _, id := range reviewPatternIds(args) {
+		changes, err := sequence.GetPatternReviewHistory(id)
+		if err != nil {
+			standardLogger.HandleFatal(err.Error())
+		}
+		for _, c := range changes {
+			fmt.Fprintf(ofile, "%s\t%s\t%s -> %s\t%s\t%s\n", c.PatternId, c.DateChanged.Format("2006-01-02 15:04:05"), c.FromState, c.ToState, c.Reviewer, c.Comment)
+		}
+	}
+}
+
+//Returns the run function of a review command that moves the patterns to the state.
+func reviewTo(state string) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		start("review")
+		updated := 0
+		for _, id := range reviewPatternIds(args) {
+			err := sequence.ReviewPattern(id, state, reviewer, reviewComment)
+			if err == sql.ErrNoRows {
+				standardLogger.HandleError(fmt.Sprintf("The pattern %s does not exist", id))
+			} else if err != nil {
+				standardLogger.HandleError(err.Error())
+			} else {
+				updated++
+			}
+		}
+		standardLogger.HandleInfo(fmt.Sprintf("%d patterns moved to %s.", updated, state))
+	}
+}
+
+//The pattern ids are passed as arguments or read from the input file, one per line.
+func reviewPatternIds(args []string) []string {
+	ids := args
+	if infile != "" {
+		iscan, ifile, err := sequence.OpenInputFile(infile)
+		if err != nil {
+			standardLogger.HandleFatal(err.Error())
+		}
+		defer ifile.Close()
+		for iscan.Scan() {
+			if id := strings.TrimSpace(iscan.Text()); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		standardLogger.HandleFatal("No pattern ids were passed, pass them as arguments or in the input file (-i)")
+	}
+	return ids
+}
+
+//The reviewer defaults to the name of the user running the command.
+func defaultReviewer() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+//Returns the review commands, review list shows the state of the patterns and
+//the other commands move the patterns through the review workflow.
+func newReviewCmd() *cobra.Command {
+	reviewCmd := &cobra.Command{
+		Use:   "review",
+		Short: "lists the review state of the patterns and moves them through the review workflow",
+	}
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "lists the patterns with their review state, use --state to only list the patterns in that state",
+		Run:   reviewList,
+	}
+	historyCmd := &cobra.Command{
+		Use:   "history [pattern ids]",
+		Short: "lists the review history of the patterns",
+		Run:   reviewHistory,
+	}
+	startCmd := &cobra.Command{
+		Use:   "start [pattern ids]",
+		Short: "moves new patterns to under review",
+		Run:   reviewTo(sequence.ReviewStateReview),
+	}
+	approveCmd := &cobra.Command{
+		Use:   "approve [pattern ids]",
+		Short: "approves the patterns so they are exported with --state approved",
+		Run:   reviewTo(sequence.ReviewStateApproved),
+	}
+	rejectCmd := &cobra.Command{
+		Use:   "reject [pattern ids]",
+		Short: "rejects the patterns, they are ignored and no longer exported",
+		Run:   reviewTo(sequence.ReviewStateIgnored),
+	}
+	deprecateCmd := &cobra.Command{
+		Use:   "deprecate [pattern ids]",
+		Short: "deprecates approved patterns so they are no longer exported with --state approved",
+		Run:   reviewTo(sequence.ReviewStateDeprecated),
+	}
+	reviewCmd.AddCommand(listCmd, historyCmd, startCmd, approveCmd, rejectCmd, deprecateCmd)
+	return reviewCmd
+}
@@ -5,12 +5,19 @@ import (
 	"os"
 	"os/signal"
 	"runtime/pprof"
+	"sort"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/ryanfaircloth/sequence-RTG/sequence/elasticsearch_ingest"
+	"github.com/ryanfaircloth/sequence-RTG/sequence/fluentbit_parser"
+	"github.com/ryanfaircloth/sequence-RTG/sequence/lognorm_rulebase"
 	"github.com/ryanfaircloth/sequence-RTG/sequence/logstash_grok"
 	"github.com/ryanfaircloth/sequence-RTG/sequence/syslog_ng_pattern_db"
+	"github.com/ryanfaircloth/sequence-RTG/sequence/vector_remap"
 	"github.com/spf13/cobra"
 )
 
@@ -28,7 +35,6 @@ var (
 	cpuprofile     string
 	dbtype         string
 	dbconn         string
-	dbpath         string
 	dbname         string
 	workers        int
 	format         string
@@ -38,10 +44,24 @@ var (
 	thresholdValue string
 	complimit      float64
 	allinone       bool
+	syslogUDP      string
+	syslogTCP      string
+	flushInterval  time.Duration
+	httpAddr       string
+	reviewState    string
+	reviewer       string
+	reviewComment  string
+	minScore       float64
+	window         time.Duration
+	growth         float64
+	incremental    bool
+	minCount       int
+	updateConfig   bool
 	standardLogger *sequence.StandardLogger
 
-	quit chan struct{}
-	done chan struct{}
+	quit    chan struct{}
+	done    chan struct{}
+	sigchan chan os.Signal
 )
 
 func profile() {
@@ -57,7 +77,7 @@ func profile() {
 		pprof.StartCPUProfile(f)
 	}
 
-	sigchan := make(chan os.Signal, 1)
+	sigchan = make(chan os.Signal, 1)
 	signal.Notify(sigchan, os.Interrupt, os.Kill)
 	go func() {
 		select {
@@ -129,7 +149,10 @@ func scan(cmd *cobra.Command, args []string) {
 
 func createdatabase(cmd *cobra.Command, args []string) {
 	start("createdatabase")
-	sequence.CreateDatabase(dbconn, dbtype, dbpath, dbname)
+	err := sequence.CreateDatabase(dbconn, dbtype, dbname)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
 	standardLogger.HandleInfo(fmt.Sprintf("Database created successfully"))
 }
 
@@ -150,24 +173,24 @@ func updateignorepatterns(cmd *cobra.Command, args []string) {
 	for iscan.Scan() {
 		ids = append(ids, iscan.Text())
 	}
-	sequence.SaveIgnoredPatterns(ids)
+	sequence.SaveIgnoredPatterns(ids, reviewer)
 	standardLogger.HandleInfo(fmt.Sprintf("Ignore patterns updated."))
 }
 
 func analyzebyservice(cmd *cobra.Command, args []string) {
 	start("analyzebyservice")
 	scanner := sequence.NewScanner()
-	var (
-		err   error
-		aseq  sequence.Sequence
-		mtype string
-	)
 	iscan, ifile, err := sequence.OpenInputFile(infile)
 	if err != nil {
 		standardLogger.HandleFatal(err.Error())
 	}
 	defer ifile.Close()
 
+	var session *incrementalSession
+	if incremental {
+		session = newIncrementalSession(scanner)
+	}
+
 	for {
 		lrMap := make(map[string]sequence.LogRecordCollection)
 		startTime := time.Now()
@@ -176,168 +199,362 @@ func analyzebyservice(cmd *cobra.Command, args []string) {
 		if exit {
 			break
 		}
-		standardLogger.HandleInfo(fmt.Sprintf("Read in %d records successfully, starting analysis..", total))
-		standardLogger.HandleDebug(fmt.Sprintf("Threshhold equals %d ", purgeThreshold))
-		//Here we group by service and process
-		//We lose the cross service patterns but we get better
-		//within service patterns
-		err_count := 0
-		processed := 0
-		amap := make(map[string]sequence.AnalyzerResult)
-		pmap := make(map[string]sequence.AnalyzerResult)
-		anStartTime := time.Now()
-		for svc, lrc := range lrMap {
-			standardLogger.HandleDebug(fmt.Sprintf("Started processing records from service: %s", svc))
-			// For all the log messages, if we can't parse it, then let's add it to the
-			// analyzer for pattern analysis, this requires the previous pattern file/folder
-			//	to be passed in
-			analyzer := sequence.NewAnalyzer()
-			jsonParser := sequence.NewParser()
-			sid := sequence.GenerateIDFromString("", svc)
-			standardLogger.HandleDebug("Started building parser using patterns from database")
-			parser := sequence.BuildParserFromDb(sid)
-			standardLogger.HandleDebug("Completed building parser and starting to check if matches existing patterns")
-			var seq sequence.Sequence
-			var isJson bool
-			partitionMap := make(map[int]sequence.LogRecordCollection)
-			var jCol sequence.LogRecordCollection
-			for _, l := range lrc.Records {
-				seq, isJson, _ = sequence.ScanMessage(scanner, l.Message, format)
-				pseq, err := parser.Parse(seq)
-				//if the pattern is found we still need to update the pattern/service relationship
-				//and the statistics
-				if err == nil {
-					pat, pos := pseq.String()
-					ar, ok := pmap[pat]
-					if !ok {
-						ar = sequence.AnalyzerResult{}
-					}
-					sequence.AddExampleToAnalyzerResult(&ar, l)
-					ar.Service.ID = sid
-					ar.Service.Name = svc
-					ar.TagPositions = sequence.SplitToString(pos, ",")
-					ar.PatternId = sequence.GenerateIDFromString(pat, svc)
-					ar.Pattern = pat
-					ar.ExampleCount++
-					pmap[pat] = ar
-
-					processed++
-
-				} else if err != nil {
-					if isJson {
-						jsonParser.Add(seq)
-						jCol.Records = append(jCol.Records, l)
-					} else {
-						//we need to do something here based on number of tokens
-						//we want to compare only those with same number.
-						if col, ok := partitionMap[len(seq)]; ok {
-							col.Records = append(col.Records, l)
-							partitionMap[len(seq)] = col
-						} else {
-							col.Records = append(col.Records, l)
-							partitionMap[len(seq)] = col
-						}
-						//analyzer.Add(seq)
-					}
+		if session != nil {
+			standardLogger.HandleInfo(fmt.Sprintf("Read in %d records successfully, starting analysis..", total))
+			for _, lrc := range lrMap {
+				for _, l := range lrc.Records {
+					session.add(l)
 				}
 			}
-			//analyzer.Finalize()
-			standardLogger.HandleDebug("Parsed statistics updated, new messages scanned and grouped.")
-			standardLogger.HandleDebug("Starting analysis of json messages")
-			for _, l := range jCol.Records {
-				seq, _, _ := sequence.ScanMessage(scanner, l.Message, format)
-				aseq, err = jsonParser.Parse(seq)
-				mtype = "json"
-				if err != nil {
-					standardLogger.LogAnalysisFailed(l, mtype)
-					err_count++
-				} else {
-					pat, pos := aseq.String()
-					ar, ok := amap[pat]
-					if !ok {
-						ar = sequence.AnalyzerResult{}
-					}
-					sequence.AddExampleToAnalyzerResult(&ar, l)
-					ar.Service.ID = sid
-					ar.Service.Name = svc
-					ar.TagPositions = sequence.SplitToString(pos, ",")
-					ar.PatternId = sequence.GenerateIDFromString(pat, svc)
-					ar.Pattern = pat
-					ar.ExampleCount++
-					ar.DateCreated = time.Now()
-					ar.DateLastMatched = time.Now()
-					ar.ComplexityScore = sequence.CalculatePatternComplexity(aseq, len(l.Message))
-					amap[pat] = ar
-
-					processed++
+			session.flush()
+		} else {
+			analyzeBatch(scanner, lrMap, total, startTime)
+		}
+		if batchsize == 0 || infile != "-" {
+			break
+		}
+	}
+}
+
+//Receives syslog messages over UDP and/or TCP and analyzes them in batches,
+//a batch is processed when it reaches the batch size or when the flush interval
+//passes, whichever comes first. On SIGINT or SIGTERM the listeners are closed
+//and the records received so far are processed before exiting.
+func listen(cmd *cobra.Command, args []string) {
+	start("listen")
+	//the signals are handled here instead of exiting straight away in profile
+	signal.Stop(sigchan)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	scanner := sequence.NewScanner()
+	srv := sequence.NewSyslogServer(10000)
+	if syslogUDP != "" {
+		if err := srv.ListenUDP(syslogUDP); err != nil {
+			standardLogger.HandleFatal(err.Error())
+		}
+		standardLogger.HandleInfo(fmt.Sprintf("Listening for syslog messages on udp %s", syslogUDP))
+	}
+	if syslogTCP != "" {
+		if err := srv.ListenTCP(syslogTCP); err != nil {
+			standardLogger.HandleFatal(err.Error())
+		}
+		standardLogger.HandleInfo(fmt.Sprintf("Listening for syslog messages on tcp %s", syslogTCP))
+	}
+	defer srv.Close()
+
+	var ticker <-chan time.Time
+	if flushInterval > 0 {
+		t := time.NewTicker(flushInterval)
+		defer t.Stop()
+		ticker = t.C
+	}
+
+	//in incremental mode each record is analyzed as it arrives and only the
+	//results are held until the batch is flushed
+	var session *incrementalSession
+	if incremental {
+		session = newIncrementalSession(scanner)
+	}
+
+	lrMap := make(map[string]sequence.LogRecordCollection)
+	total := 0
+	startTime := time.Now()
+	flush := func() {
+		if session != nil {
+			session.flush()
+		} else if total > 0 {
+			analyzeBatch(scanner, lrMap, total, startTime)
+		}
+		lrMap = make(map[string]sequence.LogRecordCollection)
+		total = 0
+		startTime = time.Now()
+	}
+
+	var stopped os.Signal
+	for {
+		select {
+		case r, ok := <-srv.Records:
+			if !ok {
+				flush()
+				if stopped != nil {
+					//stops the profile before exiting
+					close(quit)
+					<-done
 				}
+				return
 			}
-			for _, lrc := range partitionMap {
-				analyzer = sequence.NewAnalyzer()
-				for _, l := range lrc.Records {
-					seq, _, _ := sequence.ScanMessage(scanner, l.Message, format)
-					analyzer.Add(seq)
+			if session != nil {
+				session.add(r)
+			} else {
+				sequence.AddLogRecordToMap(lrMap, r)
+			}
+			total++
+			if batchsize != 0 && total >= batchsize {
+				flush()
+			}
+		case <-ticker:
+			flush()
+		case stopped = <-stop:
+			standardLogger.HandleInfo(fmt.Sprintf("Trapped signal %v, processing the records received before exiting", stopped))
+			//the records still buffered are read until the channel is closed
+			stop = nil
+			go srv.Close()
+		}
+	}
+}
+
+//The results of analyzing the records of one service.
+type serviceResult struct {
+	amap      map[string]sequence.AnalyzerResult
+	pmap      map[string]sequence.AnalyzerResult
+	processed int
+	errCount  int
+}
+
+//Analyzes a batch of log records grouped by service, matching them against the
+//existing patterns first and then either saving the results to the database or
+//exporting them directly when --all is passed.
+func analyzeBatch(scanner *sequence.Scanner, lrMap map[string]sequence.LogRecordCollection, total int, startTime time.Time) {
+	standardLogger.HandleInfo(fmt.Sprintf("Read in %d records successfully, starting analysis..", total))
+	standardLogger.HandleDebug(fmt.Sprintf("Threshhold equals %d ", purgeThreshold))
+	res, anTime := analyzeRecords(scanner, lrMap)
+	saveResults(res.amap, res.pmap, res.processed, res.errCount, startTime, anTime)
+}
+
+//Analyzes the records of each service and returns the merged results.
+//Services are analyzed concurrently by --workers goroutines, each with its own
+//scanner, and the results are merged in service name order.
+func analyzeRecords(scanner *sequence.Scanner, lrMap map[string]sequence.LogRecordCollection) (serviceResult, time.Duration) {
+	//Here we group by service and process
+	//We lose the cross service patterns but we get better
+	//within service patterns
+	services := make([]string, 0, len(lrMap))
+	for svc := range lrMap {
+		services = append(services, svc)
+	}
+	sort.Strings(services)
+	results := make([]serviceResult, len(services))
+	anStartTime := time.Now()
+	if workers <= 1 || len(services) == 1 {
+		for i, svc := range services {
+			results[i] = analyzeService(scanner, svc, lrMap[svc])
+		}
+	} else {
+		var wg sync.WaitGroup
+		svcpipe := make(chan int, len(services))
+		for i := 0; i < workers && i < len(services); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				scanner := sequence.NewScanner()
+				for i := range svcpipe {
+					results[i] = analyzeService(scanner, services[i], lrMap[services[i]])
 				}
-				analyzer.Finalize()
-				for _, l := range lrc.Records {
-					seq, _, _ := sequence.ScanMessage(scanner, l.Message, format)
-					aseq, err = analyzer.Analyze(seq)
-					mtype = "general"
-					if err != nil {
-						standardLogger.LogAnalysisFailed(l, mtype)
-						err_count++
-					} else {
-						pat, pos := aseq.String()
-						ar, ok := amap[pat]
-						if !ok {
-							ar = sequence.AnalyzerResult{}
-						}
-						sequence.AddExampleToAnalyzerResult(&ar, l)
-						ar.Service.ID = sid
-						ar.Service.Name = svc
-						ar.TagPositions = sequence.SplitToString(pos, ",")
-						ar.PatternId = sequence.GenerateIDFromString(pat, svc)
-						ar.Pattern = pat
-						ar.ExampleCount++
-						ar.DateCreated = time.Now()
-						ar.DateLastMatched = time.Now()
-						ar.ComplexityScore = sequence.CalculatePatternComplexity(aseq, len(l.Message))
-
-						amap[pat] = ar
-						processed++
-					}
+			}()
+		}
+		for i := range services {
+			svcpipe <- i
+		}
+		close(svcpipe)
+		wg.Wait()
+	}
+
+	res := serviceResult{
+		amap: make(map[string]sequence.AnalyzerResult),
+		pmap: make(map[string]sequence.AnalyzerResult),
+	}
+	for _, r := range results {
+		mergeResults(res.amap, r.amap)
+		mergeResults(res.pmap, r.pmap)
+		res.processed += r.processed
+		res.errCount += r.errCount
+	}
+	return res, time.Since(anStartTime)
+}
+
+//Analyzes the records of a single service, the scanner must not be shared with
+//another goroutine.
+func analyzeService(scanner *sequence.Scanner, svc string, lrc sequence.LogRecordCollection) serviceResult {
+	var (
+		err   error
+		aseq  sequence.Sequence
+		mtype string
+	)
+	res := serviceResult{
+		amap: make(map[string]sequence.AnalyzerResult),
+		pmap: make(map[string]sequence.AnalyzerResult),
+	}
+	standardLogger.HandleDebug(fmt.Sprintf("Started processing records from service: %s", svc))
+	// For all the log messages, if we can't parse it, then let's add it to the
+	// analyzer for pattern analysis, this requires the previous pattern file/folder
+	//	to be passed in
+	analyzer := sequence.NewAnalyzer()
+	jsonParser := sequence.NewParser()
+	sid := sequence.GenerateIDFromString("", svc)
+	standardLogger.HandleDebug("Started building parser using patterns from database")
+	parser := sequence.BuildParserFromDb(sid)
+	standardLogger.HandleDebug("Completed building parser and starting to check if matches existing patterns")
+	var seq sequence.Sequence
+	var isJson bool
+	partitionMap := make(map[int]sequence.LogRecordCollection)
+	var jCol sequence.LogRecordCollection
+	for _, l := range lrc.Records {
+		seq, isJson, _ = sequence.ScanMessage(scanner, l.Message, format)
+		pseq, err := parser.Parse(seq)
+		//if the pattern is found we still need to update the pattern/service relationship
+		//and the statistics
+		if err == nil {
+			addToResults(res.pmap, pseq, l, sid, svc, false)
+			res.processed++
+
+		} else if err != nil {
+			if isJson {
+				jsonParser.Add(seq)
+				jCol.Records = append(jCol.Records, l)
+			} else {
+				//we need to do something here based on number of tokens
+				//we want to compare only those with same number.
+				if col, ok := partitionMap[len(seq)]; ok {
+					col.Records = append(col.Records, l)
+					partitionMap[len(seq)] = col
+				} else {
+					col.Records = append(col.Records, l)
+					partitionMap[len(seq)] = col
 				}
+				//analyzer.Add(seq)
 			}
 		}
-		anTime := time.Since(anStartTime)
-		standardLogger.HandleInfo(fmt.Sprintf("Analysed in: %s\n", anTime))
-		if sequence.GetUseDatabase() && !allinone {
-			standardLogger.HandleDebug("Starting save to the database.")
-			sequence.SaveExistingToDatabase(pmap)
-			new, saved := sequence.SaveToDatabase(amap)
-			standardLogger.HandleDebug("Finished save to the database.")
-			standardLogger.AnalyzeInfo(processed, len(amap)+len(pmap), new, saved, err_count, time.Since(startTime), anTime)
+	}
+	//analyzer.Finalize()
+	standardLogger.HandleDebug("Parsed statistics updated, new messages scanned and grouped.")
+	standardLogger.HandleDebug("Starting analysis of json messages")
+	for _, l := range jCol.Records {
+		seq, _, _ := sequence.ScanMessage(scanner, l.Message, format)
+		aseq, err = jsonParser.Parse(seq)
+		mtype = "json"
+		if err != nil {
+			standardLogger.LogAnalysisFailed(l, mtype)
+			res.errCount++
 		} else {
-			//output directly to the files
-			//merge pmap and amap
-			//syslog-ng patterndb
-			cmap := amap
-			for k, v := range pmap {
-				cmap[k] = v
-			}
-			export(cmap)
-			//always output to a txt file for parsing later
-			oFile, _ := sequence.OpenOutputFile("C:\\data\\debug.txt")
-			defer oFile.Close()
-			for pat, stat := range amap {
-				fmt.Fprintf(oFile, "%s\n# %d log messages matched\n# %s\n\n", pat, stat.ExampleCount, stat.Examples[0].Message)
+			addToResults(res.amap, aseq, l, sid, svc, true)
+			res.processed++
+		}
+	}
+	for _, lrc := range partitionMap {
+		analyzer = sequence.NewAnalyzer()
+		for _, l := range lrc.Records {
+			seq, _, _ := sequence.ScanMessage(scanner, l.Message, format)
+			analyzer.Add(seq)
+		}
+		analyzer.Finalize()
+		for _, l := range lrc.Records {
+			seq, _, _ := sequence.ScanMessage(scanner, l.Message, format)
+			aseq, err = analyzer.Analyze(seq)
+			mtype = "general"
+			if err != nil {
+				standardLogger.LogAnalysisFailed(l, mtype)
+				res.errCount++
+			} else {
+				addToResults(res.amap, aseq, l, sid, svc, true)
+				res.processed++
 			}
 		}
+	}
+	return res
+}
 
-		if batchsize == 0 || infile != "-" {
-			break
+//Merges the results of one service into the batch results. The maps are keyed by
+//the pattern, so if two services produce the same pattern the later service in
+//the merge order takes the entry and the examples and counts are combined, the
+//same as when the services were analyzed one after another into a shared map.
+func mergeResults(dst map[string]sequence.AnalyzerResult, src map[string]sequence.AnalyzerResult) {
+	pats := make([]string, 0, len(src))
+	for pat := range src {
+		pats = append(pats, pat)
+	}
+	sort.Strings(pats)
+	for _, pat := range pats {
+		ar := src[pat]
+		if prev, ok := dst[pat]; ok {
+			ar.Examples = sequence.MergeExamples(prev.Examples, prev.ExampleCount, ar.Examples, ar.ExampleCount)
+			ar.ExampleCount += prev.ExampleCount
+		}
+		dst[pat] = ar
+	}
+}
+
+//Adds the log record to the result for its pattern, new patterns also get
+//their created date and complexity score set.
+func addToResults(rmap map[string]sequence.AnalyzerResult, seq sequence.Sequence, l sequence.LogRecord, sid string, svc string, isNew bool) {
+	pat, pos := seq.String()
+	ar, ok := rmap[pat]
+	if !ok {
+		ar = sequence.AnalyzerResult{}
+	}
+	//the values of the redacted tags are replaced before the message is kept as an example
+	ex := l
+	ex.Message = sequence.RedactMessage(l.Message, seq)
+	sequence.AddExampleToAnalyzerResult(&ar, ex)
+	ar.Service.ID = sid
+	ar.Service.Name = svc
+	ar.TagPositions = sequence.SplitToString(pos, ",")
+	ar.PatternId = sequence.GenerateIDFromString(pat, svc)
+	ar.Pattern = pat
+	ar.ExampleCount++
+	if isNew {
+		ar.DateCreated = time.Now()
+		ar.DateLastMatched = time.Now()
+		ar.ComplexityScore = sequence.CalculatePatternComplexity(seq, len(l.Message))
+	}
+	rmap[pat] = ar
+}
+
+//Saves the analyzed (amap) and parsed (pmap) results to the database or
+//exports them directly to the files when --all is passed.
+func saveResults(amap map[string]sequence.AnalyzerResult, pmap map[string]sequence.AnalyzerResult, processed int, err_count int, startTime time.Time, anTime time.Duration) {
+	if err := saveBatch(amap, pmap, processed, err_count, startTime, anTime); err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+}
+
+//Saves the results the same as saveResults, a database error is returned rather
+//than exiting so the API can answer the request with it.
+func saveBatch(amap map[string]sequence.AnalyzerResult, pmap map[string]sequence.AnalyzerResult, processed int, err_count int, startTime time.Time, anTime time.Duration) error {
+	standardLogger.HandleInfo(fmt.Sprintf("Analysed in: %s\n", anTime))
+	if sequence.GetUseDatabase() && !allinone {
+		standardLogger.HandleDebug("Starting save to the database.")
+		if err := sequence.SaveExistingPatterns(pmap); err != nil {
+			return err
+		}
+		//the new patterns are checked before they are saved
+		alerts := sequence.DetectAlerts(amap, pmap)
+		new, saved, err := sequence.SaveNewPatterns(amap)
+		if err != nil {
+			return err
+		}
+		standardLogger.HandleDebug("Finished save to the database.")
+		standardLogger.AnalyzeInfo(processed, len(amap)+len(pmap), new, saved, err_count, time.Since(startTime), anTime)
+		sequence.SendAlerts(alerts)
+	} else {
+		//the match counts are not saved, so only the new patterns are checked
+		sequence.SendAlerts(sequence.DetectAlerts(amap, nil))
+		//output directly to the files
+		//merge pmap and amap
+		//syslog-ng patterndb
+		cmap := amap
+		for k, v := range pmap {
+			cmap[k] = v
+		}
+		export(cmap)
+		//always output to a txt file for parsing later
+		oFile, _ := sequence.OpenOutputFile("C:\\data\\debug.txt")
+		defer oFile.Close()
+		for pat, stat := range amap {
+			fmt.Fprintf(oFile, "%s\n# %d log messages matched\n# %s\n\n", pat, stat.ExampleCount, stat.Examples[0].Message)
 		}
 	}
+	return nil
 }
 
 func exportPatterns(cmd *cobra.Command, args []string) {
@@ -347,25 +564,58 @@ func exportPatterns(cmd *cobra.Command, args []string) {
 
 func export(cmap map[string]sequence.AnalyzerResult) {
 	startTime := time.Now()
-	if outsystem == "patterndb" {
-		processed, top5, err := syslog_ng_pattern_db.OutputToFiles(outformat, outfile, cfgfile, complimit, cmap, thresholdType, thresholdValue)
-		if err != nil {
-			standardLogger.HandleError(err.Error())
-		} else {
-			standardLogger.ExportPatternsInfo(processed, top5, time.Since(startTime))
-		}
-	} else if outsystem == "grok" {
-		processed, top5, err := logstash_grok.OutputToFiles(outfile, cfgfile, complimit, cmap, thresholdType, thresholdValue)
-		if err != nil {
+	if reviewState != "" && cmap == nil {
+		var err error
+		if cmap, err = patternsInState(reviewState, complimit, thresholdType, thresholdValue); err != nil {
 			standardLogger.HandleError(err.Error())
-		} else {
-			standardLogger.ExportPatternsInfo(processed, top5, time.Since(startTime))
+			return
 		}
+	}
+	processed, top5, err := exportTo(outsystem, outformat, outfile, complimit, cmap, thresholdType, thresholdValue)
+	if err != nil {
+		standardLogger.HandleError(err.Error())
 	} else {
-		standardLogger.HandleError("No export format provided, could not export the patterns.")
+		standardLogger.ExportPatternsInfo(processed, top5, time.Since(startTime))
 	}
 }
 
+//Outputs the patterns to the file for the system, the patterns come from the
+//database when cmap is nil.
+func exportTo(system string, format string, file string, complexity float64, cmap map[string]sequence.AnalyzerResult, ttype string, tvalue string) (int, string, error) {
+	switch system {
+	case "patterndb":
+		return syslog_ng_pattern_db.OutputToFiles(format, file, cfgfile, complexity, cmap, ttype, tvalue)
+	case "grok":
+		return logstash_grok.OutputToFiles(file, cfgfile, complexity, cmap, ttype, tvalue)
+	case "lognorm":
+		return lognorm_rulebase.OutputToFiles(file, cfgfile, complexity, cmap, ttype, tvalue)
+	case "ingest":
+		return elasticsearch_ingest.OutputToFiles(file, cfgfile, complexity, cmap, ttype, tvalue)
+	case "fluentbit":
+		return fluentbit_parser.OutputToFiles(file, cfgfile, complexity, cmap, ttype, tvalue)
+	case "vector":
+		return vector_remap.OutputToFiles(file, cfgfile, complexity, cmap, ttype, tvalue)
+	}
+	return 0, "", fmt.Errorf("No export format provided, could not export the patterns.")
+}
+
+//Returns the patterns in the review state from the database, with the same
+//complexity and threshold filters the exporters use.
+func patternsInState(state string, complexity float64, ttype string, tvalue string) (map[string]sequence.AnalyzerResult, error) {
+	db, ctx := sequence.OpenDbandSetContext()
+	defer db.Close()
+	states, err := sequence.GetPatternReviewStates(db, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ttype == "" {
+		ttype = sequence.GetThresholdType()
+		tvalue = sequence.GetThresholdValue()
+	}
+	pmap, _ := sequence.GetPatternsWithExamplesFromDatabase(db, ctx, complexity, ttype, tvalue)
+	return sequence.FilterPatternsByReviewState(pmap, states, state), nil
+}
+
 func validateInputs(commandType string) {
 	var errors []string
 	err := sequence.ValidateLogLevel(loglevel)
@@ -389,6 +639,10 @@ func validateInputs(commandType string) {
 		if err != "" {
 			errors = append(errors, err)
 		}
+		err = sequence.ValidateWorkers(workers)
+		if err != "" {
+			errors = append(errors, err)
+		}
 		if allinone {
 			err = sequence.ValidateOutFile(outfile)
 			if err != "" {
@@ -458,12 +712,87 @@ func validateInputs(commandType string) {
 				errors = append(errors, "The value for the complexity score limit must be between 0 and 1.")
 			}
 		}
+		//the review state is optional
+		if reviewState != "" {
+			err = sequence.ValidateReviewState(reviewState)
+			if err != "" {
+				errors = append(errors, err)
+			}
+			if !sequence.GetUseDatabase() {
+				errors = append(errors, "The review state can only be used with the database, usedatabase must be true in the config")
+			}
+		}
+	case "fieldnames":
+		if !sequence.GetUseDatabase() {
+			errors = append(errors, "The field names are saved in the database, usedatabase must be true in the config")
+		}
+	case "lineage":
+		if !sequence.GetUseDatabase() {
+			errors = append(errors, "The lineage of the patterns is saved in the database, usedatabase must be true in the config")
+		}
+		if minScore <= 0 || minScore > 1 {
+			errors = append(errors, "The minimum score must be greater than 0 and no more than 1")
+		}
+	case "dump":
+		if !sequence.GetUseDatabase() {
+			errors = append(errors, "The bundle is dumped from the database, usedatabase must be true in the config")
+		}
+		if outformat != "" {
+			err := sequence.ValidateBundleFormat(outformat)
+			if err != "" {
+				errors = append(errors, err)
+			}
+		}
+		if reviewState != "" {
+			err := sequence.ValidateReviewState(reviewState)
+			if err != "" {
+				errors = append(errors, err)
+			}
+		}
+	case "load":
+		if !sequence.GetUseDatabase() {
+			errors = append(errors, "The bundle is loaded into the database, usedatabase must be true in the config")
+		}
+		if infile == "" {
+			errors = append(errors, "Invalid input file specified, pass the bundle with -i or - for stdin")
+		}
+	case "migrate":
+		if !sequence.GetUseDatabase() {
+			errors = append(errors, "The migrations are for the database, usedatabase must be true in the config")
+		}
+	case "stats":
+		if !sequence.GetUseDatabase() {
+			errors = append(errors, "The match counts are saved in the database, usedatabase must be true in the config")
+		}
+		if window <= 0 {
+			errors = append(errors, "The window must be greater than 0, eg 24h")
+		}
+		if growth <= 1 {
+			errors = append(errors, "The growth must be greater than 1")
+		}
+	case "review":
+		if !sequence.GetUseDatabase() {
+			errors = append(errors, "The review workflow needs the database, usedatabase must be true in the config")
+		}
+		if reviewState != "" {
+			err := sequence.ValidateReviewState(reviewState)
+			if err != "" {
+				errors = append(errors, err)
+			}
+		}
 	case "createdatabase":
-		//create database only works with Sqlite3 at the moment.
 		err = sequence.ValidateType(dbtype)
 		if err != "" {
 			errors = append(errors, err)
 		}
+		if dbname != "" {
+			err = sequence.ValidateDatabaseName(dbname)
+			if err != "" {
+				errors = append(errors, err)
+			}
+		} else if dbtype == "mssql" {
+			errors = append(errors, "SQL Server needs the name of the database to create (--name)")
+		}
 		//validate input file
 		if dbconn == "" {
 			errors = append(errors, "Invalid database connection details specified")
@@ -492,6 +821,60 @@ func validateInputs(commandType string) {
 		if infile == "" {
 			errors = append(errors, "Invalid input file specified")
 		}
+	case "analyzetimes":
+		if infile == "" {
+			errors = append(errors, "Invalid input file specified")
+		}
+		err := sequence.ValidateInformat(informat)
+		if err != "" {
+			errors = append(errors, err)
+		}
+		if minCount < 1 {
+			errors = append(errors, "The minimum count must be 1 or more")
+		}
+	case "serve":
+		if httpAddr == "" {
+			errors = append(errors, "The address for the API must be specified")
+		}
+		if !sequence.GetUseDatabase() {
+			errors = append(errors, "The API needs the database, usedatabase must be true in the config")
+		}
+		err := sequence.ValidateWorkers(workers)
+		if err != "" {
+			errors = append(errors, err)
+		}
+	case "listen":
+		if syslogUDP == "" && syslogTCP == "" {
+			errors = append(errors, "At least one of the udp or tcp listen addresses must be specified")
+		}
+		err := sequence.ValidateBatchSize(batchsize)
+		if err != "" {
+			errors = append(errors, err)
+		}
+		if flushInterval < 0 {
+			errors = append(errors, "The flush interval cannot be negative")
+		}
+		if batchsize == 0 && flushInterval == 0 {
+			errors = append(errors, "Either a batch size or a flush interval must be specified so the batches are processed")
+		}
+		err = sequence.ValidateWorkers(workers)
+		if err != "" {
+			errors = append(errors, err)
+		}
+		if allinone {
+			err = sequence.ValidateOutFile(outfile)
+			if err != "" {
+				errors = append(errors, err)
+			}
+			err = sequence.ValidateOutsystem(outsystem)
+			if err != "" {
+				errors = append(errors, err)
+			}
+			err = sequence.ValidateOutformat(outformat)
+			if err != "" {
+				errors = append(errors, err)
+			}
+		}
 	}
 	exs := ""
 	for i, ex := range errors {
@@ -511,12 +894,18 @@ func warnExtraInputs(commandType string, all bool) {
 		if purgeThreshold != 0 {
 			extras = append(extras, "purge threshold (-t)")
 		}
+		if incremental && workers != 1 {
+			extras = append(extras, "workers (-w)")
+		}
 		if dbconn != "" {
 			extras = append(extras, "connection string (--conn)")
 		}
 		if dbtype != "" {
 			extras = append(extras, "database type (--type)")
 		}
+		if dbname != "" {
+			extras = append(extras, "database name (--name)")
+		}
 		if !all {
 			if outfile != "" {
 				extras = append(extras, "output file (-o)")
@@ -557,6 +946,9 @@ func warnExtraInputs(commandType string, all bool) {
 		if dbtype != "" {
 			extras = append(extras, "database type (--type)")
 		}
+		if dbname != "" {
+			extras = append(extras, "database name (--name)")
+		}
 		if all {
 			extras = append(extras, "all in one (--all)")
 		}
@@ -573,6 +965,43 @@ func warnExtraInputs(commandType string, all bool) {
 		if dbtype != "" {
 			extras = append(extras, "database type (--type)")
 		}
+		if dbname != "" {
+			extras = append(extras, "database name (--name)")
+		}
+		if outformat != "" {
+			extras = append(extras, "output format (-f)")
+		}
+		if outsystem != "" {
+			extras = append(extras, "output system (-s)")
+		}
+		if complimit != 1 {
+			extras = append(extras, "complexity score limit (-c)")
+		}
+		if thresholdValue != "0" {
+			extras = append(extras, "threshold value (-v)")
+		}
+		if thresholdType != "" {
+			extras = append(extras, "threshold type (-y)")
+		}
+		if all {
+			extras = append(extras, "all in one (--all)")
+		}
+	case "analyzetimes":
+		if purgeThreshold != 0 {
+			extras = append(extras, "purge threshold (-t)")
+		}
+		if batchsize != 0 {
+			extras = append(extras, "batch size (-b)")
+		}
+		if dbconn != "" {
+			extras = append(extras, "connection string (--conn)")
+		}
+		if dbtype != "" {
+			extras = append(extras, "database type (--type)")
+		}
+		if dbname != "" {
+			extras = append(extras, "database name (--name)")
+		}
 		if outformat != "" {
 			extras = append(extras, "output format (-f)")
 		}
@@ -592,7 +1021,6 @@ func warnExtraInputs(commandType string, all bool) {
 			extras = append(extras, "all in one (--all)")
 		}
 	case "createdatabase":
-		//create database only works with Sqlite3 at the moment.
 		if purgeThreshold != 0 {
 			extras = append(extras, "purge threshold (-t)")
 		}
@@ -661,6 +1089,9 @@ func warnExtraInputs(commandType string, all bool) {
 		if dbtype != "" {
 			extras = append(extras, "database type (--type)")
 		}
+		if dbname != "" {
+			extras = append(extras, "database name (--name)")
+		}
 		if all {
 			extras = append(extras, "all in one (--all)")
 		}
@@ -698,9 +1129,159 @@ func warnExtraInputs(commandType string, all bool) {
 		if dbtype != "" {
 			extras = append(extras, "database type (--type)")
 		}
+		if dbname != "" {
+			extras = append(extras, "database name (--name)")
+		}
+		if all {
+			extras = append(extras, "all in one (--all)")
+		}
+	case "review", "fieldnames", "lineage", "stats", "migrate":
+		if purgeThreshold != 0 {
+			extras = append(extras, "purge threshold (-t)")
+		}
+		if informat != "" {
+			extras = append(extras, "input format (-k)")
+		}
+		if batchsize != 0 {
+			extras = append(extras, "batch size (-b)")
+		}
+		if outformat != "" {
+			extras = append(extras, "output format (-f)")
+		}
+		if outsystem != "" {
+			extras = append(extras, "output system (-s)")
+		}
+		if complimit != 1 {
+			extras = append(extras, "complexity score limit (-c)")
+		}
+		if thresholdValue != "0" {
+			extras = append(extras, "threshold value (-v)")
+		}
+		if thresholdType != "" {
+			extras = append(extras, "threshold type (-y)")
+		}
+		if dbconn != "" {
+			extras = append(extras, "connection string (--conn)")
+		}
+		if dbtype != "" {
+			extras = append(extras, "database type (--type)")
+		}
+		if dbname != "" {
+			extras = append(extras, "database name (--name)")
+		}
+		if all {
+			extras = append(extras, "all in one (--all)")
+		}
+	case "dump", "load":
+		if purgeThreshold != 0 {
+			extras = append(extras, "purge threshold (-t)")
+		}
+		if informat != "" {
+			extras = append(extras, "input format (-k)")
+		}
+		if batchsize != 0 {
+			extras = append(extras, "batch size (-b)")
+		}
+		if outsystem != "" {
+			extras = append(extras, "output system (-s)")
+		}
+		if complimit != 1 {
+			extras = append(extras, "complexity score limit (-c)")
+		}
+		if thresholdValue != "0" {
+			extras = append(extras, "threshold value (-v)")
+		}
+		if thresholdType != "" {
+			extras = append(extras, "threshold type (-y)")
+		}
+		if dbconn != "" {
+			extras = append(extras, "connection string (--conn)")
+		}
+		if dbtype != "" {
+			extras = append(extras, "database type (--type)")
+		}
+		if dbname != "" {
+			extras = append(extras, "database name (--name)")
+		}
 		if all {
 			extras = append(extras, "all in one (--all)")
 		}
+	case "serve":
+		if purgeThreshold != 0 {
+			extras = append(extras, "purge threshold (-t)")
+		}
+		if infile != "" {
+			extras = append(extras, "input file (-i)")
+		}
+		if informat != "" {
+			extras = append(extras, "input format (-k)")
+		}
+		if batchsize != 0 {
+			extras = append(extras, "batch size (-b)")
+		}
+		if outfile != "" {
+			extras = append(extras, "output file (-o)")
+		}
+		if outformat != "" {
+			extras = append(extras, "output format (-f)")
+		}
+		if outsystem != "" {
+			extras = append(extras, "output system (-s)")
+		}
+		if complimit != 1 {
+			extras = append(extras, "complexity score limit (-c)")
+		}
+		if thresholdValue != "0" {
+			extras = append(extras, "threshold value (-v)")
+		}
+		if thresholdType != "" {
+			extras = append(extras, "threshold type (-y)")
+		}
+		if dbconn != "" {
+			extras = append(extras, "connection string (--conn)")
+		}
+		if dbtype != "" {
+			extras = append(extras, "database type (--type)")
+		}
+		if dbname != "" {
+			extras = append(extras, "database name (--name)")
+		}
+		if all {
+			extras = append(extras, "all in one (--all)")
+		}
+	case "listen":
+		if purgeThreshold != 0 {
+			extras = append(extras, "purge threshold (-t)")
+		}
+		if incremental && workers != 1 {
+			extras = append(extras, "workers (-w)")
+		}
+		if infile != "" {
+			extras = append(extras, "input file (-i)")
+		}
+		if informat != "" {
+			extras = append(extras, "input format (-k)")
+		}
+		if dbconn != "" {
+			extras = append(extras, "connection string (--conn)")
+		}
+		if dbtype != "" {
+			extras = append(extras, "database type (--type)")
+		}
+		if dbname != "" {
+			extras = append(extras, "database name (--name)")
+		}
+		if !all {
+			if outfile != "" {
+				extras = append(extras, "output file (-o)")
+			}
+			if outformat != "" {
+				extras = append(extras, "output format (-f)")
+			}
+			if outsystem != "" {
+				extras = append(extras, "output system (-s)")
+			}
+		}
 	}
 	// Build message
 	for _, w := range extras {
@@ -736,6 +1317,10 @@ func readConfig() {
 	sequence.SetLogger(standardLogger)
 	syslog_ng_pattern_db.SetLogger(standardLogger)
 	logstash_grok.SetLogger(standardLogger)
+	lognorm_rulebase.SetLogger(standardLogger)
+	elasticsearch_ingest.SetLogger(standardLogger)
+	fluentbit_parser.SetLogger(standardLogger)
+	vector_remap.SetLogger(standardLogger)
 }
 
 func main() {
@@ -773,6 +1358,16 @@ func main() {
 			Short: "outputs a list of patterns to the files in the formats requested.",
 		}
 
+		listenCmd = &cobra.Command{
+			Use:   "listen",
+			Short: "receives syslog messages over udp and/or tcp and analyzes them by service in batches",
+		}
+
+		serveCmd = &cobra.Command{
+			Use:   "serve",
+			Short: "serves a REST API to parse and analyze messages and to list, ignore and export the patterns",
+		}
+
 		updateIgnoreCmd = &cobra.Command{
 			Use:   "updateignorepatterns",
 			Short: "outputs a list of patterns to the files in the formats requested.",
@@ -783,8 +1378,8 @@ func main() {
 	sequenceCmd.PersistentFlags().StringVarP(&infile, "input", "i", "", "input file, required, if - then stdin")
 	sequenceCmd.PersistentFlags().StringVarP(&outfile, "output", "o", "", "output file, if omitted, to stdout, if multiple out-formats will use the same file name with diff extensions")
 	sequenceCmd.PersistentFlags().StringVarP(&patfile, "patterns", "p", "", "existing patterns text file, can be a file or directory")
-	sequenceCmd.PersistentFlags().StringVarP(&outformat, "out-format", "f", "", "format of the output file, can be yaml, xml or txt or a combo comma separated eg txt,xml, if empty it uses text, used by analyze")
-	sequenceCmd.PersistentFlags().StringVarP(&outsystem, "out-system", "s", "", "system that will use the output, not needed if use database is set to true in the config, valid values are patterndb and grok, used by analyzebyservice")
+	sequenceCmd.PersistentFlags().StringVarP(&outformat, "out-format", "f", "", "format of the output file, can be yaml, xml or txt or a combo comma separated eg txt,xml, if empty it uses text, used by analyze, dump takes json or yaml")
+	sequenceCmd.PersistentFlags().StringVarP(&outsystem, "out-system", "s", "", "system that will use the output, not needed if use database is set to true in the config, valid values are patterndb, grok, lognorm, ingest, fluentbit and vector, used by analyzebyservice")
 	sequenceCmd.PersistentFlags().StringVarP(&informat, "in-format", "k", "", "format of the input data, can be json or txt, if empty it uses txt, used by analyze")
 	sequenceCmd.PersistentFlags().IntVarP(&batchsize, "batch-size", "b", 0, "if using a large file or stdin, the batch size sets the limit of how many to process at one time")
 	sequenceCmd.PersistentFlags().StringVarP(&logfile, "log-file", "l", "", "location of log file if different from the exe directory")
@@ -796,6 +1391,21 @@ func main() {
 	sequenceCmd.PersistentFlags().BoolVarP(&allinone, "all", "", false, "if passed to analyzebyservice it by passes saving to the database and directly out puts the patterns.")
 	sequenceCmd.PersistentFlags().StringVarP(&dbtype, "type", "", "", "type of the database when creating it, can mssql, postgres, sqlite3 or mysql")
 	sequenceCmd.PersistentFlags().StringVarP(&dbconn, "conn", "", "", "connection details for the server")
+	sequenceCmd.PersistentFlags().StringVarP(&dbname, "name", "", "", "name of the database to create on the server, used by createdatabase with mssql, postgres and mysql")
+	sequenceCmd.PersistentFlags().StringVarP(&syslogUDP, "udp", "", "", "address to receive syslog messages on over udp, eg :514, used by listen")
+	sequenceCmd.PersistentFlags().StringVarP(&syslogTCP, "tcp", "", "", "address to receive syslog messages on over tcp, eg :601, used by listen")
+	sequenceCmd.PersistentFlags().BoolVarP(&incremental, "incremental", "", false, "used by analyzebyservice and listen, keeps the analyzers between batches and absorbs each new message into them, pattern changes are logged")
+	sequenceCmd.PersistentFlags().IntVarP(&workers, "workers", "w", 1, "number of services analyzed at the same time by analyzebyservice and listen, defaults to 1, not used with --incremental")
+	sequenceCmd.PersistentFlags().StringVarP(&httpAddr, "http", "", ":8080", "address to serve the REST API on, used by serve")
+	sequenceCmd.PersistentFlags().StringVarP(&reviewState, "state", "", "", "review state of the patterns, used by exportpatterns and dump to only export the patterns in the state, eg approved, and by review list")
+	sequenceCmd.PersistentFlags().StringVarP(&reviewer, "reviewer", "", defaultReviewer(), "name recorded in the review history by the review commands, updateignorepatterns and lineage migrate, defaults to the current user")
+	sequenceCmd.PersistentFlags().StringVarP(&reviewComment, "comment", "", "", "comment recorded in the review history by the review commands")
+	sequenceCmd.PersistentFlags().Float64VarP(&minScore, "min-score", "", 0.7, "used by lineage detect, how well the tokens of a new pattern must line up with an old pattern, between 0 and 1")
+	sequenceCmd.PersistentFlags().DurationVarP(&window, "window", "", time.Hour*24, "used by stats, the time up to now the matches are compared with the same time before it")
+	sequenceCmd.PersistentFlags().Float64VarP(&growth, "growth", "", 2, "used by stats, a pattern is growing when it has this many times the matches of the window before")
+	sequenceCmd.PersistentFlags().IntVarP(&minCount, "min-count", "", 2, "used by analyzetimes, the fewest times a shape of time must be in the messages to be proposed")
+	sequenceCmd.PersistentFlags().BoolVarP(&updateConfig, "update-config", "", false, "used by analyzetimes, adds the proposed time formats to the config file")
+	sequenceCmd.PersistentFlags().DurationVarP(&flushInterval, "flush-interval", "", time.Minute, "the longest time listen waits before processing a batch that has not reached the batch size, 0 to only use the batch size")
 
 	scanCmd.Run = scan
 	createDatabaseCmd.Run = createdatabase
@@ -803,6 +1413,8 @@ func main() {
 	analyzeByServiceCmd.Run = analyzebyservice
 	exportPatternsCmd.Run = exportPatterns
 	updateIgnoreCmd.Run = updateignorepatterns
+	listenCmd.Run = listen
+	serveCmd.Run = serve
 
 	sequenceCmd.AddCommand(scanCmd)
 	sequenceCmd.AddCommand(createDatabaseCmd)
@@ -810,6 +1422,16 @@ func main() {
 	sequenceCmd.AddCommand(analyzeByServiceCmd)
 	sequenceCmd.AddCommand(exportPatternsCmd)
 	sequenceCmd.AddCommand(updateIgnoreCmd)
+	sequenceCmd.AddCommand(listenCmd)
+	sequenceCmd.AddCommand(serveCmd)
+	sequenceCmd.AddCommand(newReviewCmd())
+	sequenceCmd.AddCommand(newFieldNamesCmd())
+	sequenceCmd.AddCommand(newLineageCmd())
+	sequenceCmd.AddCommand(newStatsCmd())
+	sequenceCmd.AddCommand(newMigrateCmd())
+	sequenceCmd.AddCommand(newDumpCmd())
+	sequenceCmd.AddCommand(newLoadCmd())
+	sequenceCmd.AddCommand(newAnalyzeTimesCmd())
 
 	sequenceCmd.Execute()
 }
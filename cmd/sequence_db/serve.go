@@ -0,0 +1,393 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/spf13/cobra"
+)
+
+//The largest request body accepted by the API.
+const maxRequestSize = 32 << 20
+
+//Serializes the requests that write to the database or use the exporters, as the
+//exporters keep their settings in package variables. Parsing does not need it,
+//the parsers are safe to share and are kept in the parser cache per service
+//until new patterns are saved for the service.
+var apiLock sync.Mutex
+
+type parseRequest struct {
+	Service string `json:"service"`
+	Message string `json:"message"`
+}
+
+type parseResponse struct {
+	Service   string            `json:"service"`
+	PatternId string            `json:"pattern_id"`
+	Pattern   string            `json:"pattern"`
+	Fields    map[string]string `json:"fields"`
+	Times     map[string]string `json:"times,omitempty"`
+}
+
+type analyzeResponse struct {
+	Processed int          `json:"processed"`
+	Errors    int          `json:"errors"`
+	New       []apiPattern `json:"new"`
+	Matched   []apiPattern `json:"matched"`
+}
+
+type apiPattern struct {
+	PatternId       string               `json:"id"`
+	Service         string               `json:"service"`
+	Pattern         string               `json:"pattern"`
+	TagPositions    string               `json:"tag_positions"`
+	ExampleCount    int                  `json:"example_count"`
+	ComplexityScore float64              `json:"complexity_score"`
+	DateCreated     time.Time            `json:"date_created"`
+	DateLastMatched time.Time            `json:"date_last_matched"`
+	Examples        []sequence.LogRecord `json:"examples,omitempty"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+//Serves the REST API, see newAPIHandler for the endpoints.
+func serve(cmd *cobra.Command, args []string) {
+	start("serve")
+	standardLogger.HandleInfo(fmt.Sprintf("Serving the API on %s", httpAddr))
+	if err := http.ListenAndServe(httpAddr, newAPIHandler()); err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+}
+
+//The endpoints are:
+//  POST /parse                 parses {"service", "message"} with the patterns of the service
+//  POST /analyze               analyzes a list of {"service", "message"} and saves the patterns
+//  GET  /patterns              lists the patterns, filtered by complexity, thresholdtype, thresholdvalue and state
+//  POST /patterns/{id}/ignore  marks the pattern to be ignored, the reviewer can be passed in the query
+//  GET  /export                exports the patterns for the system, with the same filters as /patterns
+func newAPIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/parse", handleParse)
+	mux.HandleFunc("/analyze", handleAnalyze)
+	mux.HandleFunc("/patterns", handlePatterns)
+	mux.HandleFunc("/patterns/", handleIgnore)
+	mux.HandleFunc("/export", handleExport)
+	return mux
+}
+
+func handleParse(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req parseRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Service == "" || req.Message == "" {
+		writeError(w, http.StatusBadRequest, "The service and message are required")
+		return
+	}
+	sid := sequence.GenerateIDFromString("", req.Service)
+	parser := sequence.BuildParserFromDb(sid)
+	seq, _, err := sequence.ScanMessage(sequence.NewScanner(), req.Message, format)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	pseq, err := parser.Parse(seq)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	pat, _ := pseq.String()
+	writeJSON(w, http.StatusOK, parseResponse{
+		Service:   req.Service,
+		PatternId: sequence.GenerateIDFromString(pat, req.Service),
+		Pattern:   pat,
+		Fields:    pseq.Fields(),
+		Times:     pseq.Times(),
+	})
+}
+
+func handleAnalyze(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodPost) {
+		return
+	}
+	var records []sequence.LogRecord
+	if err := decodeRequest(w, r, &records); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	lrMap := make(map[string]sequence.LogRecordCollection)
+	for _, lr := range records {
+		if lr.Service == "" || lr.Message == "" {
+			writeError(w, http.StatusBadRequest, "The service and message are required for every record")
+			return
+		}
+		sequence.AddLogRecordToMap(lrMap, lr)
+	}
+	if len(records) == 0 {
+		writeJSON(w, http.StatusOK, analyzeResponse{New: []apiPattern{}, Matched: []apiPattern{}})
+		return
+	}
+
+	apiLock.Lock()
+	defer apiLock.Unlock()
+	startTime := time.Now()
+	standardLogger.HandleInfo(fmt.Sprintf("Received %d records over the API, starting analysis..", len(records)))
+	res, anTime := analyzeRecords(sequence.NewScanner(), lrMap)
+	//the response is built before the save, which can change the maps
+	resp := analyzeResponse{
+		Processed: res.processed,
+		Errors:    res.errCount,
+		New:       toAPIPatterns(res.amap, false),
+		Matched:   toAPIPatterns(res.pmap, false),
+	}
+	if err := saveBatch(res.amap, res.pmap, res.processed, res.errCount, startTime, anTime); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func handlePatterns(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	complexity, ttype, tvalue, err := readFilters(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	state := r.URL.Query().Get("state")
+	if msg := sequence.ValidateReviewState(state); state != "" && msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	db, ctx := sequence.OpenDbandSetContext()
+	defer db.Close()
+	pmap, _ := sequence.GetPatternsWithExamplesFromDatabase(db, ctx, complexity, ttype, tvalue)
+	if state != "" {
+		states, err := sequence.GetPatternReviewStates(db, ctx)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		pmap = sequence.FilterPatternsByReviewState(pmap, states, state)
+	}
+	if svc := r.URL.Query().Get("service"); svc != "" {
+		for id, ar := range pmap {
+			if ar.Service.Name != svc {
+				delete(pmap, id)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, toAPIPatterns(pmap, true))
+}
+
+func handleIgnore(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "patterns" || parts[2] != "ignore" || parts[1] == "" {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	if !checkMethod(w, r, http.MethodPost) {
+		return
+	}
+	apiLock.Lock()
+	defer apiLock.Unlock()
+	name := r.URL.Query().Get("reviewer")
+	if name == "" {
+		name = "api"
+	}
+	if err := sequence.IgnorePattern(parts[1], name); err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("The pattern %s does not exist", parts[1]))
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id": parts[1], "status": "ignored"})
+}
+
+func handleExport(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	q := r.URL.Query()
+	system := q.Get("system")
+	fmat := strings.ToLower(q.Get("format"))
+	if fmat == "" {
+		fmat = "txt"
+	}
+	if msg := sequence.ValidateOutsystem(system); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := sequence.ValidateOutformat(fmat); msg != "" || strings.Contains(fmat, ",") {
+		writeError(w, http.StatusBadRequest, "Valid values for format are: xml or yaml (for patterndb) or txt")
+		return
+	}
+	complexity, ttype, tvalue, err := readFilters(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	state := q.Get("state")
+	if msg := sequence.ValidateReviewState(state); state != "" && msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	apiLock.Lock()
+	defer apiLock.Unlock()
+	dir, err := ioutil.TempDir("", "sequence-export")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer os.RemoveAll(dir)
+	//patterndb adds the extension for the format to the file name
+	file := filepath.Join(dir, "export")
+	ofile := file
+	if system == "patterndb" {
+		ofile = file + "." + fmat
+	}
+	startTime := time.Now()
+	var cmap map[string]sequence.AnalyzerResult
+	if state != "" {
+		if cmap, err = patternsInState(state, complexity, ttype, tvalue); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	processed, top5, err := exportTo(system, fmat, file, complexity, cmap, ttype, tvalue)
+	if err != nil {
+		standardLogger.HandleError(err.Error())
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	standardLogger.ExportPatternsInfo(processed, top5, time.Since(startTime))
+	data, err := ioutil.ReadFile(ofile)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", exportContentType(system, fmat))
+	w.Header().Set("X-Sequence-Patterns", strconv.Itoa(processed))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+//Reads the complexity and threshold filters from the query, the threshold
+//defaults to the values in the config, the same as exportpatterns.
+func readFilters(r *http.Request) (float64, string, string, error) {
+	q := r.URL.Query()
+	complexity := 1.0
+	if c := q.Get("complexity"); c != "" {
+		var err error
+		if complexity, err = strconv.ParseFloat(c, 64); err != nil || complexity < 0 || complexity > 1 {
+			return 0, "", "", errors.New("The value for the complexity score limit must be between 0 and 1")
+		}
+	}
+	ttype := q.Get("thresholdtype")
+	tvalue := q.Get("thresholdvalue")
+	if ttype == "" && tvalue == "" {
+		return complexity, sequence.GetThresholdType(), sequence.GetThresholdValue(), nil
+	}
+	if msg := sequence.ValidateThresholdType(ttype); msg != "" {
+		return 0, "", "", errors.New(msg)
+	}
+	if msg := sequence.ValidateThresholdValue(ttype, tvalue); msg != "" {
+		return 0, "", "", errors.New(msg)
+	}
+	return complexity, ttype, tvalue, nil
+}
+
+//Returns the patterns sorted by the number of matches, the examples are only
+//included when asked for.
+func toAPIPatterns(pmap map[string]sequence.AnalyzerResult, examples bool) []apiPattern {
+	pats := make([]apiPattern, 0, len(pmap))
+	for _, ar := range pmap {
+		p := apiPattern{
+			PatternId:       ar.PatternId,
+			Service:         ar.Service.Name,
+			Pattern:         ar.Pattern,
+			TagPositions:    ar.TagPositions,
+			ExampleCount:    ar.ExampleCount,
+			ComplexityScore: ar.ComplexityScore,
+			DateCreated:     ar.DateCreated,
+			DateLastMatched: ar.DateLastMatched,
+		}
+		if examples {
+			p.Examples = ar.Examples
+		}
+		pats = append(pats, p)
+	}
+	sort.Slice(pats, func(i, j int) bool {
+		if pats[i].ExampleCount != pats[j].ExampleCount {
+			return pats[i].ExampleCount > pats[j].ExampleCount
+		}
+		return pats[i].PatternId < pats[j].PatternId
+	})
+	return pats
+}
+
+func exportContentType(system string, fmat string) string {
+	switch {
+	case system == "ingest":
+		return "application/json"
+	case system == "patterndb" && fmat == "xml":
+		return "application/xml"
+	case system == "patterndb" && fmat == "yaml":
+		return "application/yaml"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+func checkMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("The method must be %s", method))
+		return false
+	}
+	return true
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("Invalid request body: %s", err.Error())
+	}
+	return nil
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	if status >= http.StatusInternalServerError {
+		standardLogger.HandleError(msg)
+	}
+	writeJSON(w, status, apiError{Error: msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		standardLogger.HandleError(fmt.Sprintf("Unable to write the API response: %s", err.Error()))
+	}
+}
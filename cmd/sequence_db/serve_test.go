@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/stretchr/testify/require"
+)
+
+var apiMessages = []string{
+	"Accepted password for root from 10.0.0.1 port 22 ssh2",
+	"Accepted password for admin from 10.0.0.2 port 2222 ssh2",
+	"Accepted password for guest from 10.0.0.3 port 22 ssh2",
+}
+
+//Reads the config of the repo into a new sqlite database, so the handlers can
+//run without a server.
+func setupAPI(t *testing.T) http.Handler {
+	dir := t.TempDir()
+	config, err := ioutil.ReadFile("../../sequence.toml")
+	require.NoError(t, err)
+	conn := filepath.Join(dir, "sequence.sdb")
+	config = bytes.Replace(config, []byte(`connectioninfo = "sequence.sdb"`), []byte(`connectioninfo = "`+conn+`"`), 1)
+	cfgfile = filepath.Join(dir, "sequence.toml")
+	require.NoError(t, ioutil.WriteFile(cfgfile, config, 0644))
+	t.Cleanup(func() { cfgfile = "" })
+
+	standardLogger = sequence.NewLogger(filepath.Join(dir, "sequence.log"), "error")
+	readConfig()
+	require.NoError(t, sequence.CreateDatabase(conn, "sqlite3", ""))
+	_, err = sequence.MigrateUp()
+	require.NoError(t, err)
+	return newAPIHandler()
+}
+
+func doRequest(t *testing.T, h http.Handler, method string, url string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	switch b := body.(type) {
+	case nil:
+	case string:
+		buf.WriteString(b)
+	default:
+		require.NoError(t, json.NewEncoder(&buf).Encode(b))
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, url, &buf))
+	return w
+}
+
+//Analyzes the messages over the API and returns the id of the saved pattern.
+func analyzeMessages(t *testing.T, h http.Handler) string {
+	var records []sequence.LogRecord
+	for _, msg := range apiMessages {
+		records = append(records, sequence.LogRecord{Service: "sshd", Message: msg})
+	}
+	w := doRequest(t, h, http.MethodPost, "/analyze", records)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp analyzeResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal(t, len(apiMessages), resp.Processed)
+	require.Len(t, resp.New, 1)
+	require.Equal(t, len(apiMessages), resp.New[0].ExampleCount)
+	return resp.New[0].PatternId
+}
+
+func TestAPIAnalyze(t *testing.T) {
+	h := setupAPI(t)
+	tests := []struct {
+		method string
+		body   interface{}
+		status int
+	}{
+		{http.MethodGet, nil, http.StatusMethodNotAllowed},
+		{http.MethodPost, "not json", http.StatusBadRequest},
+		{http.MethodPost, []sequence.LogRecord{{Service: "sshd"}}, http.StatusBadRequest},
+		{http.MethodPost, []sequence.LogRecord{}, http.StatusOK},
+	}
+	for _, tc := range tests {
+		w := doRequest(t, h, tc.method, "/analyze", tc.body)
+		require.Equal(t, tc.status, w.Code, w.Body.String())
+	}
+	analyzeMessages(t, h)
+
+	//the messages match the saved pattern the second time
+	var records []sequence.LogRecord
+	for _, msg := range apiMessages {
+		records = append(records, sequence.LogRecord{Service: "sshd", Message: msg})
+	}
+	w := doRequest(t, h, http.MethodPost, "/analyze", records)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp analyzeResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.New, 0)
+	require.Len(t, resp.Matched, 1)
+}
+
+func TestAPIParse(t *testing.T) {
+	h := setupAPI(t)
+	id := analyzeMessages(t, h)
+	tests := []struct {
+		method string
+		body   interface{}
+		status int
+	}{
+		{http.MethodGet, nil, http.StatusMethodNotAllowed},
+		{http.MethodPost, "{", http.StatusBadRequest},
+		{http.MethodPost, parseRequest{Service: "sshd"}, http.StatusBadRequest},
+		{http.MethodPost, parseRequest{Message: apiMessages[0]}, http.StatusBadRequest},
+		{http.MethodPost, parseRequest{Service: "sshd", Message: "no pattern matches this message"}, http.StatusNotFound},
+		{http.MethodPost, parseRequest{Service: "sshd", Message: "Accepted password for bob from 10.1.1.1 port 22 ssh2"}, http.StatusOK},
+	}
+	for _, tc := range tests {
+		w := doRequest(t, h, tc.method, "/parse", tc.body)
+		require.Equal(t, tc.status, w.Code, w.Body.String())
+		if tc.status == http.StatusOK {
+			var resp parseResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			require.Equal(t, id, resp.PatternId)
+			require.NotEmpty(t, resp.Fields)
+		}
+	}
+}
+
+func TestAPIPatterns(t *testing.T) {
+	h := setupAPI(t)
+	id := analyzeMessages(t, h)
+	tests := []struct {
+		url    string
+		status int
+		count  int
+	}{
+		{"/patterns", http.StatusOK, 1},
+		{"/patterns?service=sshd", http.StatusOK, 1},
+		{"/patterns?service=other", http.StatusOK, 0},
+		{"/patterns?thresholdtype=count&thresholdvalue=10", http.StatusOK, 0},
+		{"/patterns?complexity=2", http.StatusBadRequest, 0},
+		{"/patterns?thresholdtype=size&thresholdvalue=1", http.StatusBadRequest, 0},
+		{"/patterns?state=unknown", http.StatusBadRequest, 0},
+	}
+	for _, tc := range tests {
+		w := doRequest(t, h, http.MethodGet, tc.url, nil)
+		require.Equal(t, tc.status, w.Code, tc.url+" "+w.Body.String())
+		if tc.status == http.StatusOK {
+			var pats []apiPattern
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&pats))
+			require.Len(t, pats, tc.count, tc.url)
+			if tc.count > 0 {
+				require.Equal(t, id, pats[0].PatternId)
+				require.Len(t, pats[0].Examples, len(apiMessages))
+			}
+		}
+	}
+	w := doRequest(t, h, http.MethodPost, "/patterns", nil)
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestAPIIgnore(t *testing.T) {
+	h := setupAPI(t)
+	id := analyzeMessages(t, h)
+	tests := []struct {
+		method string
+		url    string
+		status int
+	}{
+		{http.MethodPost, "/patterns/" + id, http.StatusNotFound},
+		{http.MethodPost, "/patterns/" + id + "/delete", http.StatusNotFound},
+		{http.MethodGet, "/patterns/" + id + "/ignore", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/patterns/missing/ignore", http.StatusNotFound},
+		{http.MethodPost, "/patterns/" + id + "/ignore?reviewer=tester", http.StatusOK},
+	}
+	for _, tc := range tests {
+		w := doRequest(t, h, tc.method, tc.url, nil)
+		require.Equal(t, tc.status, w.Code, tc.url+" "+w.Body.String())
+	}
+	//the ignored patterns are not listed
+	w := doRequest(t, h, http.MethodGet, "/patterns", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var pats []apiPattern
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&pats))
+	require.Len(t, pats, 0)
+}
+
+func TestAPIExport(t *testing.T) {
+	h := setupAPI(t)
+	analyzeMessages(t, h)
+	tests := []struct {
+		url         string
+		status      int
+		contentType string
+	}{
+		{"/export?system=grok", http.StatusOK, "text/plain; charset=utf-8"},
+		{"/export?system=patterndb&format=xml", http.StatusOK, "application/xml"},
+		{"/export?system=patterndb&format=yaml", http.StatusOK, "application/yaml"},
+		{"/export?system=ingest", http.StatusOK, "application/json"},
+		{"/export", http.StatusBadRequest, ""},
+		{"/export?system=splunk", http.StatusBadRequest, ""},
+		{"/export?system=patterndb&format=xml,yaml", http.StatusBadRequest, ""},
+		{"/export?system=grok&complexity=-1", http.StatusBadRequest, ""},
+		{"/export?system=grok&state=unknown", http.StatusBadRequest, ""},
+	}
+	for _, tc := range tests {
+		w := doRequest(t, h, http.MethodGet, tc.url, nil)
+		require.Equal(t, tc.status, w.Code, tc.url+" "+w.Body.String())
+		if tc.status == http.StatusOK {
+			require.Equal(t, tc.contentType, w.Header().Get("Content-Type"), tc.url)
+			require.Equal(t, "1", w.Header().Get("X-Sequence-Patterns"), tc.url)
+			require.True(t, strings.Contains(w.Body.String(), "srcuser"), tc.url)
+		}
+	}
+	w := doRequest(t, h, http.MethodPost, "/export?system=grok", nil)
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/spf13/cobra"
+)
+
+//Reports the new, growing and vanished patterns of the window, comparing the match
+//counts of the window with the window before it.
+func stats(cmd *cobra.Command, args []string) {
+	start("stats")
+	trends, err := sequence.GetPatternTrends(window, growth)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	ofile, err := sequence.OpenOutputFile(outfile)
+	if err != nil {
+		standardLogger.HandleFatal(err.Error())
+	}
+	defer ofile.Close()
+	counts := make(map[string]int)
+	for _, t := range trends {
+		fmt.Fprintf(ofile, "%s\t%s\t%s\t%d\t%d\t%s\t%s\n", t.Trend, t.PatternId, t.Service, t.Current, t.Previous, t.DateLastMatched.Format("2006-01-02 15:04:05"), t.Pattern)
+		counts[t.Trend]++
+	}
+	standardLogger.HandleInfo(fmt.Sprintf("Found %d new, %d growing and %d vanished patterns in the last %s.",
+		counts[sequence.TrendNew], counts[sequence.TrendGrowing], counts[sequence.TrendVanished], window))
+}
+
+//Returns the stats command, which uses the match counts saved for each interval.
+func newStatsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats",
+		Short: "reports the new, growing and vanished patterns, comparing the matches in the --window to the window before it",
+		Run:   stats,
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/zhenjl/porter2"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -38,12 +39,17 @@ var (
 		connectionInfo       string
 		databaseType         string
 		useDatabase          bool
+		parserSnapshotPath   string
+		matchCountInterval   string
+		matchCountRetention  int
 	}
 
 	timesettings struct {
-		formats map[int][]string
-		regex   map[string]string
-		grok    map[string]string
+		formats     map[int][]string
+		regex       map[string]string
+		grok        map[string]string
+		defaultYear int
+		location    *time.Location
 	}
 
 	keymaps struct {
@@ -51,8 +57,32 @@ var (
 		prekeys  map[string][]TagType
 	}
 
+	alerting struct {
+		newPatternCount int
+		spikeFactor     float64
+		spikeMinCount   int64
+		spikeHistory    int
+		file            string
+		webhook         string
+		socket          string
+	}
+
+	examples struct {
+		budget   int
+		strategy string
+	}
+
+	redaction struct {
+		key  []byte
+		tags map[string]bool
+	}
+
+	tokentypes struct {
+		custom []customTokenType
+	}
+
 	TagTypesCount   int
-	TokenTypesCount = int(token__END__) + 1
+	TokenTypesCount = int(tokenCustomStart)
 	allTypesCount   int
 	logger          *StandardLogger
 )
@@ -70,17 +100,44 @@ func ReadConfig(file string) error {
 		UseDatabase         bool
 		ConnectionInfo      string
 		DatabaseType        string
+		ParserSnapshotPath  string
+		MatchCountInterval  string
+		MatchCountRetention int
 
 		Timesettings struct {
-			Formats map[string][]string
-			Regex   map[string]string
-			Grok    map[string]string
+			Defaultyear int
+			Timezone    string
+			Formats     map[string][]string
+			Regex       map[string]string
+			Grok        map[string]string
 		}
 
 		Analyzer struct {
 			Prekeys  map[string][]string
 			Keywords map[string][]string
 		}
+
+		Alerting struct {
+			NewPatternCount int
+			SpikeFactor     float64
+			SpikeMinCount   int64
+			SpikeHistory    int
+			File            string
+			Webhook         string
+			Socket          string
+		}
+
+		Examples struct {
+			Budget   *int
+			Strategy string
+		}
+
+		Redaction struct {
+			Key  string
+			Tags []string
+		}
+
+		Tokentypes map[string]struct{ Regex, Shape string }
 	}
 
 	if _, err := toml.DecodeFile(file, &configInfo); err != nil {
@@ -97,6 +154,48 @@ func ReadConfig(file string) error {
 	config.useDatabase = configInfo.UseDatabase
 	config.connectionInfo = configInfo.ConnectionInfo
 	config.databaseType = configInfo.DatabaseType
+	config.parserSnapshotPath = configInfo.ParserSnapshotPath
+	switch strings.ToLower(configInfo.MatchCountInterval) {
+	case "", MatchCountIntervalHour:
+		config.matchCountInterval = MatchCountIntervalHour
+	case MatchCountIntervalDay:
+		config.matchCountInterval = MatchCountIntervalDay
+	default:
+		return fmt.Errorf("the matchCountInterval must be hour or day, not %s", configInfo.MatchCountInterval)
+	}
+	if configInfo.MatchCountRetention < 0 {
+		return fmt.Errorf("the matchCountRetention must be 0 to keep all the match counts or a number of days, not %d", configInfo.MatchCountRetention)
+	}
+	config.matchCountRetention = configInfo.MatchCountRetention
+	//the cached parsers depend on the tags in the config
+	InvalidateParserCache()
+
+	alerting.newPatternCount = configInfo.Alerting.NewPatternCount
+	alerting.spikeFactor = configInfo.Alerting.SpikeFactor
+	alerting.spikeMinCount = configInfo.Alerting.SpikeMinCount
+	alerting.spikeHistory = configInfo.Alerting.SpikeHistory
+	alerting.file = configInfo.Alerting.File
+	alerting.webhook = configInfo.Alerting.Webhook
+	alerting.socket = configInfo.Alerting.Socket
+	if alerting.spikeHistory == 0 {
+		alerting.spikeHistory = 24
+	}
+	if err := validateAlertingConfig(); err != nil {
+		return err
+	}
+
+	//the default is only used when the budget is not set, 0 is not a valid budget
+	examples.budget = defaultExampleBudget
+	if configInfo.Examples.Budget != nil {
+		examples.budget = *configInfo.Examples.Budget
+	}
+	examples.strategy = strings.ToLower(configInfo.Examples.Strategy)
+	if examples.strategy == "" {
+		examples.strategy = ExampleStrategyFirst
+	}
+	if err := validateExamplesConfig(); err != nil {
+		return err
+	}
 
 	timesettings.formats = make(map[int][]string, len(configInfo.Timesettings.Formats))
 	for i, f := range configInfo.Timesettings.Formats {
@@ -110,6 +209,14 @@ func ReadConfig(file string) error {
 	timesettings.grok = configInfo.Timesettings.Grok
 
 	timeFsmRoot = buildTimeFSM(timesettings.formats)
+	if err := validateTimeSettingsConfig(configInfo.Timesettings.Defaultyear, configInfo.Timesettings.Timezone); err != nil {
+		return err
+	}
+
+	//the token types are read before the tags, which can be of these types
+	if err := validateTokenTypesConfig(configInfo.Tokentypes); err != nil {
+		return err
+	}
 
 	keymaps.keywords = make(map[string]TagType, 30)
 	keymaps.prekeys = make(map[string][]TagType, 30)
@@ -128,9 +235,12 @@ func ReadConfig(file string) error {
 
 		// tag type name, token type
 		tt := name2TokenType(fs[1])
-		if tt < TokenLiteral || tt > TokenString {
+		if (tt < TokenLiteral || tt >= token__END__ || tt == TokenMultiLine) && !tt.IsCustom() {
 			return fmt.Errorf("Error parsing tag %q: invalid token type", f)
 		}
+		if customTypeByName(fs[0]) != TokenUnknown {
+			return fmt.Errorf("Error parsing tag %q: the tag has the name of a token type", f)
+		}
 
 		config.tagIDs[fs[0]] = ftype
 		config.tagNames = append(config.tagNames, fs[0])
@@ -162,7 +272,8 @@ func ReadConfig(file string) error {
 	TagTypesCount = len(config.tagNames)
 	allTypesCount = TokenTypesCount + TagTypesCount
 
-	return nil
+	//the redacted tags are checked against the tags of the config
+	return validateRedactionConfig(configInfo.Redaction.Tags, configInfo.Redaction.Key)
 }
 
 //Returns the regular expression for patterndb matching the passed time format identifier.
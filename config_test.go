@@ -15,6 +15,9 @@
 package sequence
 
 import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -24,3 +27,30 @@ func TestSequenceConfig(t *testing.T) {
 	err := ReadConfig("sequence.toml")
 	require.NoError(t, err)
 }
+
+func TestSequenceConfigExamplesBudget(t *testing.T) {
+	data, err := ioutil.ReadFile("sequence.toml")
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, ReadConfig("sequence.toml")) })
+	tests := []struct {
+		budget string
+		valid  bool
+		want   int
+	}{
+		{"budget = 5", true, 5},
+		{"", true, defaultExampleBudget},
+		{"budget = 0", false, 0},
+		{"budget = -2", false, 0},
+	}
+	for _, tc := range tests {
+		fname := filepath.Join(t.TempDir(), "sequence.toml")
+		require.NoError(t, ioutil.WriteFile(fname, []byte(strings.Replace(string(data), "budget = 3", tc.budget, 1)), 0644))
+		err := ReadConfig(fname)
+		if !tc.valid {
+			require.Error(t, err, tc.budget)
+			continue
+		}
+		require.NoError(t, err, tc.budget)
+		require.Equal(t, tc.want, examples.budget, tc.budget)
+	}
+}
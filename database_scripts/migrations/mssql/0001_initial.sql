@@ -0,0 +1,81 @@
+-- probe: SELECT id FROM [dbo].[Services] WHERE 1 = 0
+SET ANSI_NULLS ON
+GO
+
+SET QUOTED_IDENTIFIER ON
+GO
+
+CREATE TABLE [dbo].[Services](
+	[id] [nvarchar](50) NOT NULL,
+	[name] [nvarchar](50) NOT NULL,
+	[date_created] [smalldatetime] NOT NULL,
+ CONSTRAINT [PK_Services] PRIMARY KEY CLUSTERED
+(
+	[id] ASC
+)WITH (PAD_INDEX = OFF, STATISTICS_NORECOMPUTE = OFF, IGNORE_DUP_KEY = OFF, ALLOW_ROW_LOCKS = ON, ALLOW_PAGE_LOCKS = ON) ON [PRIMARY]
+) ON [PRIMARY]
+
+GO
+
+SET ANSI_NULLS ON
+GO
+
+SET QUOTED_IDENTIFIER ON
+GO
+
+CREATE TABLE [dbo].[Patterns](
+	[id] [nvarchar](50) NOT NULL,
+	[service_id] [nvarchar](50) NOT NULL,
+	[sequence_pattern] [nvarchar](max) NOT NULL,
+	[tag_positions] [nvarchar](max) NULL,
+	[date_created] [smalldatetime] NOT NULL,
+	[date_last_matched] [smalldatetime] NOT NULL,
+	[original_match_count] [bigint] NOT NULL,
+	[cumulative_match_count] [bigint] NOT NULL,
+	[ignore_pattern] [bit] NOT NULL CONSTRAINT [DF_Patterns_ignore_pattern]  DEFAULT ((0)),
+ CONSTRAINT [PK_Patterns] PRIMARY KEY CLUSTERED
+(
+	[id] ASC
+)WITH (PAD_INDEX = OFF, STATISTICS_NORECOMPUTE = OFF, IGNORE_DUP_KEY = OFF, ALLOW_ROW_LOCKS = ON, ALLOW_PAGE_LOCKS = ON) ON [PRIMARY]
+) ON [PRIMARY] TEXTIMAGE_ON [PRIMARY]
+
+GO
+
+ALTER TABLE [dbo].[Patterns]  WITH CHECK ADD  CONSTRAINT [FK_Patterns_Services] FOREIGN KEY([service_id])
+REFERENCES [dbo].[Services] ([id])
+GO
+
+ALTER TABLE [dbo].[Patterns] CHECK CONSTRAINT [FK_Patterns_Services]
+GO
+
+SET ANSI_NULLS ON
+GO
+
+SET QUOTED_IDENTIFIER ON
+GO
+
+CREATE TABLE [dbo].[Examples](
+	[id] [nvarchar](50) NOT NULL,
+	[service_id] [nvarchar](50) NOT NULL,
+	[pattern_id] [nvarchar](50) NOT NULL,
+	[example_detail] [nvarchar](max) NOT NULL,
+ CONSTRAINT [PK_Examples] PRIMARY KEY CLUSTERED
+(
+	[id] ASC
+)WITH (PAD_INDEX = OFF, STATISTICS_NORECOMPUTE = OFF, IGNORE_DUP_KEY = OFF, ALLOW_ROW_LOCKS = ON, ALLOW_PAGE_LOCKS = ON) ON [PRIMARY]
+) ON [PRIMARY] TEXTIMAGE_ON [PRIMARY]
+GO
+
+ALTER TABLE [dbo].[Examples]  WITH CHECK ADD  CONSTRAINT [FK_Examples_Patterns] FOREIGN KEY([pattern_id])
+REFERENCES [dbo].[Patterns] ([id])
+GO
+
+ALTER TABLE [dbo].[Examples] CHECK CONSTRAINT [FK_Examples_Patterns]
+GO
+
+ALTER TABLE [dbo].[Examples]  WITH CHECK ADD  CONSTRAINT [FK_Examples_Services] FOREIGN KEY([service_id])
+REFERENCES [dbo].[Services] ([id])
+GO
+
+ALTER TABLE [dbo].[Examples] CHECK CONSTRAINT [FK_Examples_Services]
+GO
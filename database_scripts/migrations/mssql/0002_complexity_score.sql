@@ -0,0 +1,3 @@
+-- probe: SELECT complexity_score FROM [dbo].[Patterns] WHERE 1 = 0
+ALTER TABLE [dbo].[Patterns] ADD [complexity_score] [float] NOT NULL CONSTRAINT [DF_Patterns_complexity_score]  DEFAULT ((0.0))
+GO
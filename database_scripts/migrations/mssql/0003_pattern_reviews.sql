@@ -0,0 +1,38 @@
+-- probe: SELECT pattern_id FROM [dbo].[PatternReviews] WHERE 1 = 0
+CREATE TABLE [dbo].[PatternReviews](
+	[pattern_id] [nvarchar](50) NOT NULL,
+	[state] [nvarchar](20) NOT NULL,
+	[reviewer] [nvarchar](100) NOT NULL,
+	[comment] [nvarchar](max) NULL,
+	[date_updated] [datetime] NOT NULL,
+ CONSTRAINT [PK_PatternReviews] PRIMARY KEY CLUSTERED
+(
+	[pattern_id] ASC
+)WITH (PAD_INDEX = OFF, STATISTICS_NORECOMPUTE = OFF, IGNORE_DUP_KEY = OFF, ALLOW_ROW_LOCKS = ON, ALLOW_PAGE_LOCKS = ON) ON [PRIMARY]
+) ON [PRIMARY] TEXTIMAGE_ON [PRIMARY]
+GO
+
+ALTER TABLE [dbo].[PatternReviews]  WITH CHECK ADD  CONSTRAINT [FK_PatternReviews_Patterns] FOREIGN KEY([pattern_id])
+REFERENCES [dbo].[Patterns] ([id])
+ON DELETE CASCADE
+GO
+
+CREATE TABLE [dbo].[PatternReviewHistory](
+	[id] [nvarchar](50) NOT NULL,
+	[pattern_id] [nvarchar](50) NOT NULL,
+	[from_state] [nvarchar](20) NOT NULL,
+	[to_state] [nvarchar](20) NOT NULL,
+	[reviewer] [nvarchar](100) NOT NULL,
+	[comment] [nvarchar](max) NULL,
+	[date_changed] [datetime] NOT NULL,
+ CONSTRAINT [PK_PatternReviewHistory] PRIMARY KEY CLUSTERED
+(
+	[id] ASC
+)WITH (PAD_INDEX = OFF, STATISTICS_NORECOMPUTE = OFF, IGNORE_DUP_KEY = OFF, ALLOW_ROW_LOCKS = ON, ALLOW_PAGE_LOCKS = ON) ON [PRIMARY]
+) ON [PRIMARY] TEXTIMAGE_ON [PRIMARY]
+GO
+
+ALTER TABLE [dbo].[PatternReviewHistory]  WITH CHECK ADD  CONSTRAINT [FK_PatternReviewHistory_Patterns] FOREIGN KEY([pattern_id])
+REFERENCES [dbo].[Patterns] ([id])
+ON DELETE CASCADE
+GO
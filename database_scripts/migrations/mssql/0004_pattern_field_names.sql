@@ -0,0 +1,18 @@
+-- probe: SELECT pattern_id FROM [dbo].[PatternFieldNames] WHERE 1 = 0
+CREATE TABLE [dbo].[PatternFieldNames](
+	[pattern_id] [nvarchar](50) NOT NULL,
+	[position] [int] NOT NULL,
+	[field_name] [nvarchar](100) NOT NULL,
+	[date_created] [datetime] NOT NULL,
+ CONSTRAINT [PK_PatternFieldNames] PRIMARY KEY CLUSTERED
+(
+	[pattern_id] ASC,
+	[position] ASC
+)WITH (PAD_INDEX = OFF, STATISTICS_NORECOMPUTE = OFF, IGNORE_DUP_KEY = OFF, ALLOW_ROW_LOCKS = ON, ALLOW_PAGE_LOCKS = ON) ON [PRIMARY]
+) ON [PRIMARY]
+GO
+
+ALTER TABLE [dbo].[PatternFieldNames]  WITH CHECK ADD  CONSTRAINT [FK_PatternFieldNames_Patterns] FOREIGN KEY([pattern_id])
+REFERENCES [dbo].[Patterns] ([id])
+ON DELETE CASCADE
+GO
@@ -0,0 +1,23 @@
+-- probe: SELECT pattern_id FROM [dbo].[PatternLineage] WHERE 1 = 0
+CREATE TABLE [dbo].[PatternLineage](
+	[pattern_id] [nvarchar](50) NOT NULL,
+	[supersedes_id] [nvarchar](50) NOT NULL,
+	[score] [float] NOT NULL,
+	[migrated] [bit] NOT NULL CONSTRAINT [DF_PatternLineage_migrated]  DEFAULT ((0)),
+	[date_created] [datetime] NOT NULL,
+ CONSTRAINT [PK_PatternLineage] PRIMARY KEY CLUSTERED
+(
+	[pattern_id] ASC,
+	[supersedes_id] ASC
+)WITH (PAD_INDEX = OFF, STATISTICS_NORECOMPUTE = OFF, IGNORE_DUP_KEY = OFF, ALLOW_ROW_LOCKS = ON, ALLOW_PAGE_LOCKS = ON) ON [PRIMARY]
+) ON [PRIMARY]
+GO
+
+ALTER TABLE [dbo].[PatternLineage]  WITH CHECK ADD  CONSTRAINT [FK_PatternLineage_Patterns] FOREIGN KEY([pattern_id])
+REFERENCES [dbo].[Patterns] ([id])
+ON DELETE CASCADE
+GO
+
+ALTER TABLE [dbo].[PatternLineage]  WITH CHECK ADD  CONSTRAINT [FK_PatternLineage_Supersedes] FOREIGN KEY([supersedes_id])
+REFERENCES [dbo].[Patterns] ([id])
+GO
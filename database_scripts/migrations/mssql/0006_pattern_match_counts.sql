@@ -0,0 +1,17 @@
+-- probe: SELECT pattern_id FROM [dbo].[PatternMatchCounts] WHERE 1 = 0
+CREATE TABLE [dbo].[PatternMatchCounts](
+	[pattern_id] [nvarchar](50) NOT NULL,
+	[interval_start] [datetime] NOT NULL,
+	[match_count] [bigint] NOT NULL,
+ CONSTRAINT [PK_PatternMatchCounts] PRIMARY KEY CLUSTERED
+(
+	[pattern_id] ASC,
+	[interval_start] ASC
+)WITH (PAD_INDEX = OFF, STATISTICS_NORECOMPUTE = OFF, IGNORE_DUP_KEY = OFF, ALLOW_ROW_LOCKS = ON, ALLOW_PAGE_LOCKS = ON) ON [PRIMARY]
+) ON [PRIMARY]
+GO
+
+ALTER TABLE [dbo].[PatternMatchCounts]  WITH CHECK ADD  CONSTRAINT [FK_PatternMatchCounts_Patterns] FOREIGN KEY([pattern_id])
+REFERENCES [dbo].[Patterns] ([id])
+ON DELETE CASCADE
+GO
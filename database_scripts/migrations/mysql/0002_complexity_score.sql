@@ -0,0 +1,2 @@
+-- probe: SELECT complexity_score FROM `patterns` WHERE 1 = 0
+ALTER TABLE `patterns` ADD COLUMN `complexity_score` float NOT NULL DEFAULT '0';
@@ -0,0 +1,23 @@
+-- probe: SELECT pattern_id FROM `patternreviews` WHERE 1 = 0
+CREATE TABLE `patternreviews` (
+  `pattern_id` varchar(50) NOT NULL,
+  `state` varchar(20) NOT NULL,
+  `reviewer` varchar(100) NOT NULL,
+  `comment` text DEFAULT NULL,
+  `date_updated` datetime NOT NULL,
+  PRIMARY KEY (`pattern_id`),
+  CONSTRAINT `FK_PatternReviews_Patterns` FOREIGN KEY (`pattern_id`) REFERENCES `patterns` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `patternreviewhistory` (
+  `id` varchar(50) NOT NULL,
+  `pattern_id` varchar(50) NOT NULL,
+  `from_state` varchar(20) NOT NULL,
+  `to_state` varchar(20) NOT NULL,
+  `reviewer` varchar(100) NOT NULL,
+  `comment` text DEFAULT NULL,
+  `date_changed` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `FK_PatternReviewHistory_Patterns_idx` (`pattern_id`),
+  CONSTRAINT `FK_PatternReviewHistory_Patterns` FOREIGN KEY (`pattern_id`) REFERENCES `patterns` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
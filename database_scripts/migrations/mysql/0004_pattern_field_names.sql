@@ -0,0 +1,9 @@
+-- probe: SELECT pattern_id FROM `patternfieldnames` WHERE 1 = 0
+CREATE TABLE `patternfieldnames` (
+  `pattern_id` varchar(50) NOT NULL,
+  `position` int(11) NOT NULL,
+  `field_name` varchar(100) NOT NULL,
+  `date_created` datetime NOT NULL,
+  PRIMARY KEY (`pattern_id`, `position`),
+  CONSTRAINT `FK_PatternFieldNames_Patterns` FOREIGN KEY (`pattern_id`) REFERENCES `patterns` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
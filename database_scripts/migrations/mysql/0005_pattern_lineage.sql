@@ -0,0 +1,12 @@
+-- probe: SELECT pattern_id FROM `patternlineage` WHERE 1 = 0
+CREATE TABLE `patternlineage` (
+  `pattern_id` varchar(50) NOT NULL,
+  `supersedes_id` varchar(50) NOT NULL,
+  `score` float NOT NULL,
+  `migrated` tinyint(4) NOT NULL DEFAULT '0',
+  `date_created` datetime NOT NULL,
+  PRIMARY KEY (`pattern_id`, `supersedes_id`),
+  KEY `FK_PatternLineage_Supersedes_idx` (`supersedes_id`),
+  CONSTRAINT `FK_PatternLineage_Patterns` FOREIGN KEY (`pattern_id`) REFERENCES `patterns` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `FK_PatternLineage_Supersedes` FOREIGN KEY (`supersedes_id`) REFERENCES `patterns` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
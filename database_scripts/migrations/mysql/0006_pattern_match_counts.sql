@@ -0,0 +1,9 @@
+-- probe: SELECT pattern_id FROM `patternmatchcounts` WHERE 1 = 0
+CREATE TABLE `patternmatchcounts` (
+  `pattern_id` varchar(50) NOT NULL,
+  `interval_start` datetime NOT NULL,
+  `match_count` bigint(20) NOT NULL,
+  PRIMARY KEY (`pattern_id`, `interval_start`),
+  KEY `IX_PatternMatchCounts_interval_start` (`interval_start`),
+  CONSTRAINT `FK_PatternMatchCounts_Patterns` FOREIGN KEY (`pattern_id`) REFERENCES `patterns` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
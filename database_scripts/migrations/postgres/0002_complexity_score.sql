@@ -0,0 +1,3 @@
+-- probe: SELECT complexity_score FROM public."Patterns" WHERE 1 = 0
+ALTER TABLE public."Patterns"
+    ADD COLUMN complexity_score double precision NOT NULL DEFAULT 0.0;
@@ -0,0 +1,43 @@
+-- probe: SELECT pattern_id FROM public."PatternReviews" WHERE 1 = 0
+CREATE TABLE public."PatternReviews"
+(
+    pattern_id character varying(50) COLLATE pg_catalog."default" NOT NULL,
+    state character varying(20) COLLATE pg_catalog."default" NOT NULL,
+    reviewer character varying(100) COLLATE pg_catalog."default" NOT NULL,
+    comment text COLLATE pg_catalog."default",
+    date_updated timestamp NOT NULL,
+    CONSTRAINT "PK_PatternReviews" PRIMARY KEY (pattern_id),
+    CONSTRAINT "FK_PatternReviews_Patterns" FOREIGN KEY (pattern_id)
+        REFERENCES public."Patterns" (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+WITH (
+    OIDS = FALSE
+)
+TABLESPACE pg_default;
+
+CREATE TABLE public."PatternReviewHistory"
+(
+    id character varying(50) COLLATE pg_catalog."default" NOT NULL,
+    pattern_id character varying(50) COLLATE pg_catalog."default" NOT NULL,
+    from_state character varying(20) COLLATE pg_catalog."default" NOT NULL,
+    to_state character varying(20) COLLATE pg_catalog."default" NOT NULL,
+    reviewer character varying(100) COLLATE pg_catalog."default" NOT NULL,
+    comment text COLLATE pg_catalog."default",
+    date_changed timestamp NOT NULL,
+    CONSTRAINT "PK_PatternReviewHistory" PRIMARY KEY (id),
+    CONSTRAINT "FK_PatternReviewHistory_Patterns" FOREIGN KEY (pattern_id)
+        REFERENCES public."Patterns" (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+WITH (
+    OIDS = FALSE
+)
+TABLESPACE pg_default;
+
+CREATE INDEX "fki_FK_PatternReviewHistory_Patterns"
+    ON public."PatternReviewHistory" USING btree
+    (pattern_id COLLATE pg_catalog."default")
+    TABLESPACE pg_default;
@@ -0,0 +1,17 @@
+-- probe: SELECT pattern_id FROM public."PatternFieldNames" WHERE 1 = 0
+CREATE TABLE public."PatternFieldNames"
+(
+    pattern_id character varying(50) COLLATE pg_catalog."default" NOT NULL,
+    "position" integer NOT NULL,
+    field_name character varying(100) COLLATE pg_catalog."default" NOT NULL,
+    date_created timestamp NOT NULL,
+    CONSTRAINT "PK_PatternFieldNames" PRIMARY KEY (pattern_id, "position"),
+    CONSTRAINT "FK_PatternFieldNames_Patterns" FOREIGN KEY (pattern_id)
+        REFERENCES public."Patterns" (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+WITH (
+    OIDS = FALSE
+)
+TABLESPACE pg_default;
@@ -0,0 +1,22 @@
+-- probe: SELECT pattern_id FROM public."PatternLineage" WHERE 1 = 0
+CREATE TABLE public."PatternLineage"
+(
+    pattern_id character varying(50) COLLATE pg_catalog."default" NOT NULL,
+    supersedes_id character varying(50) COLLATE pg_catalog."default" NOT NULL,
+    score double precision NOT NULL,
+    migrated boolean NOT NULL DEFAULT false,
+    date_created timestamp NOT NULL,
+    CONSTRAINT "PK_PatternLineage" PRIMARY KEY (pattern_id, supersedes_id),
+    CONSTRAINT "FK_PatternLineage_Patterns" FOREIGN KEY (pattern_id)
+        REFERENCES public."Patterns" (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE,
+    CONSTRAINT "FK_PatternLineage_Supersedes" FOREIGN KEY (supersedes_id)
+        REFERENCES public."Patterns" (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+WITH (
+    OIDS = FALSE
+)
+TABLESPACE pg_default;
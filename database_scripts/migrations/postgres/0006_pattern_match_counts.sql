@@ -0,0 +1,21 @@
+-- probe: SELECT pattern_id FROM public."PatternMatchCounts" WHERE 1 = 0
+CREATE TABLE public."PatternMatchCounts"
+(
+    pattern_id character varying(50) COLLATE pg_catalog."default" NOT NULL,
+    interval_start timestamp NOT NULL,
+    match_count bigint NOT NULL,
+    CONSTRAINT "PK_PatternMatchCounts" PRIMARY KEY (pattern_id, interval_start),
+    CONSTRAINT "FK_PatternMatchCounts_Patterns" FOREIGN KEY (pattern_id)
+        REFERENCES public."Patterns" (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+WITH (
+    OIDS = FALSE
+)
+TABLESPACE pg_default;
+
+CREATE INDEX "IX_PatternMatchCounts_interval_start"
+    ON public."PatternMatchCounts" USING btree
+    (interval_start)
+    TABLESPACE pg_default;
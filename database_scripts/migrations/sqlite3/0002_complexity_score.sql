@@ -0,0 +1,2 @@
+-- probe: SELECT complexity_score FROM Patterns WHERE 1 = 0
+ALTER TABLE Patterns ADD COLUMN complexity_score DOUBLE NOT NULL DEFAULT (0.0);
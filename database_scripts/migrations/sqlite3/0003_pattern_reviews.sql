@@ -0,0 +1,3 @@
+-- probe: SELECT pattern_id FROM PatternReviews WHERE 1 = 0
+CREATE TABLE PatternReviews (pattern_id STRING (20, 50) PRIMARY KEY REFERENCES Patterns (id) ON DELETE CASCADE NOT NULL, state STRING (20) NOT NULL, reviewer STRING (100) NOT NULL, comment STRING, date_updated DATETIME NOT NULL);
+CREATE TABLE PatternReviewHistory (id STRING PRIMARY KEY NOT NULL, pattern_id STRING (20, 50) REFERENCES Patterns (id) ON DELETE CASCADE NOT NULL, from_state STRING (20) NOT NULL, to_state STRING (20) NOT NULL, reviewer STRING (100) NOT NULL, comment STRING, date_changed DATETIME NOT NULL);
@@ -0,0 +1,2 @@
+-- probe: SELECT pattern_id FROM PatternFieldNames WHERE 1 = 0
+CREATE TABLE PatternFieldNames (pattern_id STRING (20, 50) REFERENCES Patterns (id) ON DELETE CASCADE NOT NULL, position INTEGER NOT NULL, field_name STRING (100) NOT NULL, date_created DATETIME NOT NULL, PRIMARY KEY (pattern_id, position));
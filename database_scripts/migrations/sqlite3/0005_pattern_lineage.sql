@@ -0,0 +1,2 @@
+-- probe: SELECT pattern_id FROM PatternLineage WHERE 1 = 0
+CREATE TABLE PatternLineage (pattern_id STRING (20, 50) REFERENCES Patterns (id) ON DELETE CASCADE NOT NULL, supersedes_id STRING (20, 50) REFERENCES Patterns (id) ON DELETE CASCADE NOT NULL, score DOUBLE NOT NULL, migrated BOOLEAN NOT NULL DEFAULT (0), date_created DATETIME NOT NULL, PRIMARY KEY (pattern_id, supersedes_id));
@@ -0,0 +1,2 @@
+-- probe: SELECT pattern_id FROM PatternMatchCounts WHERE 1 = 0
+CREATE TABLE PatternMatchCounts (pattern_id STRING (20, 50) REFERENCES Patterns (id) ON DELETE CASCADE NOT NULL, interval_start DATETIME NOT NULL, match_count INTEGER NOT NULL, PRIMARY KEY (pattern_id, interval_start));
@@ -1,10 +1,12 @@
 package sequence
 
 import (
-	"bufio"
 	"context"
 	"database/sql"
 	_ "embed"
+	"fmt"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -30,49 +32,125 @@ var createPostgres string
 //go:embed database_scripts/sqlite3.txt
 var createSQLite string
 
-// This creates the database from the scripts in the toml file at the location and db type specified.
-// SQLite3 needs cinfo and driver
-// Microsoft SQL Server needs cinfo, driver, path and dbname
-func CreateDatabase(cinfo string, driver string, path string, dbname string) {
-	database, err := sql.Open(driver, cinfo)
+// The create script for each dialect, they create the tables and the statements that
+// create the database itself are in createDatabaseStatements.
+var createScripts = map[string]string{
+	"sqlite3":  createSQLite,
+	"mssql":    createMSSQL,
+	"postgres": createPostgres,
+	"mysql":    createMYSQL,
+}
+
+// The names createdatabase accepts for a database, they are quoted in the statements that create it.
+var databaseNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,62}$`)
+
+// This creates the database from the embedded scripts for the db type.
+// SQLite3 needs cinfo and dbtype, the file in cinfo is created.
+// SQL Server, PostgreSQL and MySQL need cinfo to connect to the server and dbname, the database
+// is created if it does not exist and the tables are created in it. For PostgreSQL and MySQL
+// dbname can be empty to create the tables in the database of the connection.
+func CreateDatabase(cinfo string, dbtype string, dbname string) error {
+	dialect, err := migrationDialect(dbtype)
 	if err != nil {
-		logger.HandleFatal(err.Error())
+		return err
 	}
-	tx, err := database.Begin()
+	if dialect == "mssql" && dbname == "" {
+		return fmt.Errorf("SQL Server needs the name of the database to create")
+	}
+	if !driverRegistered(dbtype) {
+		return fmt.Errorf("The %s driver is not built in, build sequence with -tags %s, see database_scripts/README.md", dbtype, dialect)
+	}
+	database, err := sql.Open(dbtype, cinfo)
 	if err != nil {
-		logger.HandleFatal(err.Error())
+		return fmt.Errorf("Unable to open the %s database: %s", dbtype, err.Error())
 	}
-	if driver == "sqlite3" {
-		s := bufio.NewScanner(strings.NewReader(createSQLite))
-		for s.Scan() {
-			_, err = database.Exec(s.Text())
-			if err != nil {
-				logger.HandleFatal(err.Error())
-			}
+	defer database.Close()
+	ctx := context.Background()
+	if err = database.PingContext(ctx); err != nil {
+		return fmt.Errorf("Unable to connect to the %s database: %s", dbtype, err.Error())
+	}
+	if dialect == "postgres" && dbname != "" {
+		//postgres has no USE, so the tables are created over a new connection to the database
+		if err = createPostgresDatabase(ctx, database, dbname); err != nil {
+			return err
 		}
-		tx.Commit()
-	} else if driver == "sqlserver" {
-		s := bufio.NewScanner(strings.NewReader(createMSSQL))
-		query := ""
-		for s.Scan() {
-			if strings.Contains(s.Text(), "GO") {
-				_, err = database.Exec(query)
-				query = ""
-			} else {
-				q := s.Text()
-				q = strings.Replace(q, "%path%", path, -1)
-				q = strings.Replace(q, "%databasename%", dbname, -1)
-				query = query + q + "\n"
-			}
-			if err != nil {
-				logger.HandleFatal(err.Error())
-			}
+		database.Close()
+		if database, err = sql.Open(dbtype, postgresConnInfo(cinfo, dbname)); err != nil {
+			return fmt.Errorf("Unable to open the %s database %s: %s", dbtype, dbname, err.Error())
 		}
-		tx.Commit()
-	} else {
-		//Not supported
+		defer database.Close()
+	}
+	//the statements run on one connection, so the USE statements apply to the ones after them
+	conn, err := database.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("Unable to connect to the %s database: %s", dbtype, err.Error())
+	}
+	defer conn.Close()
+	for _, stmt := range createDatabaseStatements(dialect, dbname) {
+		if _, err = conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("Unable to create the database %s: %s", dbname, err.Error())
+		}
+	}
+	script := strings.Replace(createScripts[dialect], "%databasename%", dbname, -1)
+	for i, stmt := range splitStatements(script, dialect) {
+		if _, err = conn.ExecContext(ctx, stmt); err != nil {
+			logger.HandleError(fmt.Sprintf("Statement %d of the %s create script failed: %s\n%s", i+1, dialect, err.Error(), stmt))
+			return fmt.Errorf("Statement %d of the %s create script failed: %s", i+1, dialect, err.Error())
+		}
+	}
+	//the script has the latest schema, so the migrations are recorded as applied
+	if _, err = migrateUp(conn, ctx, dialect); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Returns the statements that create the database and switch to it before the create script
+// runs. The name has been checked by ValidateDatabaseName so it can be quoted as it is.
+func createDatabaseStatements(dialect string, dbname string) []string {
+	if dbname == "" {
+		return nil
+	}
+	switch dialect {
+	case "mssql":
+		//the script switches to the database after setting its options
+		return []string{fmt.Sprintf("IF DB_ID(N'%s') IS NULL CREATE DATABASE [%s]", dbname, dbname)}
+	case "mysql":
+		return []string{
+			fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s` DEFAULT CHARACTER SET utf8mb4", dbname),
+			fmt.Sprintf("USE `%s`", dbname),
+		}
+	}
+	return nil
+}
+
+// Creates the postgres database if it does not exist, CREATE DATABASE has no IF NOT EXISTS in postgres.
+func createPostgresDatabase(ctx context.Context, database *sql.DB, dbname string) error {
+	var n int
+	err := database.QueryRowContext(ctx, "SELECT COUNT(*) FROM pg_database WHERE datname = $1", dbname).Scan(&n)
+	if err != nil {
+		return fmt.Errorf("Unable to check for the database %s: %s", dbname, err.Error())
 	}
+	if n > 0 {
+		return nil
+	}
+	if _, err = database.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE \"%s\" WITH ENCODING = 'UTF8'", dbname)); err != nil {
+		return fmt.Errorf("Unable to create the database %s: %s", dbname, err.Error())
+	}
+	return nil
+}
 
+// Returns the postgres connection info with the database set to dbname, for both the
+// url and the keyword/value forms.
+func postgresConnInfo(cinfo string, dbname string) string {
+	if strings.HasPrefix(cinfo, "postgres://") || strings.HasPrefix(cinfo, "postgresql://") {
+		if u, err := url.Parse(cinfo); err == nil {
+			u.Path = "/" + dbname
+			return u.String()
+		}
+	}
+	//a later keyword overrides an earlier one
+	return strings.TrimSpace(cinfo) + " dbname='" + dbname + "'"
 }
 
 // This deletes all the patterns and related data from the database
@@ -84,8 +162,11 @@ func PurgePatternsfromDatabase(threshold int64) int64 {
 		logger.HandleError(err.Error())
 	}
 	patterns, _ := models.Patterns(models.PatternWhere.CumulativeMatchCount.LT(threshold)).All(ctx, tx)
+	var sids []string
 	for _, pat := range patterns {
 		pat.PatternExamples().DeleteAll(ctx, tx)
+		deletePatternRows(ctx, tx, pat.ID)
+		sids = append(sids, pat.ServiceID)
 	}
 	if len(patterns) > 0 {
 		rowsAff, err := patterns.DeleteAll(ctx, tx)
@@ -93,13 +174,64 @@ func PurgePatternsfromDatabase(threshold int64) int64 {
 			logger.HandleFatal(err.Error())
 		}
 		tx.Commit()
+		InvalidateParserCache(sids...)
 		return rowsAff
 	}
 	return 0
 }
 
-// This opens tha database for use.
+// The tables with rows for a pattern that are not in the models, they are
+// cleared when the pattern is deleted.
+var patternTables = []string{"PatternReviews", "PatternReviewHistory", "PatternFieldNames", "PatternLineage", "PatternMatchCounts"}
+
+// This deletes the rows of a pattern that is being removed from the tables that
+// are not in the models.
+func deletePatternRows(ctx context.Context, tx *sql.Tx, patternid string) {
+	for _, table := range patternTables {
+		if _, err := tx.ExecContext(ctx, rebind(`DELETE FROM "`+table+`" WHERE pattern_id = ?`), patternid); err != nil {
+			logger.DatabaseUpdateFailed(table, patternid, err.Error())
+		}
+	}
+	//the links from the patterns that supersede it
+	if _, err := tx.ExecContext(ctx, rebind(`DELETE FROM "PatternLineage" WHERE supersedes_id = ?`), patternid); err != nil {
+		logger.DatabaseUpdateFailed("PatternLineage", patternid, err.Error())
+	}
+}
+
+// Returns true if the driver for the database type is built in, the drivers other than
+// SQLite are added with the build tag of the database, eg -tags postgres.
+func driverRegistered(dbtype string) bool {
+	for _, d := range sql.Drivers() {
+		if d == dbtype {
+			return true
+		}
+	}
+	return false
+}
+
+// This opens tha database for use, it refuses to run against a database
+// with a schema older than the migrations.
 func OpenDbandSetContext() (*sql.DB, context.Context) {
+	db, ctx, err := openCheckedDb()
+	if err != nil {
+		logger.HandleFatal(err.Error())
+	}
+	return db, ctx
+}
+
+// This opens the database and checks the version of the schema, returning the
+// error instead of exiting.
+func openCheckedDb() (*sql.DB, context.Context, error) {
+	db, ctx := openDb()
+	if err := checkSchemaVersion(db, ctx); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, ctx, nil
+}
+
+// This opens the database without checking the version of the schema.
+func openDb() (*sql.DB, context.Context) {
 	// Get a handle to the SQLite database, using mattn/go-sqlite3
 	db, err := sql.Open(config.databaseType, config.connectionInfo)
 	if err != nil {
@@ -138,7 +270,7 @@ func GetPatternsWithExamplesFromDatabase(db *sql.DB, ctx context.Context, comple
 	if thresholdValue != "0" {
 		var threshold int64
 		if thresholdType == "count" {
-			threshold, _ = strconv.ParseInt(config.matchThresholdValue, 10, 64)
+			threshold, _ = strconv.ParseInt(thresholdValue, 10, 64)
 		} else {
 			total := getRecordProcessed(db, ctx)
 			threshold = int64(getThreshold(total, thresholdType, thresholdValue))
@@ -166,8 +298,10 @@ func GetPatternsWithExamplesFromDatabase(db *sql.DB, ctx context.Context, comple
 		}
 	}
 
+	fnames := getPatternFieldNames(db, ctx)
 	for _, p := range patterns {
 		ar := AnalyzerResult{PatternId: p.ID, Pattern: p.SequencePattern, DateCreated: p.DateCreated, DateLastMatched: p.DateLastMatched, ExampleCount: int(p.CumulativeMatchCount), TagPositions: p.TagPositions.String, ComplexityScore: p.ComplexityScore}
+		ar.FieldNames = fnames[p.ID]
 		svc, _ := p.Service().One(ctx, db)
 		ar.Service.ID = svc.ID
 		ar.Service.Name = svc.Name
@@ -182,6 +316,8 @@ func GetPatternsWithExamplesFromDatabase(db *sql.DB, ctx context.Context, comple
 			lr := LogRecord{Message: e.ExampleDetail, Service: s.Name}
 			ar.Examples = append(ar.Examples, lr)
 		}
+		//the examples saved before redaction was turned on are redacted as they are exported
+		ar.Examples = RedactExamples(ar.Pattern, ar.TagPositions, ar.Examples)
 		pmap[p.ID] = ar
 	}
 	return pmap, top5
@@ -261,57 +397,102 @@ func addPattern(ctx context.Context, tx *sql.Tx, result AnalyzerResult, tr int)
 		logger.DatabaseInsertFailed("pattern", result.PatternId, err.Error())
 		return false
 	}
+	recordMatchCount(ctx, tx, result.PatternId, int64(result.ExampleCount), p.DateCreated)
 	for _, e := range result.Examples {
 		insertExample(ctx, tx, e, result.PatternId, result.Service.ID)
 	}
 	return true
 }
 
-func SaveIgnoredPatterns(pattids []string) {
+// Marks the patterns to be ignored, the reviewer is recorded in the review history.
+func SaveIgnoredPatterns(pattids []string, reviewer string) {
 	db, ctx := OpenDbandSetContext()
 	defer db.Close()
 	for _, p := range pattids {
-		ignorePattern(ctx, db, p)
+		ignorePattern(ctx, db, p, reviewer)
 	}
 }
 
-// This updates an existing pattern record and marks it to be ignored.
-func ignorePattern(ctx context.Context, db *sql.DB, patternid string) {
-	p, _ := models.FindPattern(ctx, db, patternid)
-	p.IgnorePattern = true
-	_, err := p.Update(ctx, db, boil.Infer())
+// Marks a single pattern to be ignored, the error is sql.ErrNoRows if the pattern
+// does not exist.
+func IgnorePattern(patternid string, reviewer string) error {
+	db, ctx := OpenDbandSetContext()
+	defer db.Close()
+	return ignorePattern(ctx, db, patternid, reviewer)
+}
+
+// This updates an existing pattern record and marks it to be ignored,
+// patterns that are already ignored are left as they are.
+func ignorePattern(ctx context.Context, db *sql.DB, patternid string, reviewer string) error {
+	p, err := models.FindPattern(ctx, db, patternid)
+	if err != nil {
+		logger.DatabaseSelectFailed("pattern", patternid, err.Error())
+		return err
+	}
+	if p.IgnorePattern {
+		return nil
+	}
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		logger.DatabaseUpdateFailed("pattern", patternid, err.Error())
+		return err
+	}
+	if _, err = reviewPattern(ctx, tx, patternid, ReviewStateIgnored, reviewer, ""); err != nil {
+		tx.Rollback()
+		return err
 	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	InvalidateParserCache(p.ServiceID)
+	return nil
 }
 
-// This updates an existing pattern record and updates any related examples.
+// This updates an existing pattern record, its match count for the interval and any related examples.
 func updatePattern(ctx context.Context, tx *sql.Tx, result AnalyzerResult) {
 	p, _ := models.FindPattern(ctx, tx, result.PatternId)
+	matched := p.CumulativeMatchCount
 	p.DateLastMatched = time.Now()
 	p.CumulativeMatchCount += int64(result.ExampleCount)
 	_, err := p.Update(ctx, tx, boil.Infer())
 	if err != nil {
 		logger.DatabaseUpdateFailed("pattern", result.PatternId, err.Error())
 	}
+	recordMatchCount(ctx, tx, result.PatternId, int64(result.ExampleCount), p.DateLastMatched)
 
-	//if the example count is less than three, add the extra ones if different
-	ct, _ := p.PatternExamples().Count(ctx, tx)
-	if ct < 3 {
-		ex, _ := p.PatternExamples().All(ctx, tx)
-		for _, e := range result.Examples {
-			found := false
-			for _, h := range ex {
-				if h.ExampleDetail == e.Message {
-					found = true
-					break
-				}
-			}
-			if !found {
-				insertExample(ctx, tx, e, result.PatternId, result.Service.ID)
+	//the examples saved are merged with the batch examples within the example budget
+	ex, _ := p.PatternExamples().All(ctx, tx)
+	saved := exampleRecords(ex)
+	saveExamples(ctx, tx, ex, MergeExamples(saved, int(matched), result.Examples, result.ExampleCount), result.PatternId, result.Service.ID)
+}
+
+// Returns the examples from the database as log records.
+func exampleRecords(ex models.ExampleSlice) []LogRecord {
+	saved := make([]LogRecord, len(ex))
+	for i, e := range ex {
+		saved[i] = LogRecord{Message: e.ExampleDetail}
+	}
+	return saved
+}
+
+// This replaces the saved examples of a pattern with the ones to keep, the examples
+// that are in both are left as they are. Returns the number of examples added.
+func saveExamples(ctx context.Context, tx *sql.Tx, ex models.ExampleSlice, keep []LogRecord, pid string, sid string) int {
+	for _, e := range ex {
+		if !hasExample(keep, e.ExampleDetail) {
+			if _, err := e.Delete(ctx, tx); err != nil {
+				logger.DatabaseUpdateFailed("example", e.ID, err.Error())
 			}
 		}
 	}
+	saved := exampleRecords(ex)
+	added := 0
+	for _, e := range keep {
+		if !hasExample(saved, e.Message) {
+			insertExample(ctx, tx, e, pid, sid)
+			added++
+		}
+	}
+	return added
 }
 
 // This inserts an example record into the database.
@@ -329,7 +510,18 @@ func insertExample(ctx context.Context, tx *sql.Tx, lr LogRecord, pid string, si
 
 // This updates the patterns. services and examples in the database.
 func SaveExistingToDatabase(rmap map[string]AnalyzerResult) {
-	db, ctx := OpenDbandSetContext()
+	if err := SaveExistingPatterns(rmap); err != nil {
+		logger.HandleFatal(err.Error())
+	}
+}
+
+// This updates the existing patterns in the same way as SaveExistingToDatabase, the
+// database errors are returned instead of exiting so a server can carry on.
+func SaveExistingPatterns(rmap map[string]AnalyzerResult) error {
+	db, ctx, err := openCheckedDb()
+	if err != nil {
+		return err
+	}
 	defer db.Close()
 	//exisitng services
 	smap := getServicesFromDatabase(db, ctx)
@@ -345,17 +537,19 @@ func SaveExistingToDatabase(rmap map[string]AnalyzerResult) {
 	}
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		logger.HandleFatal("Could not start a transaction to save to the database.")
+		return fmt.Errorf("Could not start a transaction to save to the database: %s", err.Error())
 	}
 	//start with the service, so not to cause a primary key violation
 	for sid, m := range nmap {
 		addService(ctx, tx, sid, m)
 	}
-	tx.Commit()
+	if err = tx.Commit(); err != nil {
+		return err
+	}
 
 	tx, err = db.BeginTx(ctx, nil)
 	if err != nil {
-		logger.HandleFatal("Could not start a transaction to save to the database.")
+		return fmt.Errorf("Could not start a transaction to save to the database: %s", err.Error())
 	}
 	//here we want to update the existing patterns with count and last matched
 	pmap := getPatternsFromDatabase(db, ctx)
@@ -365,17 +559,31 @@ func SaveExistingToDatabase(rmap map[string]AnalyzerResult) {
 			updatePattern(ctx, tx, result)
 		}
 	}
-	tx.Commit()
-
+	//the match counts are kept for the retention in the config
+	pruneMatchCounts(ctx, tx, time.Now())
+	return tx.Commit()
 }
 
 // This saves the new patterns and related data to the database
 func SaveToDatabase(amap map[string]AnalyzerResult) (int, int) {
+	new, saved, err := SaveNewPatterns(amap)
+	if err != nil {
+		logger.HandleFatal(err.Error())
+	}
+	return new, saved
+}
+
+// This saves the new patterns in the same way as SaveToDatabase, the database
+// errors are returned instead of exiting so a server can carry on.
+func SaveNewPatterns(amap map[string]AnalyzerResult) (int, int, error) {
 	var (
 		new   = 0
 		saved = 0
 	)
-	db, ctx := OpenDbandSetContext()
+	db, ctx, err := openCheckedDb()
+	if err != nil {
+		return new, saved, err
+	}
 	defer db.Close()
 	//exisitng services
 	smap := getServicesFromDatabase(db, ctx)
@@ -391,35 +599,51 @@ func SaveToDatabase(amap map[string]AnalyzerResult) (int, int) {
 	}
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		logger.HandleFatal("Could not start a transaction to save to the database.")
+		return new, saved, fmt.Errorf("Could not start a transaction to save to the database: %s", err.Error())
 	}
 	//start with the service, so not to cause a primary key violation
 	for sid, m := range nmap {
 		addService(ctx, tx, sid, m)
 	}
-	tx.Commit()
+	if err = tx.Commit(); err != nil {
+		return new, saved, err
+	}
 
 	tx, err = db.BeginTx(ctx, nil)
 	if err != nil {
-		logger.HandleFatal("Could not start a transaction to save to the database.")
+		return new, saved, fmt.Errorf("Could not start a transaction to save to the database: %s", err.Error())
 	}
 
 	tr := getSaveThreshold()
 	//technically we should not have any existing patterns passed to here, but just in case
 	//lets check first
 	pmap := getPatternsFromDatabase(db, ctx)
+	var (
+		sids  []string
+		added []AnalyzerResult
+	)
 	for _, result := range amap {
 		_, found := pmap[result.PatternId]
 		if !found {
 			if addPattern(ctx, tx, result, tr) {
 				saved++
+				sids = append(sids, result.Service.ID)
+				added = append(added, result)
 			}
 			new++
 		} else {
 			updatePattern(ctx, tx, result)
 		}
 	}
-	tx.Commit()
+	if err = tx.Commit(); err != nil {
+		return new, saved, err
+	}
+	//the new patterns keep the field names given to the patterns they replace
+	if err = carryForwardFieldNames(db, ctx, added); err != nil {
+		logger.HandleError(fmt.Sprintf("Unable to carry the field names forward to the new patterns: %s", err.Error()))
+	}
+	//the parsers of the services with new patterns need to be rebuilt
+	InvalidateParserCache(sids...)
 
-	return new, saved
+	return new, saved, nil
 }
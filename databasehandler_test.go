@@ -0,0 +1,139 @@
+package sequence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+//The databases the create scripts are tested against, SQLite runs in process and the
+//others run when the variable has the connection info of a local server and the driver
+//for it is built in, eg SEQUENCE_TEST_POSTGRES="host=localhost user=postgres password=test sslmode=disable".
+var createDatabaseTests = []struct {
+	dbtype string
+	env    string
+}{
+	{"sqlite3", ""},
+	{"postgres", "SEQUENCE_TEST_POSTGRES"},
+	{"mysql", "SEQUENCE_TEST_MYSQL"},
+	{"mssql", "SEQUENCE_TEST_MSSQL"},
+}
+
+func TestCreateDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "createdatabase")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	SetLogger(NewLogger(filepath.Join(dir, "log.txt"), "error"))
+	ctx := context.Background()
+
+	for _, tc := range createDatabaseTests {
+		t.Run(tc.dbtype, func(t *testing.T) {
+			cinfo, dbname := filepath.Join(dir, "sequence.sdb"), ""
+			if tc.env != "" {
+				cinfo = os.Getenv(tc.env)
+				if cinfo == "" {
+					t.Skipf("set %s to the connection info of a local %s server to run", tc.env, tc.dbtype)
+				}
+				if !driverRegistered(tc.dbtype) {
+					t.Skipf("the %s driver is not built in", tc.dbtype)
+				}
+				dbname = fmt.Sprintf("sequence_test_%d", time.Now().UnixNano())
+				t.Cleanup(func() { dropTestDatabase(t, tc.dbtype, cinfo, dbname) })
+			}
+			dialect, err := migrationDialect(tc.dbtype)
+			require.NoError(t, err)
+			migrations, err := loadMigrations(dialect)
+			require.NoError(t, err)
+
+			require.NoError(t, CreateDatabase(cinfo, tc.dbtype, dbname))
+			conn := openTestDatabase(t, tc.dbtype, cinfo, dbname)
+			applied, err := appliedMigrations(conn, ctx)
+			require.NoError(t, err)
+			require.Equal(t, len(migrations), schemaVersion(applied))
+			//the probes succeed when the change of each migration is in the database
+			for _, m := range migrations {
+				rows, err := conn.QueryContext(ctx, m.probe)
+				require.NoError(t, err, m.Name)
+				rows.Close()
+			}
+
+			//the tables are there, so creating it again is an error and not a silent no-op
+			require.Error(t, CreateDatabase(cinfo, tc.dbtype, dbname))
+		})
+	}
+}
+
+func TestCreateDatabaseErrors(t *testing.T) {
+	require.Error(t, CreateDatabase("sequence.sdb", "oracle", ""))
+	require.Error(t, CreateDatabase("sqlserver://localhost", "mssql", ""))
+	for _, dbtype := range []string{"postgres", "mysql", "mssql"} {
+		if !driverRegistered(dbtype) {
+			err := CreateDatabase("localhost", dbtype, "sequence")
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "-tags "+dbtype)
+		}
+	}
+}
+
+func TestPostgresConnInfo(t *testing.T) {
+	tests := []struct {
+		cinfo, expected string
+	}{
+		{"host=localhost user=postgres sslmode=disable", "host=localhost user=postgres sslmode=disable dbname='seq'"},
+		{"dbname=postgres host=localhost", "dbname=postgres host=localhost dbname='seq'"},
+		{"postgres://user:pw@localhost:5432/postgres?sslmode=disable", "postgres://user:pw@localhost:5432/seq?sslmode=disable"},
+		{"postgresql://localhost", "postgresql://localhost/seq"},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.expected, postgresConnInfo(tc.cinfo, "seq"), tc.cinfo)
+	}
+}
+
+//Returns a connection to the created database, switched to it for the dialects with USE.
+func openTestDatabase(t *testing.T, dbtype string, cinfo string, dbname string) *sql.Conn {
+	ctx := context.Background()
+	if dbtype == "postgres" && dbname != "" {
+		cinfo = postgresConnInfo(cinfo, dbname)
+	}
+	db, err := sql.Open(dbtype, cinfo)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	switch dbtype {
+	case "mysql":
+		_, err = conn.ExecContext(ctx, fmt.Sprintf("USE `%s`", dbname))
+	case "mssql":
+		_, err = conn.ExecContext(ctx, fmt.Sprintf("USE [%s]", dbname))
+	}
+	require.NoError(t, err)
+	return conn
+}
+
+func dropTestDatabase(t *testing.T, dbtype string, cinfo string, dbname string) {
+	db, err := sql.Open(dbtype, cinfo)
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	var stmt string
+	switch dbtype {
+	case "postgres":
+		stmt = fmt.Sprintf("DROP DATABASE IF EXISTS \"%s\"", dbname)
+	case "mysql":
+		stmt = fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", dbname)
+	case "mssql":
+		stmt = fmt.Sprintf("IF DB_ID(N'%s') IS NOT NULL DROP DATABASE [%s]", dbname, dbname)
+	}
+	if _, err = db.Exec(stmt); err != nil {
+		t.Logf("unable to drop the test database %s: %s", dbname, err.Error())
+	}
+}
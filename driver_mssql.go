@@ -0,0 +1,7 @@
+//go:build mssql
+// +build mssql
+
+package sequence
+
+//Registers the SQL Server driver, build with -tags mssql.
+import _ "github.com/denisenkom/go-mssqldb"
@@ -0,0 +1,7 @@
+//go:build mysql
+// +build mysql
+
+package sequence
+
+//Registers the MySQL driver, build with -tags mysql.
+import _ "github.com/go-sql-driver/mysql"
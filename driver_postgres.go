@@ -0,0 +1,7 @@
+//go:build postgres
+// +build postgres
+
+package sequence
+
+//Registers the PostgreSQL driver, build with -tags postgres.
+import _ "github.com/lib/pq"
@@ -0,0 +1,336 @@
+// This package is solely for the transformation and the output to file of sequence patterns found in server logs
+// as an Elasticsearch/OpenSearch ingest pipeline. The patterns of each service are matched by a grok processor that
+// only runs for that service, patterns with only space delimited string fields are matched with the faster dissect
+// processor when the grok does not match. The grok patterns are built with the same tag replacement as the Logstash grok output, so the same review
+// of the patterns is needed before the pipeline is used in production.
+package elasticsearch_ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/ryanfaircloth/sequence-RTG/sequence/logstash_grok"
+)
+
+const (
+	timeDefinitionPrefix = "SEQUENCE_TIME_"
+	//added to the tags of the documents that a processor failed on
+	failureTag = "_sequence_parse_failure"
+)
+
+var (
+	settings struct {
+		description  string
+		serviceField string
+		messageField string
+	}
+	logger *sequence.StandardLogger
+
+	//matches the captures in a grok expression, eg %{SYSLOGTIMESTAMP:timestamp}
+	grokCaptureRegex = regexp.MustCompile(`%\{(\w+):[^}]*\}`)
+	//matches a single grok field, eg %{IP:srcip}
+	grokFieldRegex = regexp.MustCompile(`^%\{(\w+):([^}]+)\}$`)
+	//the grok types that match any text, only these fields can be read by dissect
+	dissectGrokTypes = map[string]bool{"DATA": true, "GREEDYDATA": true, "NOTSPACE": true}
+)
+
+type pipeline struct {
+	Description string      `json:"description"`
+	Processors  []processor `json:"processors"`
+	OnFailure   []processor `json:"on_failure,omitempty"`
+}
+
+//Each processor is an object with a single key for the processor type.
+type processor map[string]interface{}
+
+type grokProcessor struct {
+	Tag                string            `json:"tag,omitempty"`
+	If                 string            `json:"if,omitempty"`
+	Field              string            `json:"field"`
+	Patterns           []string          `json:"patterns"`
+	PatternDefinitions map[string]string `json:"pattern_definitions,omitempty"`
+	OnFailure          []processor       `json:"on_failure,omitempty"`
+}
+
+type dissectProcessor struct {
+	Tag       string      `json:"tag,omitempty"`
+	If        string      `json:"if,omitempty"`
+	Field     string      `json:"field"`
+	Pattern   string      `json:"pattern"`
+	OnFailure []processor `json:"on_failure,omitempty"`
+}
+
+// Allows the user to set the logger to a global instance.
+func SetLogger(log *sequence.StandardLogger) {
+	logger = log
+}
+
+func readConfig(file string) error {
+	var configInfo struct {
+		Ingest struct {
+			Description  string
+			ServiceField string
+			MessageField string
+		}
+	}
+	if _, err := toml.DecodeFile(file, &configInfo); err != nil {
+		return err
+	}
+
+	settings.description = configInfo.Ingest.Description
+	settings.serviceField = configInfo.Ingest.ServiceField
+	settings.messageField = configInfo.Ingest.MessageField
+	if settings.serviceField == "" {
+		settings.serviceField = "service"
+	}
+	if settings.messageField == "" {
+		settings.messageField = "message"
+	}
+
+	//the grok patterns use the tags from the [grok] section
+	return logstash_grok.ReadConfig(file)
+}
+
+// This function takes patterns created by the sequence module and outputs them as an ingest pipeline.
+// The user can pass the pattern map if no database is used or pass the map created during the analysis.
+func OutputToFiles(outfile string, config string, complexitylevel float64, cmap map[string]sequence.AnalyzerResult, thresholdType string, thresholdValue string) (int, string, error) {
+	var (
+		err    error
+		count  int
+		top5   string
+		patmap map[string]sequence.AnalyzerResult
+	)
+
+	if config == "" {
+		config = "./sequence.toml"
+	}
+	//read the config to load the tags
+	if err = readConfig(config); err != nil {
+		return count, top5, err
+	}
+	if sequence.GetUseDatabase() && cmap == nil {
+		db, ctx := sequence.OpenDbandSetContext()
+		defer db.Close()
+		//get from the config instead
+		if thresholdType == "" {
+			thresholdType = sequence.GetThresholdType()
+			thresholdValue = sequence.GetThresholdValue()
+		}
+		patmap, top5 = sequence.GetPatternsWithExamplesFromDatabase(db, ctx, complexitylevel, thresholdType, thresholdValue)
+	} else {
+		patmap = cmap
+	}
+	logger.HandleInfo(fmt.Sprintf("Found %d patterns for output", len(patmap)))
+	count = len(patmap)
+
+	//open the file for the json output
+	jsonFile, err := sequence.OpenOutputFile(outfile)
+	if err != nil {
+		return count, top5, err
+	}
+	defer jsonFile.Close()
+	enc := json.NewEncoder(jsonFile)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return count, top5, enc.Encode(buildPipeline(patmap))
+}
+
+// Groups the patterns by service and builds the processors for each service in
+// service name order, so the pipeline does not change between exports.
+func buildPipeline(patmap map[string]sequence.AnalyzerResult) pipeline {
+	services := make(map[string][]sequence.AnalyzerResult)
+	for _, result := range patmap {
+		services[result.Service.Name] = append(services[result.Service.Name], result)
+	}
+	var names []string
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pl := pipeline{Description: settings.description, Processors: []processor{}}
+	for _, name := range names {
+		if p := buildServiceProcessor(name, services[name]); p != nil {
+			pl.Processors = append(pl.Processors, p)
+		}
+	}
+	//a message that none of the patterns of its service match is tagged and indexed, not rejected
+	pl.OnFailure = []processor{
+		{"set": map[string]interface{}{"field": "error.message", "value": "{{ _ingest.on_failure_message }}"}},
+		{"append": map[string]interface{}{"field": "tags", "value": []string{failureTag}}},
+	}
+	return pl
+}
+
+// Returns the processor for the patterns of a service. The grok processor with the
+// typed patterns comes first, then the dissect processors are chained through
+// on_failure, so each is only tried when the processors before it did not match. A
+// dissect matches any text in its fields, so it must not hide a more specific grok.
+func buildServiceProcessor(service string, results []sequence.AnalyzerResult) processor {
+	//patterns with fewer tags are more specific, so they are tried first
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].ComplexityScore != results[j].ComplexityScore {
+			return results[i].ComplexityScore < results[j].ComplexityScore
+		}
+		return results[i].Pattern < results[j].Pattern
+	})
+
+	var (
+		dissects []dissectProcessor
+		grok     = grokProcessor{Tag: "sequence-" + service, Field: settings.messageField}
+	)
+	for _, result := range results {
+		if dp, ok := buildDissectPattern(result.Pattern); ok {
+			dissects = append(dissects, dissectProcessor{Tag: result.PatternId, Field: settings.messageField, Pattern: dp})
+			continue
+		}
+		gp, defs := buildGrokPattern(result.Pattern)
+		grok.Patterns = append(grok.Patterns, gp)
+		for k, v := range defs {
+			if grok.PatternDefinitions == nil {
+				grok.PatternDefinitions = make(map[string]string)
+			}
+			grok.PatternDefinitions[k] = v
+		}
+	}
+
+	var first processor
+	for i := len(dissects) - 1; i >= 0; i-- {
+		if first != nil {
+			dissects[i].OnFailure = []processor{first}
+		}
+		first = processor{"dissect": &dissects[i]}
+	}
+	if len(grok.Patterns) > 0 {
+		if first != nil {
+			grok.OnFailure = []processor{first}
+		}
+		first = processor{"grok": &grok}
+	}
+	if first == nil {
+		return nil
+	}
+	//only the first processor of the chain needs the condition
+	cond := fmt.Sprintf("%s == '%s'", serviceFieldPath(), escapePainless(service))
+	if p, ok := first["grok"]; ok {
+		p.(*grokProcessor).If = cond
+	} else {
+		first["dissect"].(*dissectProcessor).If = cond
+	}
+	return first
+}
+
+// Returns the grok pattern and the definitions of the time patterns and token types it
+// uses, the time regexes from [timesettings.grok] are added as definitions with their
+// captures removed, so the time is captured by the field in the pattern.
+func buildGrokPattern(pattern string) (string, map[string]string) {
+	var (
+		defs  map[string]string
+		names []string
+	)
+	//take the time tags out before the tags are replaced, so they can be put back
+	//as references to the pattern definitions
+	regexTimeRegex := getRegexTimeRegex()
+	pattern = regexTimeRegex.ReplaceAllStringFunc(pattern, func(m string) string {
+		id := regexTimeRegex.FindStringSubmatch(m)[1]
+		name := ""
+		if rg, ok := sequence.GetTimeSettingsGrokValue(id); ok && rg != "" {
+			name = timeDefinitionPrefix + id
+			if defs == nil {
+				defs = make(map[string]string)
+			}
+			defs[name] = grokCaptureRegex.ReplaceAllString(rg, "%{$1}")
+		}
+		names = append(names, name)
+		return timePlaceholder(len(names) - 1)
+	})
+	gp := logstash_grok.ReplaceTags(pattern)
+	for name, rg := range logstash_grok.PatternDefinitions(gp) {
+		if defs == nil {
+			defs = make(map[string]string)
+		}
+		defs[name] = rg
+	}
+	for i, name := range names {
+		fieldname := "timestamp"
+		if i > 0 {
+			fieldname += strconv.Itoa(i)
+		}
+		//no regex is configured for the format, match anything
+		if name == "" {
+			name = "DATA"
+		}
+		gp = strings.Replace(gp, timePlaceholder(i), "%{"+name+":"+fieldname+"}", 1)
+	}
+	return gp, defs
+}
+
+// Matches %regextime:N% in the sequence pattern, the tag name is only known once
+// the sequence config has been read.
+func getRegexTimeRegex() *regexp.Regexp {
+	return regexp.MustCompile(`%` + regexp.QuoteMeta(sequence.TagRegExTime.String()) + `:([^%\s]*)%`)
+}
+
+func timePlaceholder(i int) string {
+	return "SEQUENCETIMEPLACEHOLDER" + strconv.Itoa(i) + "X"
+}
+
+// Returns the dissect pattern when every field in the pattern is a whole space
+// delimited word that grok would match as any text. Patterns with typed fields, eg
+// an ip or a number, or with time fields are not converted, as dissect does not check
+// the values and the times can contain spaces. A multiline field is only allowed at the end.
+func buildDissectPattern(pattern string) (string, bool) {
+	if pattern == "" || strings.HasPrefix(pattern, " ") || strings.Contains(pattern, "  ") {
+		return "", false
+	}
+	in := strings.Split(pattern, " ")
+	out := strings.Fields(logstash_grok.ReplaceTags(pattern))
+	if len(in) != len(out) {
+		return "", false
+	}
+	var (
+		dp     []string
+		fields int
+	)
+	for i, w := range in {
+		if !strings.Contains(w, "%") {
+			if strings.Contains(w, "{") || strings.Contains(w, "}") {
+				return "", false
+			}
+			dp = append(dp, w)
+			continue
+		}
+		m := grokFieldRegex.FindStringSubmatch(out[i])
+		if m == nil || !dissectGrokTypes[m[1]] || !strings.HasPrefix(w, "%") || !strings.HasSuffix(w, "%") || strings.Count(w, "%") != 2 {
+			return "", false
+		}
+		if strings.HasPrefix(w, "%"+sequence.TagRegExTime.String()+":") || w == "%msgtime%" || w == "%time%" {
+			return "", false
+		}
+		if w == "%multiline%" && i != len(in)-1 {
+			return "", false
+		}
+		dp = append(dp, "%{"+m[2]+"}")
+		fields++
+	}
+	if fields == 0 {
+		return "", false
+	}
+	return strings.Join(dp, " "), true
+}
+
+// Returns the painless path to the service field, a dotted field name is
+// navigated with the null safe operator.
+func serviceFieldPath() string {
+	return "ctx." + strings.Join(strings.Split(settings.serviceField, "."), "?.")
+}
+
+func escapePainless(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(s)
+}
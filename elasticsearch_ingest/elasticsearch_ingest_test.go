@@ -0,0 +1,110 @@
+package elasticsearch_ingest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	dissecttests = []struct {
+		data   string
+		result string
+		ok     bool
+	}{
+		{"connection %action% by %object% %string%", "connection %{action} by %{object} %{string}", true},
+		{"job %string% %string% in %action% ms", "job %{string} %{string1} in %{action} ms", true},
+		{"%object% said %multiline%", "%{object} said %{multiline}", true},
+		{"%multiline% said %object%", "", false},
+		//dissect does not check the values of typed fields
+		{"connection %action% by %srcuser% %integer%", "", false},
+		{"%srcip% said %multiline%", "", false},
+		{"from %srcip%:%srcport%", "", false},
+		{"at %msgtime% done", "", false},
+		{"at %regextime:1% done", "", false},
+		{"no fields at all", "", false},
+	}
+
+	groktests = []struct {
+		data   string
+		result string
+		defs   map[string]string
+	}{
+		{"%regextime:1% %srchost% %string%", "%{SEQUENCE_TIME_1:timestamp} %{HOSTNAME:srchost} %{DATA:string}", map[string]string{"SEQUENCE_TIME_1": "%{SYSLOGTIMESTAMP}"}},
+		{"from %regextime:3% to %regextime:2%", "from %{SEQUENCE_TIME_3:timestamp} to %{SEQUENCE_TIME_2:timestamp1}",
+			map[string]string{"SEQUENCE_TIME_3": "%{YEAR}[-|.|\\/]%{MONTHNUM}[-|.|\\/]%{MONTHDAY} %{TIME}", "SEQUENCE_TIME_2": "%{MONTH} %{MONTHDAY}, %{YEAR} %{TIME} [A|P]M"}},
+		{"at %regextime:99%", "at %{DATA:timestamp}", nil},
+		{"\"%object%\" %srcip%:%srcport%", "%{QUOTEDSTRING:object} %{IP:srcip}:%{INT:srcport}", nil},
+	}
+)
+
+func loadConfigs() {
+	file := "../sequence.toml"
+	sequence.ReadConfig(file)
+	readConfig(file)
+}
+
+func TestDissectPattern(t *testing.T) {
+	loadConfigs()
+	for _, tc := range dissecttests {
+		dp, ok := buildDissectPattern(tc.data)
+		require.Equal(t, tc.ok, ok, tc.data)
+		require.Equal(t, tc.result, dp, tc.data)
+	}
+}
+
+func TestGrokPattern(t *testing.T) {
+	loadConfigs()
+	for _, tc := range groktests {
+		gp, defs := buildGrokPattern(tc.data)
+		require.Equal(t, tc.result, gp, tc.data)
+		require.Equal(t, tc.defs, defs, tc.data)
+	}
+}
+
+func TestBuildPipeline(t *testing.T) {
+	loadConfigs()
+	patmap := make(map[string]sequence.AnalyzerResult)
+	for i, p := range []struct{ svc, pattern string }{
+		{"sshd", "session %action% for %object%"},
+		{"sshd", "%regextime:1% session closed for %srcuser%"},
+		{"o'brien", "connection %action% by %object% %string%"},
+	} {
+		ar := sequence.AnalyzerResult{Pattern: p.pattern, PatternId: string(rune('a' + i)), ComplexityScore: float64(i)}
+		ar.Service.Name = p.svc
+		patmap[p.pattern] = ar
+	}
+	pl := buildPipeline(patmap)
+	require.Len(t, pl.Processors, 2)
+
+	//a service with only dissect patterns
+	dp := pl.Processors[0]["dissect"].(*dissectProcessor)
+	require.Equal(t, "ctx.service == 'o\\'brien'", dp.If)
+	require.Equal(t, "connection %{action} by %{object} %{string}", dp.Pattern)
+	require.Nil(t, dp.OnFailure)
+
+	//the grok is tried first and the dissect runs on failure
+	gp := pl.Processors[1]["grok"].(*grokProcessor)
+	require.Equal(t, "ctx.service == 'sshd'", gp.If)
+	require.Equal(t, "message", gp.Field)
+	require.Equal(t, []string{"%{SEQUENCE_TIME_1:timestamp} session closed for %{USER:srcuser}"}, gp.Patterns)
+	require.Equal(t, "%{SYSLOGTIMESTAMP}", gp.PatternDefinitions["SEQUENCE_TIME_1"])
+	require.Len(t, gp.OnFailure, 1)
+	dp = gp.OnFailure[0]["dissect"].(*dissectProcessor)
+	require.Equal(t, "", dp.If)
+	require.Equal(t, "a", dp.Tag)
+	require.Equal(t, "session %{action} for %{object}", dp.Pattern)
+	require.Nil(t, dp.OnFailure)
+
+	//the messages that do not match are tagged
+	require.Len(t, pl.OnFailure, 2)
+	require.Equal(t, []string{failureTag}, pl.OnFailure[1]["append"].(map[string]interface{})["value"])
+
+	_, err := json.Marshal(pl)
+	require.NoError(t, err)
+
+	settings.serviceField = "service.name"
+	require.Equal(t, "ctx.service?.name", serviceFieldPath())
+}
@@ -0,0 +1,251 @@
+package sequence
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//The ways the examples of a pattern are picked once its budget is full.
+const (
+	//keep the first examples and drop the rest
+	ExampleStrategyFirst = "first"
+	//keep a uniform random sample of all the messages the pattern has matched
+	ExampleStrategyReservoir = "reservoir"
+	//keep the examples with the most different values, which make better test messages
+	ExampleStrategyVariety = "variety"
+)
+
+const defaultExampleBudget = 3
+
+//the random numbers for the reservoir, the analyzers run in parallel with --workers
+var exampleRand = struct {
+	sync.Mutex
+	r *rand.Rand
+}{r: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+func randInt63n(n int64) int64 {
+	exampleRand.Lock()
+	defer exampleRand.Unlock()
+	return exampleRand.r.Int63n(n)
+}
+
+func validateExamplesConfig() error {
+	if examples.budget < 1 {
+		return fmt.Errorf("the examples budget must be 1 or more, not %d", examples.budget)
+	}
+	switch examples.strategy {
+	case ExampleStrategyFirst, ExampleStrategyReservoir, ExampleStrategyVariety:
+		return nil
+	}
+	return fmt.Errorf("the examples strategy must be first, reservoir or variety, not %s", examples.strategy)
+}
+
+//Returns the most examples kept for a pattern.
+func exampleBudget() int {
+	if examples.budget <= 0 {
+		return defaultExampleBudget
+	}
+	return examples.budget
+}
+
+func hasExample(list []LogRecord, msg string) bool {
+	for _, ex := range list {
+		if ex.Message == msg {
+			return true
+		}
+	}
+	return false
+}
+
+//Adds the log record to the examples of the result if there is room in the budget, once it
+//is full the strategy decides if it replaces one of them. It is called before the example
+//count of the result is incremented for the log record.
+func AddExampleToAnalyzerResult(this *AnalyzerResult, lr LogRecord) {
+	if hasExample(this.Examples, lr.Message) {
+		return
+	}
+	budget := exampleBudget()
+	if len(this.Examples) < budget {
+		this.Examples = append(this.Examples, lr)
+		return
+	}
+	switch examples.strategy {
+	case ExampleStrategyReservoir:
+		//the log record is the nth message of the pattern, it is kept with a chance of budget/n
+		n := int64(this.ExampleCount) + 1
+		if n < int64(budget)+1 {
+			n = int64(budget) + 1
+		}
+		if j := randInt63n(n); j < int64(len(this.Examples)) {
+			this.Examples[j] = lr
+		}
+	case ExampleStrategyVariety:
+		replaceForVariety(this, lr)
+	}
+	//the first examples are kept as they are
+	if len(this.Examples) > budget {
+		this.Examples = this.Examples[:budget]
+	}
+}
+
+//Returns the examples for the union of two samples, a is the sample of na messages and b of
+//nb messages. With the first strategy the examples of a come first, with the reservoir
+//strategy each example is drawn from a or b weighted by the messages left in each so the
+//result is a sample of all na+nb messages, and with variety the most varied are kept.
+func MergeExamples(a []LogRecord, na int, b []LogRecord, nb int) []LogRecord {
+	budget := exampleBudget()
+	var out []LogRecord
+	switch examples.strategy {
+	case ExampleStrategyReservoir:
+		a, b = shuffleExamples(a), shuffleExamples(b)
+		wa, wb := int64(na), int64(nb)
+		for len(out) < budget && (len(a) > 0 || len(b) > 0) {
+			//the counts can not be less than the examples of the sample
+			if wa < int64(len(a)) {
+				wa = int64(len(a))
+			}
+			if wb < int64(len(b)) {
+				wb = int64(len(b))
+			}
+			var lr LogRecord
+			if len(b) == 0 || (len(a) > 0 && randInt63n(wa+wb) < wa) {
+				lr, a, wa = a[0], a[1:], wa-1
+			} else {
+				lr, b, wb = b[0], b[1:], wb-1
+			}
+			if !hasExample(out, lr.Message) {
+				out = append(out, lr)
+			}
+		}
+	case ExampleStrategyVariety:
+		out = mostVaried(append(append([]LogRecord{}, a...), b...), budget)
+	default:
+		for _, lr := range append(append([]LogRecord{}, a...), b...) {
+			if len(out) >= budget {
+				break
+			}
+			if !hasExample(out, lr.Message) {
+				out = append(out, lr)
+			}
+		}
+	}
+	return out
+}
+
+func shuffleExamples(list []LogRecord) []LogRecord {
+	out := append([]LogRecord{}, list...)
+	for i := len(out) - 1; i > 0; i-- {
+		j := int(randInt63n(int64(i + 1)))
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+//Returns the values of the tokens of the message, the examples of a pattern share the
+//literals so only the values of the fields make them different.
+func exampleValues(msg string) []string {
+	seq, _, err := ScanMessage(NewScanner(), msg, "")
+	if err != nil {
+		return []string{msg}
+	}
+	values := make([]string, len(seq))
+	for i, t := range seq {
+		values[i] = t.Value
+	}
+	return values
+}
+
+//Returns the number of different values at each token position over the examples.
+func varietyScore(values [][]string) int {
+	seen := make(map[string]bool)
+	for _, vs := range values {
+		for i, v := range vs {
+			seen[strconv.Itoa(i)+"\x00"+v] = true
+		}
+	}
+	return len(seen)
+}
+
+//Returns the scanned values of the example message, they are kept with the result so the
+//examples are not scanned again for every message once the budget is full.
+func (this *AnalyzerResult) scannedValues(msg string) []string {
+	if values, ok := this.exampleValues[msg]; ok {
+		return values
+	}
+	if this.exampleValues == nil {
+		this.exampleValues = make(map[string][]string)
+	}
+	values := exampleValues(msg)
+	this.exampleValues[msg] = values
+	return values
+}
+
+//Replaces the example that adds the least variety with the log record, if that gives
+//the examples more different values.
+func replaceForVariety(this *AnalyzerResult, lr LogRecord) {
+	list := this.Examples
+	values := make([][]string, len(list))
+	for i, ex := range list {
+		values[i] = this.scannedValues(ex.Message)
+	}
+	best, bestScore := -1, varietyScore(values)
+	cand := exampleValues(lr.Message)
+	for i := range list {
+		old := values[i]
+		values[i] = cand
+		if score := varietyScore(values); score > bestScore {
+			best, bestScore = i, score
+		}
+		values[i] = old
+	}
+	if best >= 0 {
+		delete(this.exampleValues, list[best].Message)
+		list[best] = lr
+		this.exampleValues[lr.Message] = cand
+	}
+	//the examples can be replaced outside of here, so only the values of the current ones are kept
+	if len(this.exampleValues) > len(list) {
+		kept := make(map[string][]string, len(list))
+		for _, ex := range list {
+			if v, ok := this.exampleValues[ex.Message]; ok {
+				kept[ex.Message] = v
+			}
+		}
+		this.exampleValues = kept
+	}
+}
+
+//Returns up to budget examples picked one at a time by the most values they add,
+//the earlier examples are picked on a tie so the kept examples change as little as possible.
+func mostVaried(list []LogRecord, budget int) []LogRecord {
+	var (
+		out    []LogRecord
+		picked [][]string
+	)
+	values := make([][]string, len(list))
+	for i, ex := range list {
+		values[i] = exampleValues(ex.Message)
+	}
+	used := make([]bool, len(list))
+	for len(out) < budget {
+		best, bestScore := -1, -1
+		for i, ex := range list {
+			if used[i] || hasExample(out, ex.Message) {
+				continue
+			}
+			if score := varietyScore(append(picked, values[i])); score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+		used[best] = true
+		out = append(out, list[best])
+		picked = append(picked, values[best])
+	}
+	return out
+}
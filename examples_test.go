@@ -0,0 +1,132 @@
+package sequence
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setExamplesConfig(t *testing.T, budget int, strategy string) {
+	saved := examples
+	t.Cleanup(func() { examples = saved })
+	examples.budget = budget
+	examples.strategy = strategy
+}
+
+func exampleMessages(list []LogRecord) []string {
+	var msgs []string
+	for _, lr := range list {
+		msgs = append(msgs, lr.Message)
+	}
+	return msgs
+}
+
+func TestAddExampleFirst(t *testing.T) {
+	setExamplesConfig(t, 2, ExampleStrategyFirst)
+	var ar AnalyzerResult
+	for _, msg := range []string{"a 1", "a 1", "a 2", "a 3"} {
+		AddExampleToAnalyzerResult(&ar, LogRecord{Message: msg})
+		ar.ExampleCount++
+	}
+	require.Equal(t, []string{"a 1", "a 2"}, exampleMessages(ar.Examples))
+}
+
+func TestAddExampleReservoir(t *testing.T) {
+	setExamplesConfig(t, 1, ExampleStrategyReservoir)
+	msgs := []string{"a 1", "a 2", "a 3", "a 4"}
+	counts := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		var ar AnalyzerResult
+		for _, msg := range msgs {
+			AddExampleToAnalyzerResult(&ar, LogRecord{Message: msg})
+			ar.ExampleCount++
+		}
+		require.Len(t, ar.Examples, 1)
+		counts[ar.Examples[0].Message]++
+	}
+	//each message is kept about a quarter of the time, not just the first
+	for _, msg := range msgs {
+		require.InDelta(t, 1000, counts[msg], 150, msg)
+	}
+}
+
+func TestMergeExamples(t *testing.T) {
+	a := []LogRecord{{Message: "a 1"}, {Message: "a 2"}}
+	b := []LogRecord{{Message: "a 2"}, {Message: "a 3"}}
+
+	setExamplesConfig(t, 3, ExampleStrategyFirst)
+	require.Equal(t, []string{"a 1", "a 2", "a 3"}, exampleMessages(MergeExamples(a, 2, b, 2)))
+	examples.budget = 2
+	require.Equal(t, []string{"a 1", "a 2"}, exampleMessages(MergeExamples(a, 2, b, 2)))
+
+	//the sample from the history of 90 messages is kept about 90% of the time
+	setExamplesConfig(t, 1, ExampleStrategyReservoir)
+	fromA := 0
+	for i := 0; i < 2000; i++ {
+		out := MergeExamples(a[:1], 90, b[1:], 10)
+		require.Len(t, out, 1)
+		if out[0].Message == "a 1" {
+			fromA++
+		}
+	}
+	require.InDelta(t, 1800, fromA, 100)
+}
+
+func TestExampleVariety(t *testing.T) {
+	setExamplesConfig(t, 2, ExampleStrategyVariety)
+	bob1 := LogRecord{Message: "user bob login from 10.0.0.1"}
+	bob2 := LogRecord{Message: "user bob login from 10.0.0.2"}
+	amy3 := LogRecord{Message: "user amy login from 10.0.0.3"}
+
+	require.Equal(t, []LogRecord{bob1, amy3}, MergeExamples([]LogRecord{bob1, bob2}, 2, []LogRecord{amy3}, 1))
+
+	ar := AnalyzerResult{Examples: []LogRecord{bob1, bob2}, ExampleCount: 2}
+	AddExampleToAnalyzerResult(&ar, amy3)
+	require.Equal(t, []LogRecord{amy3, bob2}, ar.Examples)
+	//a message that adds no new values does not replace one
+	AddExampleToAnalyzerResult(&ar, LogRecord{Message: "user bob login from 10.0.0.3"})
+	require.Equal(t, []LogRecord{amy3, bob2}, ar.Examples)
+}
+
+func TestExampleVarietyCachesValues(t *testing.T) {
+	setExamplesConfig(t, 2, ExampleStrategyVariety)
+	bob1 := LogRecord{Message: "user bob login from 10.0.0.1"}
+	bob2 := LogRecord{Message: "user bob login from 10.0.0.2"}
+	amy3 := LogRecord{Message: "user amy login from 10.0.0.3"}
+
+	ar := AnalyzerResult{Examples: []LogRecord{bob1, bob2}, ExampleCount: 2}
+	AddExampleToAnalyzerResult(&ar, amy3)
+	require.Len(t, ar.exampleValues, 2)
+	require.Equal(t, exampleValues(amy3.Message), ar.exampleValues[amy3.Message])
+	require.Contains(t, ar.exampleValues, bob2.Message)
+
+	//the values of examples replaced outside of the strategy are dropped
+	ar.Examples = []LogRecord{bob1, bob2}
+	AddExampleToAnalyzerResult(&ar, LogRecord{Message: "user amy login from 10.0.0.4"})
+	require.Len(t, ar.exampleValues, len(ar.Examples))
+	for _, ex := range ar.Examples {
+		require.Contains(t, ar.exampleValues, ex.Message)
+	}
+}
+
+func TestValidateExamplesConfig(t *testing.T) {
+	tests := []struct {
+		budget   int
+		strategy string
+		valid    bool
+	}{
+		{3, ExampleStrategyFirst, true},
+		{1, ExampleStrategyVariety, true},
+		{0, ExampleStrategyFirst, false},
+		{-1, ExampleStrategyReservoir, false},
+		{3, "newest", false},
+	}
+	for _, tc := range tests {
+		setExamplesConfig(t, tc.budget, tc.strategy)
+		if tc.valid {
+			require.NoError(t, validateExamplesConfig(), "%d %s", tc.budget, tc.strategy)
+		} else {
+			require.Error(t, validateExamplesConfig(), "%d %s", tc.budget, tc.strategy)
+		}
+	}
+}
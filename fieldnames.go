@@ -0,0 +1,272 @@
+package sequence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+//field names that can be used by all of the exporters
+var fieldNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+//Returns the tags of the pattern in order, eg %srcip%, read from the pattern at
+//each of the tag positions.
+func patternTags(pattern string, tagPositions string) []string {
+	var tags []string
+	for _, pos := range SplitToInt(tagPositions, ",") {
+		if pos < 0 || pos >= len(pattern) || pattern[pos] != '%' {
+			return nil
+		}
+		end := strings.IndexByte(pattern[pos+1:], '%')
+		if end < 0 {
+			return nil
+		}
+		tags = append(tags, pattern[pos:pos+end+2])
+	}
+	return tags
+}
+
+//Returns the field name overrides keyed by the index of the tag in the pattern,
+//which is the order the exporters name the fields in.
+func (this AnalyzerResult) FieldNamesByIndex() map[int]string {
+	if len(this.FieldNames) == 0 {
+		return nil
+	}
+	names := make(map[int]string)
+	for i, pos := range SplitToInt(this.TagPositions, ",") {
+		if name, ok := this.FieldNames[pos]; ok {
+			names[i] = name
+		}
+	}
+	return names
+}
+
+//Returns the field name overrides of every pattern keyed by the pattern id and the
+//position of the tag in the pattern.
+func getPatternFieldNames(db *sql.DB, ctx context.Context) map[string]map[int]string {
+	fmap, err := queryPatternFieldNames(db, ctx, rebind(`SELECT pattern_id, position, field_name FROM "PatternFieldNames"`))
+	if err != nil {
+		logger.DatabaseSelectFailed("pattern field names", "All", err.Error())
+	}
+	return fmap
+}
+
+//Returns the field name overrides of the patterns of the service keyed by the pattern id
+//and the position of the tag in the pattern.
+func getServiceFieldNames(db *sql.DB, ctx context.Context, serviceid string) (map[string]map[int]string, error) {
+	fmap, err := queryPatternFieldNames(db, ctx, rebind(`SELECT f.pattern_id, f.position, f.field_name
+		FROM "PatternFieldNames" f INNER JOIN "Patterns" p ON p.id = f.pattern_id WHERE p.service_id = ?`), serviceid)
+	if err != nil {
+		logger.DatabaseSelectFailed("pattern field names", serviceid, err.Error())
+	}
+	return fmap, err
+}
+
+func queryPatternFieldNames(db *sql.DB, ctx context.Context, query string, args ...interface{}) (map[string]map[int]string, error) {
+	fmap := make(map[string]map[int]string)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmap, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id   string
+			pos  int
+			name string
+		)
+		if err = rows.Scan(&id, &pos, &name); err != nil {
+			return fmap, err
+		}
+		if fmap[id] == nil {
+			fmap[id] = make(map[int]string)
+		}
+		fmap[id][pos] = name
+	}
+	return fmap, rows.Err()
+}
+
+//Returns the tags of the pattern with their positions and field name overrides.
+func GetPatternFieldNames(patternid string) (AnalyzerResult, []string, error) {
+	db, ctx := OpenDbandSetContext()
+	defer db.Close()
+	p, err := models.FindPattern(ctx, db, patternid)
+	if err != nil {
+		logger.DatabaseSelectFailed("pattern", patternid, err.Error())
+		return AnalyzerResult{}, nil, err
+	}
+	ar := AnalyzerResult{PatternId: p.ID, Pattern: p.SequencePattern, TagPositions: p.TagPositions.String}
+	ar.FieldNames = getPatternFieldNames(db, ctx)[p.ID]
+	return ar, patternTags(ar.Pattern, ar.TagPositions), nil
+}
+
+//Sets the field name of the tag at the position in the pattern, the position is one
+//of the tag positions of the pattern. An empty name removes the override. The error
+//is sql.ErrNoRows if the pattern does not exist.
+func SetPatternFieldName(patternid string, position int, name string) error {
+	db, ctx := OpenDbandSetContext()
+	defer db.Close()
+	p, err := models.FindPattern(ctx, db, patternid)
+	if err != nil {
+		logger.DatabaseSelectFailed("pattern", patternid, err.Error())
+		return err
+	}
+	found := false
+	for _, pos := range SplitToInt(p.TagPositions.String, ",") {
+		if pos == position {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("The pattern %s has no tag at position %d, the tag positions are %s", patternid, position, p.TagPositions.String)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = setPatternFieldName(ctx, tx, patternid, position, name); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func setPatternFieldName(ctx context.Context, tx *sql.Tx, patternid string, position int, name string) error {
+	_, err := tx.ExecContext(ctx, rebind(`DELETE FROM "PatternFieldNames" WHERE pattern_id = ? AND position = ?`), patternid, position)
+	if err != nil {
+		logger.DatabaseUpdateFailed("pattern field name", patternid, err.Error())
+		return err
+	}
+	if name == "" {
+		return nil
+	}
+	_, err = tx.ExecContext(ctx, rebind(`INSERT INTO "PatternFieldNames" (pattern_id, position, field_name, date_created) VALUES (?, ?, ?, ?)`),
+		patternid, position, name, time.Now())
+	if err != nil {
+		logger.DatabaseInsertFailed("pattern field name", patternid, err.Error())
+	}
+	return err
+}
+
+//The lowest token alignment of an old pattern with a new pattern for its field names to
+//be carried forward.
+const carryForwardMinScore = 0.7
+
+//Returns the pattern of the same service the field names of the new pattern are carried
+//forward from, with the tag positions of the old pattern mapped to those of the new one.
+//The old pattern has the same tags in the same order and its tokens line up with the
+//tokens of the new pattern with at least carryForwardMinScore, so two patterns that only
+//share their tags do not swap names. The best aligned pattern is used, on a tie the first
+//in the list.
+func fieldNameSource(result AnalyzerResult, named []*models.Pattern) (*models.Pattern, map[int]int) {
+	tags := strings.Join(patternTags(result.Pattern, result.TagPositions), " ")
+	if tags == "" {
+		return nil, nil
+	}
+	toks, err := patternTokens(result.Pattern, result.TagPositions)
+	if err != nil {
+		return nil, nil
+	}
+	var (
+		best      *models.Pattern
+		bestScore float64
+	)
+	for _, p := range named {
+		if p.ID == result.PatternId || p.ServiceID != result.Service.ID || strings.Join(patternTags(p.SequencePattern, p.TagPositions.String), " ") != tags {
+			continue
+		}
+		ptoks, err := patternTokens(p.SequencePattern, p.TagPositions.String)
+		if err != nil {
+			continue
+		}
+		if score := alignmentScore(ptoks, toks); score >= carryForwardMinScore && score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	return best, alignTagPositions(best.SequencePattern, best.TagPositions.String, result.Pattern, result.TagPositions)
+}
+
+//Copies the field names to the new patterns from the pattern of the same service they
+//line up with, as happens when the analysis derives the same tokens with a change to the
+//literals. If more than one pattern lines up as well, the names come from the one matched
+//most recently.
+func carryForwardFieldNames(db *sql.DB, ctx context.Context, results []AnalyzerResult) error {
+	byService := make(map[string][]AnalyzerResult)
+	for _, result := range results {
+		if len(patternTags(result.Pattern, result.TagPositions)) > 0 {
+			byService[result.Service.ID] = append(byService[result.Service.ID], result)
+		}
+	}
+	type carry struct {
+		result AnalyzerResult
+		from   string
+		names  map[int]string
+	}
+	var carries []carry
+	for sid, list := range byService {
+		fmap, err := getServiceFieldNames(db, ctx, sid)
+		if err != nil {
+			return err
+		}
+		if len(fmap) == 0 {
+			continue
+		}
+		var ids []interface{}
+		for id := range fmap {
+			ids = append(ids, id)
+		}
+		named, err := models.Patterns(qm.WhereIn(models.PatternColumns.ID+" IN ?", ids...), qm.OrderBy(models.PatternColumns.DateLastMatched+" DESC")).All(ctx, db)
+		if err != nil {
+			logger.DatabaseSelectFailed("patterns", "With field names", err.Error())
+			return err
+		}
+		for _, result := range list {
+			if len(fmap[result.PatternId]) > 0 {
+				continue
+			}
+			p, pmap := fieldNameSource(result, named)
+			if p == nil {
+				continue
+			}
+			names := make(map[int]string)
+			for pos, name := range fmap[p.ID] {
+				if npos, ok := pmap[pos]; ok {
+					names[npos] = name
+				}
+			}
+			carries = append(carries, carry{result, p.ID, names})
+		}
+	}
+	if len(carries) == 0 {
+		return nil
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, c := range carries {
+		for pos, name := range c.names {
+			if err = setPatternFieldName(ctx, tx, c.result.PatternId, pos, name); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	for _, c := range carries {
+		logger.HandleInfo(fmt.Sprintf("Field names of the pattern %s carried forward to the pattern %s", c.from, c.result.PatternId))
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package sequence
+
+import (
+	"testing"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence/models"
+	"github.com/stretchr/testify/require"
+	"github.com/volatiletech/null"
+)
+
+func TestPatternTags(t *testing.T) {
+	tests := []struct {
+		pattern, pos string
+		tags         []string
+	}{
+		{"%srcuser% logged in from %srcip% and %srcip%", "0,25,37", []string{"%srcuser%", "%srcip%", "%srcip%"}},
+		{"job %string% in queue %regextime:3%", "4,22", []string{"%string%", "%regextime:3%"}},
+		{"no tags here", "", nil},
+		{"%string% moved", "3", nil},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.tags, patternTags(tc.pattern, tc.pos), tc.pattern)
+	}
+}
+
+func TestFieldNamesByIndex(t *testing.T) {
+	ar := AnalyzerResult{
+		Pattern:      "%srcuser% logged in from %srcip% and %srcip%",
+		TagPositions: "0,25,37",
+		FieldNames:   map[int]string{37: "proxyip", 99: "unused"},
+	}
+	require.Equal(t, map[int]string{2: "proxyip"}, ar.FieldNamesByIndex())
+	ar.FieldNames = nil
+	require.Nil(t, ar.FieldNamesByIndex())
+}
+
+func TestFieldNameSource(t *testing.T) {
+	named := []*models.Pattern{
+		{ID: "other", ServiceID: "svc", SequencePattern: "reject %srcip% code %integer%", TagPositions: null.StringFrom("7,20")},
+		{ID: "old", ServiceID: "svc", SequencePattern: "connect from %srcip% port %integer%", TagPositions: null.StringFrom("13,26")},
+		{ID: "elsewhere", ServiceID: "svc2", SequencePattern: "connect from %srcip% on port %integer%", TagPositions: null.StringFrom("13,29")},
+	}
+	result := AnalyzerResult{PatternId: "new", Service: models.Service{ID: "svc"}, Pattern: "connect from %srcip% on port %integer%", TagPositions: "13,29"}
+	p, pmap := fieldNameSource(result, named)
+	require.NotNil(t, p)
+	require.Equal(t, "old", p.ID)
+	require.Equal(t, map[int]int{13: 13, 26: 29}, pmap)
+
+	//the same tags with other literals do not line up
+	result = AnalyzerResult{PatternId: "new", Service: models.Service{ID: "svc"}, Pattern: "drop %srcip% after %integer%", TagPositions: "5,19"}
+	p, _ = fieldNameSource(result, named)
+	require.Nil(t, p)
+}
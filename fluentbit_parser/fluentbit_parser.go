@@ -0,0 +1,133 @@
+// This package is solely for the transformation and the output to file of sequence patterns found in server logs
+// as Fluent Bit regex parsers. Each pattern is written as a named [PARSER] entry in a parsers.conf file, the name
+// includes the service so the parsers for a service can be listed in the parser filter for its inputs.
+// The regular expressions are built from the pattern tokens, with a named capture for each tag, and need to be
+// reviewed before use in production, as with the other exporters.
+package fluentbit_parser
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+)
+
+var (
+	settings struct {
+		namePrefix string
+	}
+	logger *sequence.StandardLogger
+
+	//parser names cannot contain spaces
+	nameRegex = regexp.MustCompile(`[^a-zA-Z0-9_.\-]`)
+)
+
+// Allows the user to set the logger to a global instance.
+func SetLogger(log *sequence.StandardLogger) {
+	logger = log
+}
+
+func readConfig(file string) error {
+	var configInfo struct {
+		Fluentbit struct {
+			NamePrefix string
+		}
+	}
+	if _, err := toml.DecodeFile(file, &configInfo); err != nil {
+		return err
+	}
+
+	settings.namePrefix = configInfo.Fluentbit.NamePrefix
+	if settings.namePrefix == "" {
+		settings.namePrefix = "sequence"
+	}
+	return nil
+}
+
+// This function takes patterns created by the sequence module and outputs them as Fluent Bit parsers.
+// The user can pass the pattern map if no database is used or pass the map created during the analysis.
+func OutputToFiles(outfile string, config string, complexitylevel float64, cmap map[string]sequence.AnalyzerResult, thresholdType string, thresholdValue string) (int, string, error) {
+	var (
+		err    error
+		count  int
+		top5   string
+		patmap map[string]sequence.AnalyzerResult
+	)
+
+	if config == "" {
+		config = "./sequence.toml"
+	}
+	//read the config to load the parser names
+	if err = readConfig(config); err != nil {
+		return count, top5, err
+	}
+	if sequence.GetUseDatabase() && cmap == nil {
+		db, ctx := sequence.OpenDbandSetContext()
+		defer db.Close()
+		//get from the config instead
+		if thresholdType == "" {
+			thresholdType = sequence.GetThresholdType()
+			thresholdValue = sequence.GetThresholdValue()
+		}
+		patmap, top5 = sequence.GetPatternsWithExamplesFromDatabase(db, ctx, complexitylevel, thresholdType, thresholdValue)
+	} else {
+		patmap = cmap
+	}
+	logger.HandleInfo(fmt.Sprintf("Found %d patterns for output", len(patmap)))
+	count = len(patmap)
+	//open the file for the parsers output
+	confFile, err := sequence.OpenOutputFile(outfile)
+	if err != nil {
+		return count, top5, err
+	}
+	defer confFile.Close()
+
+	//sort by service and pattern so the file does not change between exports
+	var results []sequence.AnalyzerResult
+	for _, result := range patmap {
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Service.Name != results[j].Service.Name {
+			return results[i].Service.Name < results[j].Service.Name
+		}
+		return results[i].Pattern < results[j].Pattern
+	})
+	for _, result := range results {
+		if err := writeParser(confFile, result); err != nil {
+			logger.HandleError(fmt.Sprintf("Unable to export pattern %s: %s", result.PatternId, err.Error()))
+		}
+	}
+	return count, top5, nil
+}
+
+func writeParser(f *os.File, result sequence.AnalyzerResult) error {
+	rg, err := buildRegex(result)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(f, "# service: %s\n# %d log messages matched\n", result.Service.Name, result.ExampleCount)
+	if len(result.Examples) > 0 {
+		fmt.Fprintf(f, "# %s\n", strings.Replace(result.Examples[0].Message, "\n", " ", -1))
+	}
+	fmt.Fprintf(f, "[PARSER]\n    Name   %s\n    Format regex\n    Regex  %s\n\n", getParserName(result), rg)
+	return nil
+}
+
+// Fluent Bit uses Onigmo for the regular expressions, which only supports the
+// (?<name>) syntax for named groups.
+func buildRegex(result sequence.AnalyzerResult) (string, error) {
+	rg, err := sequence.PatternToRegex(result.Pattern, result.TagPositions)
+	if err != nil {
+		return "", err
+	}
+	return strings.Replace(rg, "(?P<", "(?<", -1), nil
+}
+
+func getParserName(result sequence.AnalyzerResult) string {
+	return nameRegex.ReplaceAllString(settings.namePrefix+"-"+result.Service.Name+"-"+result.PatternId, "_")
+}
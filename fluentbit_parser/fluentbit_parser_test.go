@@ -0,0 +1,45 @@
+package fluentbit_parser
+
+import (
+	"testing"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	regextests = []struct {
+		pattern string
+		pos     string
+		result  string
+	}{
+		{"%srcuser% logged in from %srcip%", "0,25", `^(?<srcuser>\S+?)\s+logged\s+in\s+from\s+(?<srcip>\d{1,3}(?:\.\d{1,3}){3})$`},
+		{"job %string% %string% in %integer% ms", "4,13,25", `^job\s+(?<string>\S+?)\s+(?<string1>\S+?)\s+in\s+(?<integer>[+-]?\d+)\s+ms$`},
+		{"by (uid=%srcuid%)", "8", `^by\s+\(uid=\s*(?<srcuid>[+-]?\d+)\s*\)$`},
+	}
+)
+
+func loadConfigs() {
+	sequence.ReadConfig("../sequence.toml")
+}
+
+func TestBuildRegex(t *testing.T) {
+	loadConfigs()
+	for _, tc := range regextests {
+		rg, err := buildRegex(sequence.AnalyzerResult{Pattern: tc.pattern, TagPositions: tc.pos})
+		require.NoError(t, err, tc.pattern)
+		require.Equal(t, tc.result, rg, tc.pattern)
+	}
+}
+
+func TestParserName(t *testing.T) {
+	require.NoError(t, readConfig("../sequence.toml"))
+	ar := sequence.AnalyzerResult{PatternId: "abc123"}
+	ar.Service.Name = "my service/1"
+	require.Equal(t, "sequence-my_service_1-abc123", getParserName(ar))
+
+	saved := settings
+	defer func() { settings = saved }()
+	settings.namePrefix = "app logs"
+	require.Equal(t, "app_logs-my_service_1-abc123", getParserName(ar))
+}
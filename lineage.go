@@ -0,0 +1,356 @@
+package sequence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence/models"
+	"github.com/volatiletech/sqlboiler/boil"
+)
+
+//A link between a pattern and the older pattern it replaces, the score is the
+//token alignment of the two patterns between 0 and 1.
+type PatternLink struct {
+	PatternId    string
+	SupersedesId string
+	Service      string
+	Score        float64
+	Migrated     bool
+	DateCreated  time.Time
+}
+
+//Returns the tokens of the pattern, the tags are kept as they are written in the
+//pattern, eg %srcip%, so the tokens of two patterns can be compared.
+func patternTokens(pattern string, tagPositions string) ([]string, error) {
+	scanner := NewScanner()
+	seq, _, err := scanner.Scan(pattern, true, SplitToInt(tagPositions, ","))
+	if err != nil {
+		return nil, err
+	}
+	toks := make([]string, len(seq))
+	for i, t := range seq {
+		toks[i] = t.Value
+	}
+	return toks, nil
+}
+
+//Returns the index pairs of the tokens that are the same in both lists, in order,
+//using the longest common subsequence of the two.
+func alignTokens(a []string, b []string) [][2]int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var pairs [][2]int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
+
+//Returns how well the tokens of the two patterns line up, 1 when they are the same
+//and 0 when they have no tokens in common.
+func alignmentScore(a []string, b []string) float64 {
+	if len(a)+len(b) == 0 {
+		return 0
+	}
+	return float64(2*len(alignTokens(a, b))) / float64(len(a)+len(b))
+}
+
+//Returns true if the pattern parses all of the examples.
+func matchesExamples(pattern string, tagPositions string, examples []string) bool {
+	if len(examples) == 0 {
+		return false
+	}
+	seq, _, err := NewScanner().Scan(pattern, true, SplitToInt(tagPositions, ","))
+	if err != nil {
+		return false
+	}
+	parser := NewParser()
+	if err = parser.Add(seq); err != nil {
+		return false
+	}
+	scanner := NewScanner()
+	for _, ex := range examples {
+		mseq, _, err := ScanMessage(scanner, ex, "")
+		if err != nil {
+			return false
+		}
+		if _, err = parser.Parse(mseq); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+//Maps the tag positions of the old pattern to the tag positions of the tags that
+//line up with them in the new pattern.
+func alignTagPositions(oldPattern string, oldPositions string, newPattern string, newPositions string) map[int]int {
+	a, err := patternTokens(oldPattern, oldPositions)
+	if err != nil {
+		return nil
+	}
+	b, err := patternTokens(newPattern, newPositions)
+	if err != nil {
+		return nil
+	}
+	//the index of each tag among the tags of the pattern
+	tagIndex := func(toks []string) map[int]int {
+		m := make(map[int]int)
+		n := 0
+		for i, t := range toks {
+			if len(t) >= 2 && t[0] == '%' && t[len(t)-1] == '%' {
+				m[i] = n
+				n++
+			}
+		}
+		return m
+	}
+	ta, tb := tagIndex(a), tagIndex(b)
+	pa, pb := SplitToInt(oldPositions, ","), SplitToInt(newPositions, ",")
+	pmap := make(map[int]int)
+	for _, pair := range alignTokens(a, b) {
+		ia, oka := ta[pair[0]]
+		ib, okb := tb[pair[1]]
+		if oka && okb && ia < len(pa) && ib < len(pb) {
+			pmap[pa[ia]] = pb[ib]
+		}
+	}
+	return pmap
+}
+
+//Looks for the patterns that replace older patterns of the same service. A pattern
+//is a likely successor of an old pattern when the old one has not been matched since
+//the new one was created, the tokens of the two line up with at least the minimum
+//score and the new pattern parses all of the examples stored for the old one. The
+//links found are saved and returned, the patterns that already have a link are skipped.
+func DetectLineage(minScore float64) ([]PatternLink, error) {
+	db, ctx := OpenDbandSetContext()
+	defer db.Close()
+	existing, err := getPatternLinks(db, ctx)
+	if err != nil {
+		return nil, err
+	}
+	linked := make(map[string]bool)
+	for _, l := range existing {
+		linked[l.PatternId] = true
+		linked[l.SupersedesId] = true
+	}
+	patterns, err := models.Patterns(models.PatternWhere.IgnorePattern.EQ(false)).All(ctx, db)
+	if err != nil {
+		logger.DatabaseSelectFailed("patterns", "All", err.Error())
+		return nil, err
+	}
+	services := getServicesFromDatabase(db, ctx)
+	tokens := make(map[string][]string)
+	for _, p := range patterns {
+		if tokens[p.ID], err = patternTokens(p.SequencePattern, p.TagPositions.String); err != nil {
+			logger.HandleError(fmt.Sprintf("Unable to scan the pattern %s: %s", p.ID, err.Error()))
+		}
+	}
+
+	var links []PatternLink
+	for _, np := range patterns {
+		if linked[np.ID] || tokens[np.ID] == nil {
+			continue
+		}
+		//the old patterns that line up best are checked first
+		type candidate struct {
+			p     *models.Pattern
+			score float64
+		}
+		var cands []candidate
+		for _, op := range patterns {
+			if op.ID == np.ID || op.ServiceID != np.ServiceID || linked[op.ID] || tokens[op.ID] == nil || !op.DateLastMatched.Before(np.DateCreated) {
+				continue
+			}
+			if score := alignmentScore(tokens[op.ID], tokens[np.ID]); score >= minScore && score < 1 {
+				cands = append(cands, candidate{op, score})
+			}
+		}
+		sort.Slice(cands, func(i, j int) bool {
+			if cands[i].score != cands[j].score {
+				return cands[i].score > cands[j].score
+			}
+			return cands[i].p.DateLastMatched.After(cands[j].p.DateLastMatched)
+		})
+		for _, c := range cands {
+			ex, err := c.p.PatternExamples().All(ctx, db)
+			if err != nil {
+				logger.DatabaseSelectFailed("examples", c.p.ID, err.Error())
+				continue
+			}
+			var msgs []string
+			for _, e := range ex {
+				msgs = append(msgs, e.ExampleDetail)
+			}
+			if !matchesExamples(np.SequencePattern, np.TagPositions.String, msgs) {
+				continue
+			}
+			link := PatternLink{PatternId: np.ID, SupersedesId: c.p.ID, Service: services[np.ServiceID], Score: c.score, DateCreated: time.Now()}
+			if err = addPatternLink(ctx, db, link); err != nil {
+				return links, err
+			}
+			links = append(links, link)
+			linked[np.ID] = true
+			linked[c.p.ID] = true
+			break
+		}
+	}
+	return links, nil
+}
+
+func addPatternLink(ctx context.Context, db *sql.DB, link PatternLink) error {
+	_, err := db.ExecContext(ctx, rebind(`INSERT INTO "PatternLineage" (pattern_id, supersedes_id, score, migrated, date_created) VALUES (?, ?, ?, ?, ?)`),
+		link.PatternId, link.SupersedesId, link.Score, false, link.DateCreated)
+	if err != nil {
+		logger.DatabaseInsertFailed("pattern lineage", link.PatternId, err.Error())
+	}
+	return err
+}
+
+//Returns the links between the patterns and the patterns they supersede.
+func GetPatternLineage() ([]PatternLink, error) {
+	db, ctx := OpenDbandSetContext()
+	defer db.Close()
+	return getPatternLinks(db, ctx)
+}
+
+func getPatternLinks(db *sql.DB, ctx context.Context) ([]PatternLink, error) {
+	rows, err := db.QueryContext(ctx, rebind(`SELECT l.pattern_id, l.supersedes_id, s.name, l.score, l.migrated, l.date_created
+		FROM "PatternLineage" l INNER JOIN "Patterns" p ON p.id = l.pattern_id INNER JOIN "Services" s ON s.id = p.service_id
+		ORDER BY s.name, l.date_created`))
+	if err != nil {
+		logger.DatabaseSelectFailed("pattern lineage", "All", err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+	var links []PatternLink
+	for rows.Next() {
+		var l PatternLink
+		if err = rows.Scan(&l.PatternId, &l.SupersedesId, &l.Service, &l.Score, &l.Migrated, &l.DateCreated); err != nil {
+			logger.DatabaseSelectFailed("pattern lineage", "All", err.Error())
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+//Moves the review state, the field names and the cumulative match count of the old
+//pattern to the pattern that supersedes it. The review state is only copied if the
+//new pattern has not been reviewed and the old one is in review or approved, and an
+//approved old pattern is deprecated. The
+//field names are copied to the tags that line up with the old ones, unless the new
+//pattern already has a name for the tag. Each link is only migrated once.
+func MigrateLineage(patternid string, supersedesid string, reviewer string) error {
+	db, ctx := OpenDbandSetContext()
+	defer db.Close()
+	var migrated bool
+	err := db.QueryRowContext(ctx, rebind(`SELECT migrated FROM "PatternLineage" WHERE pattern_id = ? AND supersedes_id = ?`), patternid, supersedesid).Scan(&migrated)
+	if err != nil {
+		logger.DatabaseSelectFailed("pattern lineage", patternid, err.Error())
+		return err
+	}
+	if migrated {
+		return fmt.Errorf("The link from %s to %s has already been migrated", patternid, supersedesid)
+	}
+	np, err := models.FindPattern(ctx, db, patternid)
+	if err != nil {
+		logger.DatabaseSelectFailed("pattern", patternid, err.Error())
+		return err
+	}
+	op, err := models.FindPattern(ctx, db, supersedesid)
+	if err != nil {
+		logger.DatabaseSelectFailed("pattern", supersedesid, err.Error())
+		return err
+	}
+	states, err := GetPatternReviewStates(db, ctx)
+	if err != nil {
+		return err
+	}
+	fnames := getPatternFieldNames(db, ctx)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = migrateLink(ctx, tx, np, op, states, fnames, reviewer); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+//Returns true if the review state of the old pattern is copied to the new pattern, only
+//a pattern in review or approved hands its state on to a new pattern that was not reviewed.
+//The ignored and deprecated states were decided for the old pattern and are not carried over.
+func carriesReviewState(newState string, oldState string) bool {
+	return newState == ReviewStateNew && (oldState == ReviewStateReview || oldState == ReviewStateApproved)
+}
+
+func migrateLink(ctx context.Context, tx *sql.Tx, np *models.Pattern, op *models.Pattern, states map[string]string, fnames map[string]map[int]string, reviewer string) error {
+	//review state
+	oldState := states[op.ID]
+	if carriesReviewState(states[np.ID], oldState) {
+		if _, err := reviewPattern(ctx, tx, np.ID, oldState, reviewer, "migrated from "+op.ID); err != nil {
+			return err
+		}
+	}
+	if oldState == ReviewStateApproved {
+		if _, err := reviewPattern(ctx, tx, op.ID, ReviewStateDeprecated, reviewer, "superseded by "+np.ID); err != nil {
+			return err
+		}
+	}
+
+	//field names
+	pmap := alignTagPositions(op.SequencePattern, op.TagPositions.String, np.SequencePattern, np.TagPositions.String)
+	for pos, name := range fnames[op.ID] {
+		npos, ok := pmap[pos]
+		if !ok {
+			continue
+		}
+		if _, ok = fnames[np.ID][npos]; ok {
+			continue
+		}
+		if err := setPatternFieldName(ctx, tx, np.ID, npos, name); err != nil {
+			return err
+		}
+	}
+
+	//match counts
+	np.CumulativeMatchCount += op.CumulativeMatchCount
+	if _, err := np.Update(ctx, tx, boil.Whitelist(models.PatternColumns.CumulativeMatchCount)); err != nil {
+		logger.DatabaseUpdateFailed("pattern", np.ID, err.Error())
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, rebind(`UPDATE "PatternLineage" SET migrated = ? WHERE pattern_id = ? AND supersedes_id = ?`), true, np.ID, op.ID)
+	if err != nil {
+		logger.DatabaseUpdateFailed("pattern lineage", np.ID, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+package sequence
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlignTokens(t *testing.T) {
+	tests := []struct {
+		a, b  []string
+		pairs [][2]int
+		score float64
+	}{
+		{[]string{"user", "%srcuser%", "logged", "in"}, []string{"user", "%srcuser%", "logged", "in"}, [][2]int{{0, 0}, {1, 1}, {2, 2}, {3, 3}}, 1},
+		{[]string{"user", "%srcuser%", "logged", "in"}, []string{"user", "%srcuser%", "signed", "in"}, [][2]int{{0, 0}, {1, 1}, {3, 3}}, 0.75},
+		{[]string{"a", "b"}, []string{"c", "a", "x", "b"}, [][2]int{{0, 1}, {1, 3}}, 4.0 / 6.0},
+		{[]string{"a"}, []string{"b"}, nil, 0},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.pairs, alignTokens(tc.a, tc.b), "%v %v", tc.a, tc.b)
+		require.InDelta(t, tc.score, alignmentScore(tc.a, tc.b), 0.0001, "%v %v", tc.a, tc.b)
+	}
+}
+
+func TestAlignTagPositions(t *testing.T) {
+	//a literal was added before the address, the user and the address still line up
+	pmap := alignTagPositions("user %srcuser% logged in from %srcip%", "5,30",
+		"user %srcuser% logged in remotely from %srcip%", "5,39")
+	require.Equal(t, map[int]int{5: 5, 30: 39}, pmap)
+}
+
+func TestMatchesExamples(t *testing.T) {
+	examples := []string{"user root logged in from 10.0.0.1", "user bob logged in from 10.0.0.2"}
+	require.True(t, matchesExamples("user %srcuser% logged in from %srcip%", "5,30", examples))
+	require.True(t, matchesExamples("user %string% logged in from %srcip%", "5,29", examples))
+	require.False(t, matchesExamples("user %srcuser% logged into %srcip%", "5,27", examples))
+	require.False(t, matchesExamples("user %srcuser% logged in from %srcip%", "5,30", nil))
+}
+
+func TestCarriesReviewState(t *testing.T) {
+	tests := []struct {
+		newState, oldState string
+		carried            bool
+	}{
+		{ReviewStateNew, ReviewStateReview, true},
+		{ReviewStateNew, ReviewStateApproved, true},
+		{ReviewStateNew, ReviewStateNew, false},
+		{ReviewStateNew, ReviewStateIgnored, false},
+		{ReviewStateNew, ReviewStateDeprecated, false},
+		{ReviewStateReview, ReviewStateApproved, false},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.carried, carriesReviewState(tc.newState, tc.oldState), "%s %s", tc.newState, tc.oldState)
+	}
+}
@@ -0,0 +1,323 @@
+// This package is solely for the transformation and the output to file of sequence patterns found in server logs
+// for use with liblognorm, the normalizer used by rsyslog's mmnormalize module. The patterns are written as a
+// version 2 rulebase, one rule per pattern, tagged with the service and pattern id so the matched rule can be
+// traced back to the sequence database.
+// As with the other exporters, the transformation is designed to assist a system administrator to create the
+// rulebase, not to be a full automation of the process, the rules need to be reviewed before use in production.
+package lognorm_rulebase
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+)
+
+var (
+	ruletags string
+	tags     struct {
+		general map[string]string
+		delstr  map[string]string
+		cfield  map[string]string
+	}
+	logger *sequence.StandardLogger
+
+	//matches the sequence tags, eg %srcip%, %regextime:3% or %string::+%
+	tagRegex = regexp.MustCompile(`%([a-zA-Z0-9_]+)(:[^%\s]*)?%`)
+	//liblognorm tags can only contain these characters
+	ruleTagRegex = regexp.MustCompile(`[^a-zA-Z0-9_.\-]`)
+
+	//the liblognorm field types for the time layouts in [timesettings.formats] that have one,
+	//a time with any other layout cannot be written as a rule
+	timeLayoutTypes = map[string]string{
+		"Jan _2 15:04:05":                     "date-rfc3164",
+		"Jan 02 15:04:05":                     "date-rfc3164",
+		"2006-01-02T15:04:05Z07:00":           "date-rfc5424",
+		"2006-01-02T15:04:05.999999999Z07:00": "date-rfc5424",
+		"2006-01-02T15:04:05.999999Z":         "date-rfc5424",
+		"2006-01-02T15:04:05Z":                "date-rfc5424",
+		"2006-01-02":                          "date-iso",
+		"15:04:05":                            "time-24hr",
+		"3:04:05":                             "time-12hr",
+	}
+)
+
+// Allows the user to set the logger to a global instance.
+func SetLogger(log *sequence.StandardLogger) {
+	logger = log
+}
+
+func readConfig(file string) error {
+	var configInfo struct {
+		Lognorm struct {
+			RuleTags string
+			Tags     struct {
+				General         map[string]string
+				DelimitedString map[string]string
+				Fieldname       map[string]string
+			}
+		}
+	}
+	if _, err := toml.DecodeFile(file, &configInfo); err != nil {
+		return err
+	}
+
+	ruletags = configInfo.Lognorm.RuleTags
+	tags.general = configInfo.Lognorm.Tags.General
+	tags.delstr = configInfo.Lognorm.Tags.DelimitedString
+	tags.cfield = configInfo.Lognorm.Tags.Fieldname
+
+	return nil
+}
+
+// This function takes patterns created by the sequence module and outputs them as a liblognorm v2 rulebase.
+// The user can pass the pattern map if no database is used or pass the map created during the analysis.
+func OutputToFiles(outfile string, config string, complexitylevel float64, cmap map[string]sequence.AnalyzerResult, thresholdType string, thresholdValue string) (int, string, error) {
+	var (
+		err    error
+		count  int
+		top5   string
+		patmap map[string]sequence.AnalyzerResult
+	)
+
+	if config == "" {
+		config = "./sequence.toml"
+	}
+	//read the config to load the tags
+	if err = readConfig(config); err != nil {
+		return count, top5, err
+	}
+	if sequence.GetUseDatabase() && cmap == nil {
+		db, ctx := sequence.OpenDbandSetContext()
+		defer db.Close()
+		//get from the config instead
+		if thresholdType == "" {
+			thresholdType = sequence.GetThresholdType()
+			thresholdValue = sequence.GetThresholdValue()
+		}
+		patmap, top5 = sequence.GetPatternsWithExamplesFromDatabase(db, ctx, complexitylevel, thresholdType, thresholdValue)
+	} else {
+		patmap = cmap
+	}
+	logger.HandleInfo(fmt.Sprintf("Found %d patterns for output", len(patmap)))
+	count = len(patmap)
+	//open the file for the rulebase output
+	rbFile, err := sequence.OpenOutputFile(outfile)
+	if err != nil {
+		return count, top5, err
+	}
+	defer rbFile.Close()
+	fmt.Fprintf(rbFile, "version=2\n\n")
+
+	//sort by service and pattern so the rulebase does not change between exports
+	var results []sequence.AnalyzerResult
+	for _, result := range patmap {
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Service.Name != results[j].Service.Name {
+			return results[i].Service.Name < results[j].Service.Name
+		}
+		return results[i].Pattern < results[j].Pattern
+	})
+	for _, result := range results {
+		rule, err := replaceTags(result.Pattern, getTimeLayouts(result))
+		if err != nil {
+			logger.HandleError(fmt.Sprintf("Unable to export pattern %s: %s", result.PatternId, err.Error()))
+			continue
+		}
+		fmt.Fprintf(rbFile, "# %s\n# %d log messages matched\n", result.PatternId, result.ExampleCount)
+		if len(result.Examples) > 0 {
+			fmt.Fprintf(rbFile, "# %s\n", strings.Replace(result.Examples[0].Message, "\n", " ", -1))
+		}
+		fmt.Fprintf(rbFile, "rule=%s:%s\n\n", getRuleTags(result), rule)
+	}
+	return count, top5, nil
+}
+
+// Returns the layouts of the times in the first example of the pattern in the order
+// they are in the message, the time tags of the pattern are matched to them in order.
+func getTimeLayouts(result sequence.AnalyzerResult) []string {
+	var layouts []string
+	if len(result.Examples) == 0 {
+		return layouts
+	}
+	seq, _, err := sequence.ScanMessage(sequence.NewScanner(), result.Examples[0].Message, "")
+	if err != nil {
+		return layouts
+	}
+	for _, tok := range seq {
+		if tok.Type == sequence.TokenTime {
+			layouts = append(layouts, tok.Layout)
+		}
+	}
+	return layouts
+}
+
+// Returns the tags for the rule from the ruletags config value, [service] and
+// [patternid] are replaced with the values for the pattern.
+func getRuleTags(result sequence.AnalyzerResult) string {
+	var rt []string
+	for _, t := range strings.Split(ruletags, ",") {
+		t = strings.TrimSpace(t)
+		t = strings.Replace(t, "[service]", result.Service.Name, -1)
+		t = strings.Replace(t, "[patternid]", result.PatternId, -1)
+		t = ruleTagRegex.ReplaceAllString(t, "_")
+		if t != "" {
+			rt = append(rt, t)
+		}
+	}
+	return strings.Join(rt, ",")
+}
+
+// This replaces the sequence tags with the liblognorm field definitions, the
+// literal text in between is kept as it is, with any % escaped as %%. The time tags
+// are given the field type of the time layouts, in order, and a tag that liblognorm
+// has no field type for returns an error.
+func replaceTags(pattern string, layouts []string) (string, error) {
+	var (
+		result strings.Builder
+		last   = 0
+		times  = 0
+	)
+	mtc := make(map[string]int)
+	for _, m := range tagRegex.FindAllStringSubmatchIndex(pattern, -1) {
+		start, end := m[0], m[1]
+		if start < last {
+			//the opening % was used by the previous tag
+			continue
+		}
+		name := pattern[m[2]:m[3]]
+		val, ok := tags.general["%"+name+"%"]
+		if !ok {
+			//not a tag, this is handled as literal text
+			continue
+		}
+		if isTimeTag(name) {
+			layout := ""
+			if times < len(layouts) {
+				layout = layouts[times]
+			}
+			times++
+			if strings.Contains(val, "[time]") {
+				ttype, ok := timeLayoutTypes[layout]
+				if !ok {
+					return "", fmt.Errorf("the time layout %q of the tag %s has no liblognorm field type", layout, pattern[start:end])
+				}
+				val = strings.Replace(val, "[time]", ttype, 1)
+			}
+		}
+		meta := ""
+		if m[4] >= 0 {
+			meta = pattern[m[4]:m[5]]
+		}
+		flag, until := tagMeta(meta)
+		switch {
+		case until != "":
+			//the field reads up to the literal that follows it
+			val = "%[fieldname]:string-to:" + until + "%"
+		case flag == "-":
+			val = "%[fieldname]:rest%"
+		case flag == "+" || flag == "*":
+			return "", fmt.Errorf("the tag %s repeats the field, which liblognorm has no field type for", pattern[start:end])
+		}
+		//the surrounding characters decide how string fields are delimited
+		before, after := "", ""
+		if start > last {
+			before = pattern[start-1 : start]
+		}
+		if end < len(pattern) {
+			after = pattern[end : end+1]
+		}
+		del := ""
+		if isStringField(val) {
+			switch {
+			case before == after && (before == "\"" || before == "'" || before == "`"),
+				before == "(" && after == ")", before == "[" && after == "]", before == "<" && after == ">":
+				del = before + after
+			case after != "" && after != " " && after != "%":
+				del = after
+			}
+		}
+		if len(del) == 2 {
+			if dval, ok := tags.delstr[del]; ok {
+				//the delimiters are part of the field definition
+				start--
+				end++
+				val = dval
+			} else {
+				del = after
+			}
+		} else if del != "" {
+			if dval, ok := tags.delstr[del]; ok {
+				val = dval
+			} else if dval, ok := tags.delstr["default"]; ok {
+				val = strings.Replace(dval, "[del]", del, 1)
+			}
+		}
+		result.WriteString(escapeLiteral(pattern[last:start]))
+		result.WriteString(getUpdatedTag(name, mtc, val))
+		last = end
+	}
+	result.WriteString(escapeLiteral(pattern[last:]))
+	return result.String(), nil
+}
+
+func isTimeTag(name string) bool {
+	return name == "regextime" || name == "msgtime" || name == "time"
+}
+
+// Returns the meta character of the tag, - for the rest of the message, + for one or
+// more and * for zero or more of the field, and the literal the field reads up to for
+// the %tag:-:until% form. The meta part starts with the colon, eg :-, ::+ or :integer:*.
+func tagMeta(meta string) (string, string) {
+	if meta == "" {
+		return "", ""
+	}
+	parts := strings.Split(meta[1:], ":")
+	if len(parts) == 2 && parts[0] == "-" {
+		return "-", parts[1]
+	}
+	switch last := parts[len(parts)-1]; last {
+	case "-", "+", "*":
+		return last, ""
+	}
+	return "", ""
+}
+
+// Only the fields that match a single word can be changed to read up to a delimiter.
+func isStringField(val string) bool {
+	return strings.HasSuffix(val, ":word%")
+}
+
+// liblognorm uses % for the fields, so a literal % has to be doubled.
+func escapeLiteral(s string) string {
+	return strings.Replace(s, "%", "%%", -1)
+}
+
+// Replaces [fieldname] in the field definition, when the same name is used more
+// than once in a pattern it is numbered, eg string, string1.
+func getUpdatedTag(tok string, mtc map[string]int, tag string) string {
+	//replace any field names that have a custom value in the config
+	tok = checkForCustomFieldName(tok)
+	fieldname := tok
+	//check if there is more than one in the pattern and number
+	if t, ok := mtc[tok]; ok {
+		fieldname = fieldname + strconv.Itoa(t)
+		mtc[tok] = t + 1
+	} else {
+		mtc[tok] = 1
+	}
+	return strings.Replace(tag, "[fieldname]", fieldname, 1)
+}
+
+func checkForCustomFieldName(f string) string {
+	if val, ok := tags.cfield[f]; ok {
+		return val
+	}
+	return f
+}
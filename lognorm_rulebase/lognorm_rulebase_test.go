@@ -0,0 +1,95 @@
+package lognorm_rulebase
+
+import (
+	"testing"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	tagtests = []struct {
+		data   string
+		result string
+	}{
+		{"%object% ", "%object:word% "},
+		{"%object%,", "%object:char-to:,%,"},
+		{"%object%:", "%object:char-to::%:"},
+		{"%object%:%string% ", "%object:char-to::%:%string:word% "},
+		{"%srcip%,", "%srcip:ipv4%,"},
+		{"%srcip%", "%srcip:ipv4%"},
+		{"%ipv6%:", "%ipv6:ipv6%:"},
+		{"%integer% ", "%integer:number% "},
+		{"%string%,%string%", "%string:char-to:,%,%string1:word%"},
+		{"%srcmac%", "%srcmac:mac48%"},
+		{"%float%", "%decimal:float%"},
+		{"<%string%>,", "<%string:char-to:>%>,"},
+		{"\"%object%\"", "%object:quoted-string%"},
+		{"(%object%)", "(%object:char-to:)%)"},
+		{"usage at 100% for %string%", "usage at 100%% for %string:word%"},
+		{"%notatag% %multiline%", "%%notatag%% %multiline:rest%"},
+		{"%string:-%", "%string:rest%"},
+		{"%srcuser::-% done", "%srcuser:rest% done"},
+		{"%object:-:;%; ok", "%object:string-to:;%; ok"},
+	}
+
+	timetests = []struct {
+		data    string
+		layouts []string
+		result  string
+	}{
+		{"%msgtime% %regextime:1%", []string{"2006-01-02T15:04:05Z07:00", "Jan _2 15:04:05"}, "%timestamp:date-rfc5424% %timestamp1:date-rfc3164%"},
+		{"at %time%", []string{"15:04:05"}, "at %time:time-24hr%"},
+		{"on %msgtime%", []string{"2006-01-02"}, "on %timestamp:date-iso%"},
+	}
+)
+
+func loadConfigs() {
+	file := "../sequence.toml"
+	readConfig(file)
+	sequence.ReadConfig(file)
+}
+
+func TestTagTransformation(t *testing.T) {
+	loadConfigs()
+	for _, tc := range tagtests {
+		tag, err := replaceTags(tc.data, nil)
+		require.NoError(t, err, tc.data)
+		require.Equal(t, tc.result, tag, tc.data)
+	}
+}
+
+func TestTimeTags(t *testing.T) {
+	loadConfigs()
+	for _, tc := range timetests {
+		tag, err := replaceTags(tc.data, tc.layouts)
+		require.NoError(t, err, tc.data)
+		require.Equal(t, tc.result, tag, tc.data)
+	}
+
+	//a layout liblognorm cannot parse, or a time with no example, is not exported
+	_, err := replaceTags("%regextime:3% done", []string{"2006-01-02 15:04:05"})
+	require.Error(t, err)
+	_, err = replaceTags("%msgtime% done", nil)
+	require.Error(t, err)
+
+	ar := sequence.AnalyzerResult{Pattern: "%msgtime% %string% done", Examples: []sequence.LogRecord{{Message: "2023-04-05T10:11:12Z job done"}}}
+	rule, err := replaceTags(ar.Pattern, getTimeLayouts(ar))
+	require.NoError(t, err)
+	require.Equal(t, "%timestamp:date-rfc5424% %string:word% done", rule)
+}
+
+func TestRepeatedTags(t *testing.T) {
+	loadConfigs()
+	for _, p := range []string{"users %string::+%", "ids %integer:*%"} {
+		_, err := replaceTags(p, nil)
+		require.Error(t, err, p)
+	}
+}
+
+func TestRuleTags(t *testing.T) {
+	loadConfigs()
+	ar := sequence.AnalyzerResult{PatternId: "abc123"}
+	ar.Service.Name = "my service:1"
+	require.Equal(t, "sequence,my_service_1,abc123", getRuleTags(ar))
+}
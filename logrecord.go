@@ -76,7 +76,6 @@ func ReadLogRecord(fname string, format string, lr []LogRecord, batchLimit int)
 //See Examples folder for example files.
 //Returns a map.
 func ReadLogRecordAsMap(iscan *bufio.Scanner, format string, smap map[string]LogRecordCollection, batchLimit int) (int, map[string]LogRecordCollection, bool) {
-	var lr LogRecordCollection
 	var count = 0
 	var exit = false
 	var r LogRecord
@@ -118,15 +117,7 @@ func ReadLogRecordAsMap(iscan *bufio.Scanner, format string, smap map[string]Log
 		if len(strings.TrimSpace(r.Message)) == 0 {
 			continue
 		}
-		//look for the service in the map
-		if val, ok := smap[r.Service]; ok {
-			val.Records = append(val.Records, r)
-			smap[r.Service] = val
-		} else {
-			lr = LogRecordCollection{Service: r.Service}
-			lr.Records = append(lr.Records, r)
-			smap[r.Service] = lr
-		}
+		AddLogRecordToMap(smap, r)
 		count++
 		if batchLimit != 0 && count >= batchLimit {
 			break
@@ -134,3 +125,17 @@ func ReadLogRecordAsMap(iscan *bufio.Scanner, format string, smap map[string]Log
 	}
 	return count, smap, exit
 }
+
+//Adds the record to the collection for its service, creating the collection
+//if this is the first record seen for the service.
+func AddLogRecordToMap(smap map[string]LogRecordCollection, r LogRecord) {
+	//look for the service in the map
+	if val, ok := smap[r.Service]; ok {
+		val.Records = append(val.Records, r)
+		smap[r.Service] = val
+	} else {
+		lr := LogRecordCollection{Service: r.Service}
+		lr.Records = append(lr.Records, r)
+		smap[r.Service] = lr
+	}
+}
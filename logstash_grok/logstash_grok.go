@@ -9,6 +9,7 @@ package logstash_grok
 import (
 	"fmt"
 	"index/suffixarray"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -17,6 +18,14 @@ import (
 	"github.com/ryanfaircloth/sequence-RTG/sequence"
 )
 
+// The prefix of the pattern definitions for the token types from the [tokentypes] section of the config.
+const customTypePrefix = "SEQUENCE_TYPE_"
+
+var (
+	customTypeRefRegex = regexp.MustCompile(`%\{` + customTypePrefix + `([A-Z0-9_]+)[:}]`)
+	patternRefRegex    = regexp.MustCompile(`%\{([A-Z0-9_]+)[:}]`)
+)
+
 var (
 	tags struct {
 		general map[string]string
@@ -24,6 +33,10 @@ var (
 		cfield  map[string]string
 	}
 	logger *sequence.StandardLogger
+
+	// The patterns from the [grok.definitions] section of the config, for the tags that
+	// have no standard grok pattern.
+	definitions map[string]string
 )
 
 func SetLogger(log *sequence.StandardLogger) {
@@ -38,6 +51,7 @@ func readConfig(file string) error {
 				DelimitedString map[string]string
 				Fieldname       map[string]string
 			}
+			Definitions map[string]string
 		}
 	}
 
@@ -48,10 +62,17 @@ func readConfig(file string) error {
 	tags.general = configInfo.Grok.Tags.General
 	tags.delstr = configInfo.Grok.Tags.DelimitedString
 	tags.cfield = configInfo.Grok.Tags.Fieldname
+	definitions = configInfo.Grok.Definitions
 
 	return nil
 }
 
+// Reads the [grok] section of the config file, this needs to be called before ReplaceTags
+// when the tags are used outside of OutputToFiles.
+func ReadConfig(file string) error {
+	return readConfig(file)
+}
+
 // This function takes patterns created by the sequence module and outputs them in a grok format.
 // This translation is only lighly tested and as with any translation is not always perfect.
 // Transformed patterns need to be reviewed before use in production.
@@ -96,24 +117,42 @@ func OutputToFiles(outfile string, config string, complexitylevel float64, cmap
 	//match => { "message" => "Duration: %{NUMBER:duration}", "Speed: %{NUMBER:speed}" }
 	//add_tag => [ "id_value", "pattern_id" ]
 	for _, result := range patmap {
-		fmt.Fprintf(txtFile, "\tgrok {\n \t\tmatch => {\"message\" => \"%s\"}\n\t\tadd_tag => [\"%s\", \"pattern_id\"]\n\t}\n", replaceTags(result.Pattern), result.PatternId)
+		gp := replaceTags(result.Pattern, result.FieldNamesByIndex())
+		fmt.Fprintf(txtFile, "\tgrok {\n \t\tmatch => {\"message\" => \"%s\"}\n", gp)
+		//the token types from the config are matched with their own regex
+		if defs := PatternDefinitions(gp); len(defs) > 0 {
+			var names []string
+			for name := range defs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			fmt.Fprintf(txtFile, "\t\tpattern_definitions => {")
+			for _, name := range names {
+				fmt.Fprintf(txtFile, " \"%s\" => \"%s\"", name, strings.Replace(defs[name], "\"", "\\\"", -1))
+			}
+			fmt.Fprintf(txtFile, " }\n")
+		}
+		fmt.Fprintf(txtFile, "\t\tadd_tag => [\"%s\", \"pattern_id\"]\n\t}\n", result.PatternId)
 	}
 	fmt.Fprintf(txtFile, "}\n")
-	return 0, top5, nil
+	return count, top5, nil
 }
 
 // This replaces the sequence tags with the grok formatted tags
-func replaceTags(pattern string) string {
+func replaceTags(pattern string, names map[int]string) string {
 	//make sure " are escaped \" before we start
 	//pattern = strings.Replace(pattern, "\"", "\\\"", -1)
 	s := strings.Fields(pattern)
 	var new []string
 	mtc := make(map[string]int)
+	//the index of the next tag in the pattern, for the field name overrides
+	idx := 0
 	for _, p := range s {
-		if val, ok := tags.general[p]; ok {
-			p, mtc = getUpdatedTag(p, mtc, val, "")
+		if val, ok := generalTag(p); ok {
+			p, mtc = getUpdatedTag(p, mtc, val, "", names[idx])
+			idx++
 		} else {
-			p, mtc = getSpecial(p, mtc)
+			p, mtc, idx = getSpecial(p, mtc, names, idx)
 		}
 		//reconstruct
 		new = append(new, p)
@@ -131,7 +170,15 @@ func replaceTags(pattern string) string {
 	return output
 }
 
-func getUpdatedTag(p string, mtc map[string]int, tag string, del string) (string, map[string]int) {
+// This replaces the sequence tags with the grok formatted tags for use outside of a
+// Logstash config file, the double quotes are not escaped.
+func ReplaceTags(pattern string) string {
+	return strings.Replace(replaceTags(pattern, nil), "\\\"", "\"", -1)
+}
+
+// The name is the field name set for the tag in the database, it replaces the
+// generated field name, which is still counted so the other fields keep their numbers.
+func getUpdatedTag(p string, mtc map[string]int, tag string, del string, name string) (string, map[string]int) {
 	tok := ""
 	xchars := len(del)
 	if xchars == 2 {
@@ -148,14 +195,60 @@ func getUpdatedTag(p string, mtc map[string]int, tag string, del string) (string
 	if t, ok := mtc[tok]; ok {
 		fieldname = fieldname + strconv.Itoa(t)
 		mtc[tok] = t + 1
-		p = strings.Replace(tag, "[fieldname]", fieldname, 1)
 	} else {
 		mtc[tok] = 1
-		p = strings.Replace(tag, "[fieldname]", fieldname, 1)
 	}
+	if name != "" {
+		fieldname = name
+	}
+	p = strings.Replace(tag, "[fieldname]", fieldname, 1)
 	return p, mtc
 }
 
+// Returns the grok tag for a sequence tag, from the config or, for a token type from the [tokentypes]
+// section of the config or a tag of that type, a reference to a pattern definition with its regex.
+func generalTag(p string) (string, bool) {
+	if val, ok := tags.general[p]; ok {
+		return val, true
+	}
+	if name := strings.Trim(p, "%"); isCustomType(name) {
+		return "%{" + customTypePrefix + strings.ToUpper(name) + ":[fieldname]}", true
+	}
+	return "", false
+}
+
+func isCustomType(name string) bool {
+	_, ok := sequence.CustomTokenTypeRegex(name)
+	return ok
+}
+
+// Returns the pattern definitions for the token types from the [tokentypes] section of the
+// config and the patterns from the [grok.definitions] section used in the grok pattern, by
+// the name the pattern refers to. A definition can refer to another definition.
+func PatternDefinitions(grokPattern string) map[string]string {
+	var defs map[string]string
+	add := func(name, rg string) {
+		if defs == nil {
+			defs = make(map[string]string)
+		}
+		defs[name] = rg
+	}
+	for _, m := range customTypeRefRegex.FindAllStringSubmatch(grokPattern, -1) {
+		if rg, ok := sequence.CustomTokenTypeRegex(strings.ToLower(m[1])); ok {
+			add(customTypePrefix+m[1], rg)
+		}
+	}
+	for refs := []string{grokPattern}; len(refs) > 0; refs = refs[1:] {
+		for _, m := range patternRefRegex.FindAllStringSubmatch(refs[0], -1) {
+			if rg, ok := definitions[m[1]]; ok && defs[m[1]] == "" {
+				add(m[1], rg)
+				refs = append(refs, rg)
+			}
+		}
+	}
+	return defs
+}
+
 func checkForCustomFieldName(f string) string {
 	if val, ok := tags.cfield[f]; ok {
 		return val
@@ -163,7 +256,7 @@ func checkForCustomFieldName(f string) string {
 	return f
 }
 
-func getSpecial(p string, mtc map[string]int) (string, map[string]int) {
+func getSpecial(p string, mtc map[string]int, names map[int]string, idx int) (string, map[string]int, int) {
 	var (
 		last              = -1
 		fieldname, del, s string
@@ -181,32 +274,42 @@ func getSpecial(p string, mtc map[string]int) (string, map[string]int) {
 		if i%2 == 0 && i < len(offsets)-1 {
 			s, del, fieldname, last = getWithDelimiters(p, off, offsets[i+1]+1, last)
 			fieldname = checkForCustomFieldName(fieldname)
+			//only the tags are counted, a literal can have a pair of % too
+			tagged := false
 			//TODO: deal with regex and time formats
 			if strings.Contains(s, sequence.TagRegExTime.String()) {
 				k = getTimeRegex(s)
+				tagged = true
 			}
 			if del != "" {
 				//remove any extra colons and numbers
 				if val, ok := tags.delstr[del]; ok {
-					val, mtc = getUpdatedTag(s, mtc, val, del)
+					val, mtc = getUpdatedTag(s, mtc, val, del, names[idx])
 					k = strings.Replace(k, s, val, 1)
+					tagged = true
 				}
 			} else {
-				if val, ok := tags.general[s]; ok {
-					val, mtc = getUpdatedTag(s, mtc, val, del)
+				if val, ok := generalTag(s); ok {
+					val, mtc = getUpdatedTag(s, mtc, val, del, names[idx])
 					k = strings.Replace(k, s, val, 1)
+					tagged = true
 				}
 			}
+			if tagged {
+				idx++
+			}
 		}
 	}
-	return k, mtc
+	return k, mtc, idx
 }
 
 func getWithDelimiters(p string, start, end int, last int) (string, string, string, int) {
 	fieldname := p[start+1 : end-1]
 	before := ""
 	//integer and ip fields are not considered strings so can bypass this
-	if fieldname == "integer" || fieldname == "srcip" || fieldname == "dstip" || fieldname == "float" || fieldname == "ipv6" {
+	if fieldname == "integer" || fieldname == "srcip" || fieldname == "dstip" || fieldname == "float" || fieldname == "ipv6" ||
+		fieldname == "uuid" || fieldname == "hex" || fieldname == "base64" ||
+		fieldname == "ipv4cidr" || fieldname == "ipv6cidr" || fieldname == "hostport" || isCustomType(fieldname) {
 		return p[start:end], "", fieldname, end - 1
 	}
 	if start > 0 && end < len(p) {
@@ -1,6 +1,8 @@
 package logstash_grok
 
 import (
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	"github.com/ryanfaircloth/sequence-RTG/sequence"
@@ -25,6 +27,11 @@ var (
 		{"%srchost% ", "%{HOSTNAME:srchost}"},
 		{"<%string%>,", "<%{DATA:string}>,"},
 		{"%multiline%", "%{GREEDYDATA:multiline}"},
+		{"%uuid%,", "%{UUID:uuid},"},
+		{"%hex% ", "%{BASE16NUM:hex}"},
+		{"%base64%", "%{NOTSPACE:base64}"},
+		{"%ipv4cidr%,", "%{IPV4CIDR:ipv4cidr},"},
+		{"%hostport% ", "%{IPHOSTPORT:hostport}"},
 	}
 )
 
@@ -37,7 +44,47 @@ func loadConfigs() {
 func TestTagTransformation(t *testing.T) {
 	loadConfigs()
 	for _, tc := range tagtests {
-		tag := replaceTags(tc.data)
+		tag := replaceTags(tc.data, nil)
 		require.Equal(t, tc.result, tag, tc.data)
 	}
 }
+
+func TestTagTransformationWithFieldNames(t *testing.T) {
+	loadConfigs()
+	tests := []struct {
+		data   string
+		names  map[int]string
+		result string
+	}{
+		{"%string% logged in from %srcip%", map[int]string{0: "username"}, "%{DATA:username} logged in from %{IP:srcip}"},
+		{"%string%,%string% %string%", map[int]string{1: "group"}, "%{DATA:string},%{DATA:group} %{DATA:string2}"},
+		//the % of the literal are not a tag
+		{"load 10%-20% by %srcuser%", map[int]string{0: "account"}, "load 10%-20% by %{USER:account}"},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.result, replaceTags(tc.data, tc.names), tc.data)
+	}
+}
+
+func TestCustomTokenTypeTags(t *testing.T) {
+	data, err := ioutil.ReadFile("../sequence.toml")
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "sequence.toml")
+	data = append(data, "[tokentypes.elapsed]\n    regex = '\\d+(?:ms|s)'\n"...)
+	require.NoError(t, ioutil.WriteFile(file, data, 0644))
+	require.NoError(t, sequence.ReadConfig(file))
+	require.NoError(t, readConfig(file))
+	t.Cleanup(loadConfigs)
+
+	gp := replaceTags("took %elapsed%, retry in %elapsed%", nil)
+	require.Equal(t, "took %{SEQUENCE_TYPE_ELAPSED:elapsed}, retry in %{SEQUENCE_TYPE_ELAPSED:elapsed1}", gp)
+	require.Equal(t, map[string]string{"SEQUENCE_TYPE_ELAPSED": `\d+(?:ms|s)`}, PatternDefinitions(gp))
+	require.Nil(t, PatternDefinitions(replaceTags("%srcip%", nil)))
+}
+
+func TestAddressPatternDefinitions(t *testing.T) {
+	loadConfigs()
+	gp := replaceTags("route %ipv4cidr% via %hostport%", nil)
+	require.Equal(t, "route %{IPV4CIDR:ipv4cidr} via %{IPHOSTPORT:hostport}", gp)
+	require.Equal(t, map[string]string{"IPV4CIDR": "%{IPV4}/[0-9]{1,2}", "IPHOSTPORT": `(?:\[%{IPV6}\]|%{IPORHOST}):%{POSINT}`}, PatternDefinitions(gp))
+}
@@ -17,8 +17,13 @@ package sequence
 import (
 	"fmt"
 	"io"
+	"net"
+	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
+
+	"github.com/zhenjl/xparse/etld"
 )
 
 type Message struct {
@@ -198,7 +203,7 @@ func (this *Message) skipSpace(data string) int {
 
 func (this *Message) scanToken(data string, nt int) (int, Token, error) {
 	var (
-		tnode                                  = timeFsmRoot
+		tnode, tfinal                          = timeFsmRoot, timeFsmRoot
 		tokenStop, timeStop, hexStop, hexValid bool
 		timeLen, hexLen, tokenLen              int
 		l                                      = len(data)
@@ -207,6 +212,21 @@ func (this *Message) scanToken(data string, nt int) (int, Token, error) {
 
 	this.resetTokenStates()
 
+	// the token types from the config come first, the hex and time checks would split them
+	if cl, ct := scanCustomToken(data, nt); cl > 0 {
+		return cl, Token{Type: ct, Tag: tagType}, nil
+	}
+
+	// the identifiers come next, the hex check would split a uuid at the dashes
+	if il, it := scanIdentifier(data, nt); il > 0 {
+		return il, Token{Type: it, Tag: tagType}, nil
+	}
+
+	// then the addresses that the ip checks would split at the / : or %
+	if al, at := scanAddress(data, nt); al > 0 {
+		return al, Token{Type: at, Tag: tagType}, nil
+	}
+
 	// short circuit the hex check
 	if l < 3 {
 		hexStop = true
@@ -253,14 +273,16 @@ func (this *Message) scanToken(data string, nt int) (int, Token, error) {
 		if !timeStop {
 			if tnode, timeStop = timeStep(r, tnode); timeStop == true {
 				if timeLen > 0 {
-					if tnode.regextype != "" {
-						tagType = TagRegExTime
+					if tok, ok := timeToken(data[:timeLen], tfinal, tnode.regextype); ok {
+						return timeLen, tok, nil
 					}
-					return timeLen, Token{Type: TokenTime, Tag: tagType, Special: tnode.regextype}, nil
+					// an impossible date is scanned as the other tokens
+					timeLen = 0
 				}
 			} else if tnode.final == TokenTime {
 				if i+1 > timeLen {
 					timeLen = i + 1
+					tfinal = tnode
 				}
 			}
 		}
@@ -269,11 +291,12 @@ func (this *Message) scanToken(data string, nt int) (int, Token, error) {
 		// This means either we found something, or we have exhausted the string
 		if (tokenStop && timeStop && hexStop) || i == l-1 {
 			if timeLen > 0 {
-				if tnode.regextype != "" {
-					tagType = TagRegExTime
+				if tok, ok := timeToken(data[:timeLen], tfinal, tnode.regextype); ok {
+					return timeLen, tok, nil
 				}
-				return timeLen, Token{Type: TokenTime, Tag: tagType, Special: tnode.regextype}, nil
-			} else if hexLen > 0 && this.state.hexColons > 1 {
+				timeLen = 0
+			}
+			if hexLen > 0 && this.state.hexColons > 1 {
 				if this.state.hexColons == 5 && this.state.hexMaxSuccColons == 1 {
 					//if the end of the message there won't be an extra space
 					if i == l-1 {
@@ -664,6 +687,215 @@ func (this *Message) hexStep(i int, r rune, nr string) (bool, bool) {
 	return false, true
 }
 
+// scanIdentifier checks if the data starts with an identifier and returns its length
+// and type, or 0 if it does not. The identifiers are
+// - 550e8400-e29b-41d4-a716-446655440000 (uuid)
+// - 16 or more hex digits with at least one letter and one digit (hex), such as a hash
+// - 20 or more base64 characters with upper and lower case letters and digits and up to
+//   two = for padding (base64), such as a session token. The characters must change
+//   between upper case, lower case and digits often enough that it is not a word, and a
+//   string with a / must be padded to a multiple of 4 or it is more likely to be a path.
+//
+// The identifier must end where a literal would, and not run past the next tag position.
+func scanIdentifier(data string, nt int) (int, TokenType) {
+	var (
+		l, pad, slashes, changes        int
+		hex, upper, lower, digit, other bool
+		class, prev                     byte
+	)
+	for ; l < len(data); l++ {
+		c := data[l]
+		if pad > 0 && c != '=' {
+			break
+		}
+		switch {
+		case c == '=':
+			pad++
+		case c >= '0' && c <= '9':
+			digit, class = true, '9'
+		case c >= 'a' && c <= 'z':
+			hex = hex || c <= 'f'
+			other = other || c > 'f'
+			lower, class = true, 'a'
+		case c >= 'A' && c <= 'Z':
+			hex = hex || c <= 'F'
+			other = other || c > 'F'
+			upper, class = true, 'A'
+		case c == '+' || c == '/':
+			other = true
+			if c == '/' {
+				slashes++
+			}
+		case c == '-':
+			other = true
+		default:
+			goto done
+		}
+		if prev != 0 && class != prev {
+			changes++
+		}
+		prev = class
+	}
+done:
+	if l == 0 || (nt > 0 && l > nt) || pad > 2 {
+		return 0, TokenUnknown
+	}
+	if l < len(data) {
+		r, _ := utf8.DecodeRuneInString(data[l:])
+		if isLiteral(r) && !(r == '.' && (l == len(data)-1 || data[l+1] == ' ')) {
+			return 0, TokenUnknown
+		}
+	}
+	id := data[:l]
+	switch {
+	case pad == 0 && isUUID(id):
+		return l, TokenUUID
+	case pad == 0 && !other && l >= 16 && hex && digit:
+		return l, TokenHex
+	case l >= 20 && upper && lower && digit && changes >= l/4 && id[0] != '/' && !strings.Contains(id, "-") &&
+		(slashes == 0 || (pad > 0 && l%4 == 0)):
+		return l, TokenBase64
+	}
+	return 0, TokenUnknown
+}
+
+// scanAddress checks if the data starts with a network address that the ip checks would
+// split and returns its length and type, or 0 if it does not. The addresses are
+// - 10.0.0.0/8 and 2001:db8::/32 (ipv4cidr and ipv6cidr), the address must be the network
+//   of the prefix, so an address with a port such as 10.1.2.3/22 is still split
+// - fe80::1%eth0 (ipv6), an ipv6 address with a zone
+// - 10.1.2.3:22, [fe80::1%eth0]:443 and db.example.com:5432 (hostport), the host name
+//   must end in a known top level domain and the port can be up to 65535
+//
+// The address must end where a literal would, and not run past the next tag position.
+func scanAddress(data string, nt int) (int, TokenType) {
+	var (
+		l       int
+		bracket = len(data) > 0 && data[0] == '['
+		sep     bool
+	)
+	if bracket {
+		l++
+	}
+	for ; l < len(data); l++ {
+		c := data[l]
+		if c == ':' || c == '/' || c == '%' {
+			sep = true
+		} else if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '.' || c == '-' || c == '_' || (bracket && c == ']')) {
+			break
+		}
+	}
+	if !sep || (nt > 0 && l > nt) {
+		return 0, TokenUnknown
+	}
+	// a full stop that ends the message or a sentence is not part of the address
+	if data[l-1] == '.' && (l == len(data) || data[l] == ' ') {
+		l--
+	}
+	if l < len(data) {
+		r, _ := utf8.DecodeRuneInString(data[l:])
+		if isLiteral(r) && !(r == '.' && (l == len(data)-1 || data[l+1] == ' ')) {
+			return 0, TokenUnknown
+		}
+	}
+	addr := data[:l]
+	if i := strings.LastIndexByte(addr, '/'); i > 0 && !bracket {
+		if tt := cidrType(addr[:i], addr[i+1:]); tt != TokenUnknown {
+			return l, tt
+		}
+		return 0, TokenUnknown
+	}
+	if _, _, ht := splitHostPort(addr); ht != TokenUnknown {
+		return l, TokenHostPort
+	}
+	if i := strings.IndexByte(addr, '%'); i > 0 && !bracket && isIPv6Zone(addr) {
+		return l, TokenIPv6
+	}
+	return 0, TokenUnknown
+}
+
+// cidrType returns the type of the cidr with the address and prefix, or TokenUnknown if the
+// address is not the network of the prefix.
+func cidrType(addr, prefix string) TokenType {
+	if len(prefix) == 0 || len(prefix) > 3 {
+		return TokenUnknown
+	}
+	ones, err := strconv.Atoi(prefix)
+	ip := net.ParseIP(addr)
+	if err != nil || ip == nil {
+		return TokenUnknown
+	}
+	tt, bits := TokenIPv6CIDR, 128
+	if !strings.Contains(addr, ":") {
+		tt, bits, ip = TokenIPv4CIDR, 32, ip.To4()
+	}
+	if ones > bits || !ip.Mask(net.CIDRMask(ones, bits)).Equal(ip) {
+		return TokenUnknown
+	}
+	return tt
+}
+
+// isIPv6Zone checks if the address is an ipv6 address with a zone, such as fe80::1%eth0.
+func isIPv6Zone(addr string) bool {
+	i := strings.IndexByte(addr, '%')
+	if i < 0 || i == len(addr)-1 || !strings.Contains(addr[:i], ":") || net.ParseIP(addr[:i]) == nil {
+		return false
+	}
+	for _, c := range addr[i+1:] {
+		if !(isLetter(c) || isDigit(c) || c == '.' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// splitHostPort splits a host:port address into the host and the port and returns the type
+// of the host, TokenIPv4, TokenIPv6 for an [ipv6] host, which is returned without the
+// brackets, or token__host__, and TokenUnknown if it is not a host:port address.
+func splitHostPort(addr string) (string, string, TokenType) {
+	i := strings.LastIndexByte(addr, ':')
+	if i <= 0 || i > len(addr)-2 || len(addr)-i > 6 {
+		return "", "", TokenUnknown
+	}
+	host, port := addr[:i], addr[i+1:]
+	if n, err := strconv.Atoi(port); err != nil || n > 65535 || port[0] == '+' || port[0] == '-' {
+		return "", "", TokenUnknown
+	}
+	switch {
+	case len(host) > 2 && host[0] == '[' && host[len(host)-1] == ']':
+		host = host[1 : len(host)-1]
+		if (net.ParseIP(host) != nil && strings.Contains(host, ":")) || isIPv6Zone(host) {
+			return host, port, TokenIPv6
+		}
+	case strings.ContainsAny(host, ":[]%"):
+	case net.ParseIP(host) != nil:
+		return host, port, TokenIPv4
+	case strings.Contains(host, ".") && !strings.Contains(host, "..") && isLetter(rune(host[len(host)-1])) && etld.Match(host) > 0:
+		return host, port, token__host__
+	}
+	return "", "", TokenUnknown
+}
+
+// isUUID checks if the string is a uuid in the 8-4-4-4-12 hex digit form.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !isHex(r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func (this *Message) reset() {
 	this.state.prevToken = Token{}
 	this.state.inquote = false
@@ -0,0 +1,331 @@
+package sequence
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//The up-migrations of the database schema, a folder for each dialect with a file for each
+//version named <version>_<name>.sql. The first line of each file is a probe query, it
+//succeeds on a database that already has the change, so a database that was created
+//before the schema_version table can be brought under the migrations without rerunning them.
+//The postgres migrations do not set the owner of the tables, so they run as any user that
+//can create tables and the tables are owned by that user.
+//
+//go:embed database_scripts/migrations
+var migrationFiles embed.FS
+
+//The table that records the migrations applied to the database.
+var createSchemaVersion = map[string]string{
+	"sqlite3":  "CREATE TABLE schema_version (version INTEGER PRIMARY KEY NOT NULL, name STRING (100) NOT NULL, date_applied DATETIME NOT NULL)",
+	"mssql":    "CREATE TABLE [dbo].[schema_version] ([version] [int] NOT NULL PRIMARY KEY, [name] [nvarchar](100) NOT NULL, [date_applied] [datetime] NOT NULL)",
+	"postgres": "CREATE TABLE public.schema_version (version integer NOT NULL PRIMARY KEY, name character varying(100) NOT NULL, date_applied timestamp NOT NULL)",
+	"mysql":    "CREATE TABLE `schema_version` (`version` int(11) NOT NULL, `name` varchar(100) NOT NULL, `date_applied` datetime NOT NULL, PRIMARY KEY (`version`)) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+}
+
+//A version of the database schema.
+type Migration struct {
+	Version    int
+	Name       string
+	probe      string
+	statements []string
+}
+
+//A migration and when it was applied to the database, the date is zero if it has not been applied.
+type MigrationStatus struct {
+	Version     int
+	Name        string
+	Applied     bool
+	DateApplied time.Time
+}
+
+//A database or a single connection to it, the create scripts run on one connection
+//so the migrations are recorded in the database the script switched to.
+type migrationDB interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+//the connections the version of the schema has been checked for, the databases are
+//opened from the workers of analyzebyservice and listen at the same time
+var schemaChecked = struct {
+	sync.Mutex
+	conns map[string]bool
+}{conns: make(map[string]bool)}
+
+func setSchemaChecked(cinfo string) {
+	schemaChecked.Lock()
+	schemaChecked.conns[cinfo] = true
+	schemaChecked.Unlock()
+}
+
+func isSchemaChecked(cinfo string) bool {
+	schemaChecked.Lock()
+	defer schemaChecked.Unlock()
+	return schemaChecked.conns[cinfo]
+}
+
+//Returns the folder of the migrations for the database type in the config.
+func migrationDialect(dbtype string) (string, error) {
+	switch dbtype {
+	case "sqlite3":
+		return "sqlite3", nil
+	case "mssql", "sqlserver":
+		return "mssql", nil
+	case "postgres", "psql":
+		return "postgres", nil
+	case "mysql":
+		return "mysql", nil
+	}
+	return "", fmt.Errorf("There are no migrations for the database type %s", dbtype)
+}
+
+//Splits a migration into statements, the SQL Server scripts are split on the GO lines
+//and the others on the lines that end with a semicolon. Comment lines are dropped.
+func splitStatements(script string, dialect string) []string {
+	var (
+		stmts []string
+		sb    strings.Builder
+	)
+	add := func() {
+		if q := strings.TrimSpace(sb.String()); q != "" {
+			stmts = append(stmts, q)
+		}
+		sb.Reset()
+	}
+	s := bufio.NewScanner(strings.NewReader(script))
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for s.Scan() {
+		line := s.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		if dialect == "mssql" {
+			if strings.EqualFold(trimmed, "GO") {
+				add()
+				continue
+			}
+			sb.WriteString(line + "\n")
+			continue
+		}
+		sb.WriteString(line + "\n")
+		if strings.HasSuffix(trimmed, ";") {
+			add()
+		}
+	}
+	add()
+	return stmts
+}
+
+//Returns the migrations for the dialect in version order.
+func loadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("database_scripts/migrations", dialect)
+	entries, err := migrationFiles.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".sql")
+		parts := strings.SplitN(name, "_", 2)
+		v, err := strconv.Atoi(parts[0])
+		if err != nil || len(parts) != 2 {
+			return nil, fmt.Errorf("The migration %s must be named <version>_<name>.sql", e.Name())
+		}
+		data, err := migrationFiles.ReadFile(path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		script := string(data)
+		m := Migration{Version: v, Name: parts[1]}
+		if first := strings.SplitN(script, "\n", 2)[0]; strings.HasPrefix(first, "-- probe:") {
+			m.probe = strings.TrimSpace(strings.TrimPrefix(first, "-- probe:"))
+		}
+		m.statements = splitStatements(script, dialect)
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("The migrations for %s are missing version %d", dialect, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+//Returns the version of the schema the code needs, the version of the last migration.
+func LatestSchemaVersion() int {
+	dialect, err := migrationDialect(config.databaseType)
+	if err != nil {
+		return 0
+	}
+	migrations, err := loadMigrations(dialect)
+	if err != nil {
+		return 0
+	}
+	return len(migrations)
+}
+
+//Returns the applied migrations by version, the error is returned when the
+//schema_version table does not exist.
+func appliedMigrations(db migrationDB, ctx context.Context) (map[int]time.Time, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version, date_applied FROM schema_version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			v int
+			d time.Time
+		)
+		if err = rows.Scan(&v, &d); err != nil {
+			return nil, err
+		}
+		applied[v] = d
+	}
+	return applied, rows.Err()
+}
+
+//Returns the version of the schema, the highest version applied in order from 1.
+func schemaVersion(applied map[int]time.Time) int {
+	v := 0
+	for {
+		if _, ok := applied[v+1]; !ok {
+			return v
+		}
+		v++
+	}
+}
+
+//Returns the migrations for the database in the config and whether each has been applied.
+func GetMigrationStatus() ([]MigrationStatus, error) {
+	dialect, err := migrationDialect(config.databaseType)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := loadMigrations(dialect)
+	if err != nil {
+		return nil, err
+	}
+	db, ctx := openDb()
+	defer db.Close()
+	applied, err := appliedMigrations(db, ctx)
+	if err != nil {
+		//the database has not been migrated yet
+		applied = map[int]time.Time{}
+	}
+	var status []MigrationStatus
+	for _, m := range migrations {
+		d, ok := applied[m.Version]
+		status = append(status, MigrationStatus{Version: m.Version, Name: m.Name, Applied: ok, DateApplied: d})
+	}
+	return status, nil
+}
+
+//Applies the migrations that have not been applied to the database in the config and
+//returns them. When the database has no schema_version table it is created, and the
+//migrations whose probe succeeds are recorded as applied without being run.
+func MigrateUp() ([]MigrationStatus, error) {
+	dialect, err := migrationDialect(config.databaseType)
+	if err != nil {
+		return nil, err
+	}
+	db, ctx := openDb()
+	defer db.Close()
+	done, err := migrateUp(db, ctx, dialect)
+	if err == nil {
+		setSchemaChecked(config.connectionInfo)
+	}
+	return done, err
+}
+
+func migrateUp(db migrationDB, ctx context.Context, dialect string) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(dialect)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db, ctx)
+	if err != nil {
+		if _, err = db.ExecContext(ctx, createSchemaVersion[dialect]); err != nil {
+			logger.DatabaseInsertFailed("schema_version", "create", err.Error())
+			return nil, err
+		}
+		applied = map[int]time.Time{}
+	}
+	//a database with no migrations recorded may have been created before them
+	adopt := len(applied) == 0
+	var done []MigrationStatus
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		run := true
+		if adopt && m.probe != "" {
+			//the probe runs outside of the transaction, as a failed statement aborts a postgres transaction
+			if rows, err := db.QueryContext(ctx, m.probe); err == nil {
+				rows.Close()
+				run = false
+				logger.HandleInfo(fmt.Sprintf("Migration %d %s is already in the database, recording it as applied.", m.Version, m.Name))
+			}
+		}
+		//once a migration has run, the later ones can not already be in the database
+		if run {
+			adopt = false
+		}
+		now := time.Now().UTC()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return done, err
+		}
+		if run {
+			for _, stmt := range m.statements {
+				if _, err = tx.ExecContext(ctx, stmt); err != nil {
+					tx.Rollback()
+					logger.HandleError(fmt.Sprintf("Migration %d %s failed: %s", m.Version, m.Name, err.Error()))
+					return done, fmt.Errorf("Migration %d %s failed: %s", m.Version, m.Name, err.Error())
+				}
+			}
+		}
+		if _, err = tx.ExecContext(ctx, rebindFor(dialect, "INSERT INTO schema_version (version, name, date_applied) VALUES (?, ?, ?)"), m.Version, m.Name, now); err != nil {
+			tx.Rollback()
+			logger.DatabaseInsertFailed("schema_version", strconv.Itoa(m.Version), err.Error())
+			return done, err
+		}
+		if err = tx.Commit(); err != nil {
+			return done, err
+		}
+		done = append(done, MigrationStatus{Version: m.Version, Name: m.Name, Applied: true, DateApplied: now})
+	}
+	return done, nil
+}
+
+//Returns an error if the schema of the database is older than the migrations, the
+//check is made once for each connection.
+func checkSchemaVersion(db *sql.DB, ctx context.Context) error {
+	if isSchemaChecked(config.connectionInfo) {
+		return nil
+	}
+	latest := LatestSchemaVersion()
+	applied, err := appliedMigrations(db, ctx)
+	if err != nil {
+		return fmt.Errorf("The database has no schema version, run sequence_db migrate up to bring it to version %d", latest)
+	}
+	if v := schemaVersion(applied); v < latest {
+		return fmt.Errorf("The database schema is at version %d and needs to be at version %d, run sequence_db migrate up", v, latest)
+	}
+	setSchemaChecked(config.connectionInfo)
+	return nil
+}
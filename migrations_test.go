@@ -0,0 +1,111 @@
+package sequence
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := "-- probe: SELECT 1\nCREATE TABLE a\n(\n  id int\n);\n\nALTER TABLE a\n    OWNER to postgres;\n"
+	require.Equal(t, []string{"CREATE TABLE a\n(\n  id int\n);", "ALTER TABLE a\n    OWNER to postgres;"}, splitStatements(script, "postgres"))
+
+	script = "-- probe: SELECT 1\nSET ANSI_NULLS ON\nGO\n\nCREATE TABLE [a](\n\t[id] [int]\n)\nGO\n"
+	require.Equal(t, []string{"SET ANSI_NULLS ON", "CREATE TABLE [a](\n\t[id] [int]\n)"}, splitStatements(script, "mssql"))
+}
+
+func TestLoadMigrations(t *testing.T) {
+	var names []string
+	for _, dialect := range []string{"sqlite3", "mssql", "postgres", "mysql"} {
+		migrations, err := loadMigrations(dialect)
+		require.NoError(t, err, dialect)
+		require.NotEmpty(t, migrations, dialect)
+		var n []string
+		for i, m := range migrations {
+			require.Equal(t, i+1, m.Version, dialect)
+			require.NotEmpty(t, m.probe, "%s %s", dialect, m.Name)
+			require.NotEmpty(t, m.statements, "%s %s", dialect, m.Name)
+			n = append(n, m.Name)
+		}
+		//every dialect has the same migrations
+		if names == nil {
+			names = n
+		}
+		require.Equal(t, names, n, dialect)
+	}
+}
+
+func TestMigrateUpSQLite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	SetLogger(NewLogger(filepath.Join(dir, "log.txt"), "error"))
+	ctx := context.Background()
+	migrations, err := loadMigrations("sqlite3")
+	require.NoError(t, err)
+
+	//a new database gets all the migrations
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "new.sdb"))
+	require.NoError(t, err)
+	done, err := migrateUp(db, ctx, "sqlite3")
+	require.NoError(t, err)
+	require.Len(t, done, len(migrations))
+	applied, err := appliedMigrations(db, ctx)
+	require.NoError(t, err)
+	require.Equal(t, len(migrations), schemaVersion(applied))
+	done, err = migrateUp(db, ctx, "sqlite3")
+	require.NoError(t, err)
+	require.Empty(t, done)
+	db.Close()
+
+	//a database created before the migrations keeps its tables and gets the later changes
+	db, err = sql.Open("sqlite3", filepath.Join(dir, "old.sdb"))
+	require.NoError(t, err)
+	defer db.Close()
+	for _, stmt := range migrations[0].statements {
+		_, err = db.Exec(stmt)
+		require.NoError(t, err)
+	}
+	_, err = db.Exec("INSERT INTO Services (id, name, date_created) VALUES ('s1', 'app', '2021-01-01')")
+	require.NoError(t, err)
+	done, err = migrateUp(db, ctx, "sqlite3")
+	require.NoError(t, err)
+	require.Len(t, done, len(migrations))
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM Services").Scan(&count))
+	require.Equal(t, 1, count)
+	_, err = db.Exec("SELECT complexity_score FROM Patterns")
+	require.NoError(t, err)
+}
+
+func TestCheckSchemaVersionConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	SetLogger(NewLogger(filepath.Join(dir, "log.txt"), "error"))
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "new.sdb"))
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = migrateUp(db, ctx, "sqlite3")
+	require.NoError(t, err)
+
+	cinfo := config.connectionInfo
+	t.Cleanup(func() { config.connectionInfo = cinfo })
+	config.connectionInfo = filepath.Join(dir, "new.sdb")
+	//the workers open the database at the same time
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, checkSchemaVersion(db, ctx))
+		}()
+	}
+	wg.Wait()
+	require.True(t, isSchemaChecked(config.connectionInfo))
+}
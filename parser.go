@@ -38,6 +38,9 @@ type Parser struct {
 	root   *parseNode
 	height int
 	mu     sync.RWMutex
+
+	hostports bool // does a pattern have a host:port token?
+	cidrparts bool // does a pattern have an address followed by a "/"?
 }
 
 type parseNode struct {
@@ -61,6 +64,7 @@ type stackParseNode struct {
 	seqidx int    // current index of the sequence being parsed
 	score  int    // the score of the path traversed
 	value  string // value of the token evaluated
+	layout string // layout of the time token evaluated
 }
 
 func (this stackParseNode) String() string {
@@ -94,6 +98,7 @@ func (this *Parser) Add(seq Sequence) error {
 
 	parent := this.root
 	var grandparent *parseNode = nil
+	var prev TokenType
 
 	for _, token := range seq {
 		vl := len(token.Value)
@@ -108,6 +113,15 @@ func (this *Parser) Add(seq Sequence) error {
 
 		//log.Printf("add token=%s", token)
 
+		// The messages are only parsed unsplit when a pattern could match them
+		switch {
+		case token.Type == TokenHostPort:
+			this.hostports = true
+		case token.Type == TokenLiteral && token.Value == "/" && (prev == TokenIPv4 || prev == TokenIPv6):
+			this.cidrparts = true
+		}
+		prev = token.Type
+
 		var found *parseNode
 
 		switch {
@@ -207,6 +221,30 @@ func (this *Parser) Parse(seq Sequence) (Sequence, error) {
 	this.mu.RLock()
 	defer this.mu.RUnlock()
 
+	// The analyzer splits the host:port tokens to tag their parts, so the message is parsed
+	// with them split first. The patterns saved before the cidrs were recognised have their
+	// parts too, and a pattern can still have a host:port token of its own. The other
+	// sequences are only parsed when they differ and a pattern in the tree can match them,
+	// so a message that does not match is usually parsed once.
+	hseq, hsplit := splitAddresses(seq, false)
+	pseq, err := this.parse(hseq)
+	if err == nil {
+		return pseq, nil
+	}
+	if this.cidrparts {
+		if cseq, ok := splitAddresses(hseq, true); ok {
+			if pseq, err := this.parse(cseq); err == nil {
+				return pseq, nil
+			}
+		}
+	}
+	if hsplit && this.hostports {
+		return this.parse(seq)
+	}
+	return nil, err
+}
+
+func (this *Parser) parse(seq Sequence) (Sequence, error) {
 	var (
 		parent stackParseNode
 
@@ -239,6 +277,7 @@ func (this *Parser) Parse(seq Sequence) (Sequence, error) {
 			l := parent.level - 1
 			path[l] = parent.node.Token
 			path[l].Value = parent.value
+			path[l].Layout = parent.layout
 
 			if parent.node.until != "" {
 				i := parent.seqidx
@@ -299,18 +338,29 @@ func (this *Parser) Parse(seq Sequence) (Sequence, error) {
 			// Find any children that's a string token and add them to the stack
 			// if len(token.Value) > 1 || (len(token.Value) == 1 && isLiteral(rune(token.Value[0]))) {
 			for _, n := range parent.node.tc[TokenString] {
-				toVisit = append(toVisit, stackParseNode{n, parent.level + 1, parent.seqidx + 1, parent.score + partialMatchWeight, token.Value})
+				toVisit = append(toVisit, stackParseNode{n, parent.level + 1, parent.seqidx + 1, parent.score + partialMatchWeight, token.Value, ""})
 			}
 			// }
 
 			// If the values match, then it's a full match, add it to the stack
 			if n, ok := parent.node.lc[token.Value]; ok {
-				toVisit = append(toVisit, stackParseNode{n, parent.level + 1, parent.seqidx + 1, parent.score + fullMatchWeight, token.Value})
+				toVisit = append(toVisit, stackParseNode{n, parent.level + 1, parent.seqidx + 1, parent.score + fullMatchWeight, token.Value, ""})
 			}
 
 		default:
 			for _, n := range parent.node.tc[token.Type] {
-				toVisit = append(toVisit, stackParseNode{n, parent.level + 1, parent.seqidx + 1, parent.score + fullMatchWeight, token.Value})
+				toVisit = append(toVisit, stackParseNode{n, parent.level + 1, parent.seqidx + 1, parent.score + fullMatchWeight, token.Value, token.Layout})
+			}
+
+			// A token of a type from the config, or an identifier, also matches like a
+			// literal, so the patterns saved before the type was recognised still match
+			if token.Type.matchesLiteral() {
+				for _, n := range parent.node.tc[TokenString] {
+					toVisit = append(toVisit, stackParseNode{n, parent.level + 1, parent.seqidx + 1, parent.score + partialMatchWeight, token.Value, ""})
+				}
+				if n, ok := parent.node.lc[token.Value]; ok {
+					toVisit = append(toVisit, stackParseNode{n, parent.level + 1, parent.seqidx + 1, parent.score + fullMatchWeight, token.Value, ""})
+				}
 			}
 		}
 	}
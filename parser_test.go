@@ -15,6 +15,8 @@
 package sequence
 
 import (
+	"bufio"
+	"bytes"
 	"github.com/stretchr/testify/require"
 	"testing"
 )
@@ -551,6 +553,147 @@ func TestParserParseMessages(t *testing.T) {
 	}
 }
 
+func TestParserFields(t *testing.T) {
+	parser := NewParser()
+	scanner := NewScanner()
+	var pos []int
+
+	seq, _, err := scanner.Scan("%srcuser% logged in from %srcip% to %string% %string%", true, []int{0, 25, 36, 45})
+	require.NoError(t, err)
+	require.NoError(t, parser.Add(seq))
+
+	seq, _, err = scanner.Scan("root logged in from 10.1.2.3 to web01 console", false, pos)
+	require.NoError(t, err)
+	seq, err = parser.Parse(seq)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"srcuser": "root", "srcip": "10.1.2.3", "string": "web01", "string1": "console"}, seq.Fields())
+}
+
+func TestParserParseIdentifiers(t *testing.T) {
+	scanner := NewScanner()
+	parser := NewParser()
+	seq, _, err := scanner.Scan("request %uuid% digest %hex% token %base64%", true, nil)
+	require.NoError(t, err)
+	require.NoError(t, parser.Add(seq))
+
+	seq, _, err = scanner.Scan("request 550e8400-e29b-41d4-a716-446655440000 digest 9f86d081884c7d659a2feaa0c55ad015 token dGhpcyBpcyBhIHNlc3Npb24gdG9rZW4=", false, nil)
+	require.NoError(t, err)
+	seq, err = parser.Parse(seq)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"uuid": "550e8400-e29b-41d4-a716-446655440000", "hex": "9f86d081884c7d659a2feaa0c55ad015",
+		"base64": "dGhpcyBpcyBhIHNlc3Npb24gdG9rZW4="}, seq.Fields())
+
+	//the uuid is not split by the hex check, so a pattern with a string for it still matches
+	seq, _, err = scanner.Scan("request %string% done", true, nil)
+	require.NoError(t, err)
+	require.NoError(t, parser.Add(seq))
+	seq, _, err = scanner.Scan("request 550e8400-e29b-41d4-a716-446655440000 done", false, nil)
+	require.NoError(t, err)
+	seq, err = parser.Parse(seq)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"string": "550e8400-e29b-41d4-a716-446655440000"}, seq.Fields())
+}
+
+func TestParserParseAddresses(t *testing.T) {
+	scanner := NewScanner()
+	parser := NewParser()
+	patterns := []struct {
+		pattern string
+		pos     []int
+	}{
+		{"conn from %srcip%:%srcport% route %ipv4cidr%", nil},
+		{"accept [%ipv6%]:%srcport% from %srchost%:%dstport%", []int{8, 16, 31, 41}},
+		{"listen on %hostport%", nil},
+		//a pattern saved before the cidrs were recognised
+		{"drop %ipv4%/%integer% via %dstip%:%dstport%", []int{5, 12, 26, 34}},
+	}
+	for _, p := range patterns {
+		seq, _, err := scanner.Scan(p.pattern, true, p.pos)
+		require.NoError(t, err)
+		require.NoError(t, parser.Add(seq))
+	}
+	var tests = []struct {
+		msg    string
+		fields map[string]string
+	}{
+		{"conn from 10.1.2.3:22 route 10.0.0.0/8", map[string]string{"srcip": "10.1.2.3", "srcport": "22", "ipv4cidr": "10.0.0.0/8"}},
+		{"accept [fe80::1%eth0]:443 from db.example.com:5432", map[string]string{"ipv6": "fe80::1%eth0", "srcport": "443", "srchost": "db.example.com", "dstport": "5432"}},
+		{"listen on [::1]:8080", map[string]string{"hostport": "[::1]:8080"}},
+		{"drop 192.168.0.0/16 via 10.9.8.7:80", map[string]string{"ipv4": "192.168.0.0", "integer": "16", "dstip": "10.9.8.7", "dstport": "80"}},
+	}
+	require.True(t, parser.hostports)
+	require.True(t, parser.cidrparts)
+
+	//the parser read from a snapshot parses the unsplit messages the same
+	var buf bytes.Buffer
+	require.NoError(t, WriteParserSnapshot(&buf, parser, "svc", "rev1"))
+	loaded, err := ReadParserSnapshot(bufio.NewReader(&buf), "svc", "rev1")
+	require.NoError(t, err)
+	require.True(t, loaded.hostports)
+	require.True(t, loaded.cidrparts)
+
+	for _, tc := range tests {
+		for _, p := range []*Parser{parser, loaded} {
+			seq, _, err := scanner.Scan(tc.msg, false, nil)
+			require.NoError(t, err)
+			seq, err = p.Parse(seq)
+			require.NoError(t, err, tc.msg)
+			require.Equal(t, tc.fields, seq.Fields(), tc.msg)
+		}
+	}
+
+	//without those patterns only the split message is parsed
+	parser = NewParser()
+	seq, _, err := scanner.Scan(patterns[0].pattern, true, nil)
+	require.NoError(t, err)
+	require.NoError(t, parser.Add(seq))
+	require.False(t, parser.hostports)
+	require.False(t, parser.cidrparts)
+}
+
+func TestParserParseTimes(t *testing.T) {
+	setTimeSettings(t, 2024, "UTC")
+	scanner := NewScanner()
+	parser := NewParser()
+	patterns := []struct {
+		pattern string
+		pos     []int
+	}{
+		{"%regextime:1% %apphost% started", []int{0, 14}},
+		{"job %time% done at %time%", []int{4, 19}},
+	}
+	for _, p := range patterns {
+		seq, _, err := scanner.Scan(p.pattern, true, p.pos)
+		require.NoError(t, err)
+		require.NoError(t, parser.Add(seq))
+	}
+	var tests = []struct {
+		msg    string
+		fields map[string]string
+		times  map[string]string
+	}{
+		{"Feb 29 10:00:00 web01 started", map[string]string{"regextime": "Feb 29 10:00:00", "apphost": "web01"},
+			map[string]string{"regextime": "2024-02-29T10:00:00Z"}},
+		//the time of day has no date so it is only in the fields
+		{"job 2014-08-16T12:45:03-0400 done at 12:00:01", map[string]string{"time": "2014-08-16T12:45:03-0400", "time1": "12:00:01"},
+			map[string]string{"time": "2014-08-16T12:45:03-04:00"}},
+	}
+	for _, tc := range tests {
+		seq, _, err := scanner.Scan(tc.msg, false, nil)
+		require.NoError(t, err)
+		seq, err = parser.Parse(seq)
+		require.NoError(t, err, tc.msg)
+		require.Equal(t, tc.fields, seq.Fields(), tc.msg)
+		require.Equal(t, tc.times, seq.Times(), tc.msg)
+	}
+
+	//Feb 30 is not a time so the message does not match
+	seq, _, err := scanner.Scan("Feb 30 10:00:00 web01 started", false, nil)
+	require.NoError(t, err)
+	_, err = parser.Parse(seq)
+	require.Error(t, err)
+}
+
 func BenchmarkParserParseMeta(b *testing.B) {
 	benchmarkRunParser(b, parsetests2[3])
 }
@@ -559,6 +702,25 @@ func BenchmarkParserParseNoMeta(b *testing.B) {
 	benchmarkRunParser(b, parsetests[1])
 }
 
+//A message with a host:port and a cidr that does not match, so each of the
+//split sequences is parsed
+func BenchmarkParserParseAddressesNoMatch(b *testing.B) {
+	parser := NewParser()
+	scanner := NewScanner()
+	for _, rule := range []string{"listen on %hostport%", "drop %ipv4%/%integer% via %dstip%:%dstport%"} {
+		seq, _, _ := scanner.Scan(rule, true, nil)
+		parser.Add(seq)
+	}
+	seq, _, _ := scanner.Scan("conn from 10.1.2.3:22 route 10.0.0.0/8", false, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		parser.Parse(seq)
+	}
+}
+
 func benchmarkRunParser(b *testing.B, tc struct {
 	format, msg, rule string
 	pos               []int
@@ -0,0 +1,412 @@
+package sequence
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+const (
+	snapshotMagic   = "SEQP"
+	snapshotVersion = 1
+	snapshotExt     = ".snap"
+)
+
+var (
+	ErrSnapshotInvalid  = errors.New("parser snapshot: invalid or corrupt file")
+	ErrSnapshotMismatch = errors.New("parser snapshot: built for a different service, revision or configuration")
+
+	//the in-process cache of parsers built from the database, keyed by service id
+	parserCache = struct {
+		sync.Mutex
+		parsers map[string]cachedParser
+	}{parsers: make(map[string]cachedParser)}
+)
+
+type cachedParser struct {
+	revision string
+	parser   *Parser
+}
+
+//Returns the parser for the service, from the in-process cache if it has not been
+//invalidated since it was built, then from the snapshot on disk if one exists for the
+//current revision of the service's patterns, and finally by scanning the patterns.
+func getCachedParser(serviceid string, build func(db *sql.DB, ctx context.Context) *Parser) *Parser {
+	parserCache.Lock()
+	cp, ok := parserCache.parsers[serviceid]
+	parserCache.Unlock()
+	if ok {
+		return cp.parser
+	}
+
+	db, ctx := OpenDbandSetContext()
+	defer db.Close()
+	rev := getServiceRevision(db, ctx, serviceid)
+
+	var parser *Parser
+	fname := snapshotFileName(serviceid, rev)
+	if fname != "" {
+		if p, err := LoadParserSnapshot(fname, serviceid, rev); err == nil {
+			parser = p
+		} else if !os.IsNotExist(err) {
+			logger.HandleError(fmt.Sprintf("Unable to load the parser snapshot %s, rebuilding it: %s", fname, err))
+		}
+	}
+	if parser == nil {
+		parser = build(db, ctx)
+		if fname != "" {
+			removeParserSnapshots(serviceid)
+			if err := SaveParserSnapshot(parser, fname, serviceid, rev); err != nil {
+				logger.HandleError(fmt.Sprintf("Unable to save the parser snapshot %s: %s", fname, err))
+			}
+		}
+	}
+
+	parserCache.Lock()
+	parserCache.parsers[serviceid] = cachedParser{revision: rev, parser: parser}
+	parserCache.Unlock()
+	return parser
+}
+
+//Removes the services from the in-process parser cache, so the next call to
+//BuildParserFromDb checks the database revision again. If no services are
+//passed the whole cache is cleared.
+func InvalidateParserCache(serviceids ...string) {
+	parserCache.Lock()
+	defer parserCache.Unlock()
+	if len(serviceids) == 0 {
+		parserCache.parsers = make(map[string]cachedParser)
+		return
+	}
+	for _, sid := range serviceids {
+		delete(parserCache.parsers, sid)
+	}
+}
+
+//The revision of a service is a hash of the ids of all its patterns, since the
+//pattern id is a hash of the pattern itself, it changes whenever a pattern is
+//added or removed and only needs the ids to be read from the database.
+func getServiceRevision(db *sql.DB, ctx context.Context, serviceid string) string {
+	patterns, err := models.Patterns(qm.Select(models.PatternColumns.ID), models.PatternWhere.ServiceID.EQ(serviceid)).All(ctx, db)
+	if err != nil {
+		logger.DatabaseSelectFailed("patterns", "Where Serviceid = "+serviceid, err.Error())
+	}
+	ids := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		ids = append(ids, p.ID)
+	}
+	sort.Strings(ids)
+	h := sha1.New()
+	h.Write([]byte(strings.Join(ids, ",")))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+//Returns the snapshot file name for the service and revision, or an empty string
+//if parser snapshots are not configured.
+func snapshotFileName(serviceid string, revision string) string {
+	if config.parserSnapshotPath == "" {
+		return ""
+	}
+	return filepath.Join(config.parserSnapshotPath, serviceid+"-"+revision+snapshotExt)
+}
+
+//Deletes the snapshots of older revisions for the service.
+func removeParserSnapshots(serviceid string) {
+	if config.parserSnapshotPath == "" {
+		return
+	}
+	files, _ := filepath.Glob(filepath.Join(config.parserSnapshotPath, serviceid+"-*"+snapshotExt))
+	for _, f := range files {
+		os.Remove(f)
+	}
+}
+
+//Hash of the configuration the tree depends on, tag and token types are stored
+//as numbers, so the snapshot cannot be used if these or the token types of the
+//config change.
+func snapshotConfigHash() string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%d|%s", TokenTypesCount, strings.Join(config.tagNames, ","))
+	for _, c := range tokentypes.custom {
+		fmt.Fprintf(h, "|%s=%s", c.name, c.regex)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+//Writes the parser tree to the file in a binary format, the service id and
+//revision are stored in the header and checked when the snapshot is loaded.
+func SaveParserSnapshot(parser *Parser, fname string, serviceid string, revision string) error {
+	if dir := filepath.Dir(fname); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	//write to a temporary file first so a reader never sees half a snapshot
+	tmp := fname + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = WriteParserSnapshot(w, parser, serviceid, revision)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, fname)
+}
+
+//Reads the parser tree from the file, the snapshot must have been saved for the
+//same service and revision with the same tag configuration.
+func LoadParserSnapshot(fname string, serviceid string, revision string) (*Parser, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadParserSnapshot(bufio.NewReader(f), serviceid, revision)
+}
+
+type snapshotWriter struct {
+	w   io.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (this *snapshotWriter) uint(v uint64) {
+	if this.err == nil {
+		n := binary.PutUvarint(this.buf[:], v)
+		_, this.err = this.w.Write(this.buf[:n])
+	}
+}
+
+func (this *snapshotWriter) string(s string) {
+	this.uint(uint64(len(s)))
+	if this.err == nil {
+		_, this.err = io.WriteString(this.w, s)
+	}
+}
+
+func (this *snapshotWriter) flags(f ...bool) {
+	var v uint64
+	for i, b := range f {
+		if b {
+			v |= 1 << uint(i)
+		}
+	}
+	this.uint(v)
+}
+
+//Writes the parser tree to w. The tree is a graph, nodes with + or * point back
+//to themselves and can be shared by their grandparents, so every node is numbered
+//once and the children are written as node numbers.
+func WriteParserSnapshot(w io.Writer, parser *Parser, serviceid string, revision string) error {
+	parser.mu.RLock()
+	defer parser.mu.RUnlock()
+
+	ids := map[*parseNode]uint64{parser.root: 0}
+	nodes := []*parseNode{parser.root}
+	for i := 0; i < len(nodes); i++ {
+		for _, child := range nodeChildren(nodes[i]) {
+			if _, ok := ids[child]; !ok {
+				ids[child] = uint64(len(nodes))
+				nodes = append(nodes, child)
+			}
+		}
+	}
+
+	sw := &snapshotWriter{w: w}
+	sw.string(snapshotMagic)
+	sw.uint(snapshotVersion)
+	sw.string(serviceid)
+	sw.string(revision)
+	sw.string(snapshotConfigHash())
+	sw.uint(uint64(parser.height))
+	sw.uint(uint64(len(nodes)))
+
+	for _, n := range nodes {
+		sw.uint(uint64(n.Type))
+		sw.uint(uint64(n.Tag))
+		sw.string(n.Value)
+		sw.string(n.Special)
+		sw.string(n.until)
+		sw.flags(n.IsSpaceBefore, n.isValue, n.isKey, n.Token.minus, n.plus, n.star, n.leaf, n.parent, n.minus)
+
+		var types []int
+		for t, children := range n.tc {
+			if len(children) > 0 {
+				types = append(types, t)
+			}
+		}
+		sw.uint(uint64(len(types)))
+		for _, t := range types {
+			sw.uint(uint64(t))
+			sw.uint(uint64(len(n.tc[t])))
+			for _, child := range n.tc[t] {
+				sw.uint(ids[child])
+			}
+		}
+
+		keys := make([]string, 0, len(n.lc))
+		for k := range n.lc {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		sw.uint(uint64(len(keys)))
+		for _, k := range keys {
+			sw.string(k)
+			sw.uint(ids[n.lc[k]])
+		}
+	}
+	return sw.err
+}
+
+//Returns the token type children followed by the literal children in a fixed order.
+func nodeChildren(n *parseNode) []*parseNode {
+	var children []*parseNode
+	for _, tc := range n.tc {
+		children = append(children, tc...)
+	}
+	keys := make([]string, 0, len(n.lc))
+	for k := range n.lc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		children = append(children, n.lc[k])
+	}
+	return children
+}
+
+type snapshotReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (this *snapshotReader) uint() uint64 {
+	if this.err != nil {
+		return 0
+	}
+	var v uint64
+	v, this.err = binary.ReadUvarint(this.r)
+	return v
+}
+
+func (this *snapshotReader) string() string {
+	l := this.uint()
+	if this.err != nil {
+		return ""
+	}
+	if l > 1<<24 {
+		this.err = ErrSnapshotInvalid
+		return ""
+	}
+	b := make([]byte, l)
+	_, this.err = io.ReadFull(this.r, b)
+	return string(b)
+}
+
+func (this *snapshotReader) flags(f ...*bool) {
+	v := this.uint()
+	for i, b := range f {
+		*b = v&(1<<uint(i)) != 0
+	}
+}
+
+//Reads a parser tree written by WriteParserSnapshot. An empty service id or
+//revision skips the check of that value.
+func ReadParserSnapshot(r *bufio.Reader, serviceid string, revision string) (*Parser, error) {
+	sr := &snapshotReader{r: r}
+	if sr.string() != snapshotMagic || sr.uint() != snapshotVersion {
+		if sr.err != nil && sr.err != io.EOF {
+			return nil, sr.err
+		}
+		return nil, ErrSnapshotInvalid
+	}
+	sid, rev, cfg := sr.string(), sr.string(), sr.string()
+	if sr.err != nil {
+		return nil, ErrSnapshotInvalid
+	}
+	if (serviceid != "" && sid != serviceid) || (revision != "" && rev != revision) || cfg != snapshotConfigHash() {
+		return nil, ErrSnapshotMismatch
+	}
+
+	height := int(sr.uint())
+	count := sr.uint()
+	if sr.err != nil || count == 0 || count > 1<<24 {
+		return nil, ErrSnapshotInvalid
+	}
+
+	parser := &Parser{height: height}
+	nodes := make([]*parseNode, count)
+	for i := range nodes {
+		nodes[i] = newParseNode()
+	}
+	for _, n := range nodes {
+		n.Type = TokenType(sr.uint())
+		n.Tag = TagType(sr.uint())
+		n.Value = sr.string()
+		n.Special = sr.string()
+		n.until = sr.string()
+		sr.flags(&n.IsSpaceBefore, &n.isValue, &n.isKey, &n.Token.minus, &n.plus, &n.star, &n.leaf, &n.parent, &n.minus)
+
+		types := sr.uint()
+		for j := uint64(0); j < types && sr.err == nil; j++ {
+			t := sr.uint()
+			l := sr.uint()
+			if t >= uint64(TokenTypesCount) || l > count {
+				return nil, ErrSnapshotInvalid
+			}
+			for k := uint64(0); k < l && sr.err == nil; k++ {
+				id := sr.uint()
+				if id >= count {
+					return nil, ErrSnapshotInvalid
+				}
+				n.tc[t] = append(n.tc[t], nodes[id])
+			}
+		}
+
+		lits := sr.uint()
+		for j := uint64(0); j < lits && sr.err == nil; j++ {
+			k := sr.string()
+			id := sr.uint()
+			if id >= count {
+				return nil, ErrSnapshotInvalid
+			}
+			n.lc[k] = nodes[id]
+		}
+		if sr.err != nil {
+			return nil, ErrSnapshotInvalid
+		}
+		//the same as Add sets them from the patterns
+		switch {
+		case n.Type == TokenHostPort:
+			parser.hostports = true
+		case (n.Type == TokenIPv4 || n.Type == TokenIPv6) && n.lc["/"] != nil:
+			parser.cidrparts = true
+		}
+	}
+
+	parser.root = nodes[0]
+	return parser, nil
+}
@@ -0,0 +1,71 @@
+package sequence
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserSnapshot(t *testing.T) {
+	parser := NewParser()
+	scanner := NewScanner()
+	var pos []int
+
+	testset := append(parsetests, parsetests2...)
+	if config.markSpaces {
+		testset = append(parsetestsnosp, parsetests2nosp...)
+	}
+
+	for _, tc := range testset {
+		seq, _, err := scanner.Scan(tc.rule, true, tc.pos)
+		require.NoError(t, err, tc.rule)
+		require.NoError(t, parser.Add(seq), tc.rule)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteParserSnapshot(&buf, parser, "svc", "rev1"))
+	data := buf.Bytes()
+
+	loaded, err := ReadParserSnapshot(bufio.NewReader(bytes.NewReader(data)), "svc", "rev1")
+	require.NoError(t, err)
+	require.Equal(t, parser.height, loaded.height)
+
+	// writing the loaded parser must give exactly the same snapshot
+	var buf2 bytes.Buffer
+	require.NoError(t, WriteParserSnapshot(&buf2, loaded, "svc", "rev1"))
+	require.Equal(t, data, buf2.Bytes())
+
+	for _, tc := range testset {
+		var seq Sequence
+		if tc.format == "json" {
+			seq, _, err = scanner.ScanJson(tc.msg)
+		} else {
+			seq, _, err = scanner.Scan(tc.msg, false, pos)
+		}
+		require.NoError(t, err, tc.msg)
+
+		expected, err := parser.Parse(seq)
+		require.NoError(t, err, tc.msg)
+		actual, err := loaded.Parse(seq)
+		require.NoError(t, err, tc.msg)
+		require.Equal(t, expected, actual, tc.msg)
+	}
+
+	_, err = ReadParserSnapshot(bufio.NewReader(bytes.NewReader(data)), "svc", "rev2")
+	require.Equal(t, ErrSnapshotMismatch, err)
+	_, err = ReadParserSnapshot(bufio.NewReader(bytes.NewReader(data)), "other", "rev1")
+	require.Equal(t, ErrSnapshotMismatch, err)
+	_, err = ReadParserSnapshot(bufio.NewReader(bytes.NewReader(data[:len(data)/2])), "svc", "rev1")
+	require.Error(t, err)
+	_, err = ReadParserSnapshot(bufio.NewReader(bytes.NewReader([]byte("not a snapshot"))), "", "")
+	require.Error(t, err)
+
+	fname := filepath.Join(t.TempDir(), "snapshots", "svc-rev1"+snapshotExt)
+	require.NoError(t, SaveParserSnapshot(parser, fname, "svc", "rev1"))
+	fromFile, err := LoadParserSnapshot(fname, "svc", "rev1")
+	require.NoError(t, err)
+	require.Equal(t, parser.height, fromFile.height)
+}
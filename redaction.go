@@ -0,0 +1,223 @@
+package sequence
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//The environment variable read for the redaction key when it is not in the config.
+const RedactionKeyEnv = "SEQUENCE_REDACTION_KEY"
+
+//The pseudonyms that can be told apart from real values, so an example that has been
+//redacted is not redacted again when it is exported. The addresses are from the reserved
+//240.0.0.0/4 and the 2001:db8::/32 documentation ranges and the domain is reserved.
+var (
+	pseudonymWordRegex = regexp.MustCompile(`^anon[0-9a-f]{8}(@example\.invalid|\.example\.com)?$`)
+	pseudonymPrefixes  = map[TokenType]string{
+		TokenIPv4:     "240.",
+		TokenIPv6:     "2001:db8:",
+		TokenURI:      "https://redacted.invalid/",
+		TokenIPv4CIDR: "240.",
+		TokenIPv6CIDR: "2001:db8:",
+	}
+)
+
+func validateRedactionConfig(tags []string, key string) error {
+	redaction.tags = make(map[string]bool, len(tags))
+	redaction.key = nil
+	for _, t := range tags {
+		if _, ok := config.tagIDs[t]; !ok || t == "funknown" {
+			return fmt.Errorf("the redaction tag %s is not one of the tags in the config", t)
+		}
+		redaction.tags[t] = true
+	}
+	if key == "" {
+		key = os.Getenv(RedactionKeyEnv)
+	}
+	if len(tags) > 0 && len(key) < 16 {
+		return fmt.Errorf("the redaction key must be at least 16 characters, set it in the config or %s", RedactionKeyEnv)
+	}
+	redaction.key = []byte(key)
+	return nil
+}
+
+//Returns true if the values of some tags are redacted.
+func RedactionEnabled() bool {
+	return len(redaction.tags) > 0
+}
+
+//Returns the pseudonym of the value, the same key and value always give the same
+//pseudonym and it is in the same lexical class as the value, so the example still
+//parses with the pattern of the message.
+func pseudonym(value string, tt TokenType) string {
+	mac := hmac.New(sha256.New, redaction.key)
+	mac.Write([]byte(value))
+	sum := mac.Sum(nil)
+	switch tt {
+	case TokenIPv4:
+		return fmt.Sprintf("240.%d.%d.%d", sum[0], sum[1], sum[2])
+	case TokenIPv6:
+		return fmt.Sprintf("2001:db8:%x:%x:%x:%x:%x:%x", uint16(sum[0])<<8|uint16(sum[1]), uint16(sum[2])<<8|uint16(sum[3]),
+			uint16(sum[4])<<8|uint16(sum[5]), uint16(sum[6])<<8|uint16(sum[7]), uint16(sum[8])<<8|uint16(sum[9]), uint16(sum[10])<<8|uint16(sum[11]))
+	case TokenInteger, TokenFloat:
+		//each digit is replaced, the sign, the point and the number of digits are kept
+		b := []byte(value)
+		lead := len(strings.Trim(value, "+-")) > 1
+		for i, c := range b {
+			if c < '0' || c > '9' {
+				continue
+			}
+			if lead {
+				b[i] = '1' + sum[i%len(sum)]%9
+				lead = false
+			} else {
+				b[i] = '0' + sum[i%len(sum)]%10
+			}
+		}
+		return string(b)
+	case TokenMac:
+		sep := ":"
+		if strings.Contains(value, "-") {
+			sep = "-"
+		}
+		//a locally administered address
+		return fmt.Sprintf("02%s%02x%s%02x%s%02x%s%02x%s%02x", sep, sum[0], sep, sum[1], sep, sum[2], sep, sum[3], sep, sum[4])
+	case TokenURI:
+		return pseudonymPrefixes[TokenURI] + hex.EncodeToString(sum[:4])
+	case TokenUUID:
+		h := hex.EncodeToString(sum[:16])
+		return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+	case TokenHex:
+		//the same number of digits and the same case, with a letter and a digit so it is still hex
+		b := []byte(strings.Repeat(hex.EncodeToString(sum), len(value)/(2*len(sum))+1)[:len(value)])
+		b[0], b[1] = 'a'+sum[0]%6, '0'+sum[1]%10
+		if strings.ToUpper(value) == value {
+			return strings.ToUpper(string(b))
+		}
+		return string(b)
+	case TokenBase64:
+		//the same length and padding, the characters cycle through upper case, lower case and digits
+		pad := len(value) - len(strings.TrimRight(value, "="))
+		b := make([]byte, len(value)-pad)
+		for i := range b {
+			r := sum[i%len(sum)]
+			switch i % 3 {
+			case 0:
+				b[i] = 'A' + r%26
+			case 1:
+				b[i] = 'a' + r%26
+			default:
+				b[i] = '0' + r%10
+			}
+		}
+		return string(b) + strings.Repeat("=", pad)
+	case TokenIPv4CIDR, TokenIPv6CIDR:
+		//the address of the network is replaced, the prefix length is kept
+		i := strings.LastIndexByte(value, '/')
+		ones, _ := strconv.Atoi(value[i+1:])
+		at, bits := TokenIPv4, 32
+		if tt == TokenIPv6CIDR {
+			at, bits = TokenIPv6, 128
+		}
+		return fmt.Sprintf("%s/%d", net.ParseIP(pseudonym(value[:i], at)).Mask(net.CIDRMask(ones, bits)), ones)
+	case TokenHostPort:
+		//the host is replaced, the port is kept
+		host, port, ht := splitHostPort(value)
+		switch ht {
+		case TokenIPv4:
+			return pseudonym(host, TokenIPv4) + ":" + port
+		case TokenIPv6:
+			return "[" + pseudonym(host, TokenIPv6) + "]:" + port
+		}
+		return "anon" + hex.EncodeToString(sum[:4]) + ".example.com:" + port
+	}
+	if strings.Contains(value, "@") {
+		return "anon" + hex.EncodeToString(sum[:4]) + "@example.invalid"
+	}
+	return "anon" + hex.EncodeToString(sum[:4])
+}
+
+//Returns true if the value is a pseudonym, the numbers, mac addresses and identifiers can
+//not be told apart from real values so they are always redacted.
+func isPseudonym(value string, tt TokenType) bool {
+	if tt == TokenHostPort {
+		host, _, ht := splitHostPort(value)
+		return isPseudonym(host, ht)
+	}
+	if p, ok := pseudonymPrefixes[tt]; ok {
+		return strings.HasPrefix(value, p)
+	}
+	return pseudonymWordRegex.MatchString(value)
+}
+
+//Returns the message with the values of the tokens that have a redacted tag replaced by
+//their pseudonyms, the sequence is the analyzed or parsed sequence of the message.
+func RedactMessage(msg string, seq Sequence) string {
+	if !RedactionEnabled() {
+		return msg
+	}
+	var (
+		sb  strings.Builder
+		pos int
+	)
+	for _, t := range seq {
+		if t.Value == "" {
+			continue
+		}
+		//the values are in the order of the message, a value the scanner changed is not found
+		i := strings.Index(msg[pos:], t.Value)
+		if i < 0 {
+			continue
+		}
+		start := pos + i
+		sb.WriteString(msg[pos:start])
+		if t.Tag != TagUnknown && int(t.Tag) < len(config.tagNames) && redaction.tags[t.Tag.String()] && !isPseudonym(t.Value, t.Type) {
+			sb.WriteString(pseudonym(t.Value, t.Type))
+		} else {
+			sb.WriteString(t.Value)
+		}
+		pos = start + len(t.Value)
+	}
+	sb.WriteString(msg[pos:])
+	return sb.String()
+}
+
+//Returns the examples of a pattern with the values of the redacted tags replaced, the
+//examples are parsed with the pattern to find the tags. An example that does not parse
+//with the pattern is left out, so a value that should be redacted is never returned.
+func RedactExamples(pattern string, tagPositions string, examples []LogRecord) []LogRecord {
+	if !RedactionEnabled() || len(examples) == 0 {
+		return examples
+	}
+	seq, _, err := NewScanner().Scan(pattern, true, SplitToInt(tagPositions, ","))
+	parser := NewParser()
+	if err == nil {
+		err = parser.Add(seq)
+	}
+	if err != nil {
+		logger.HandleError(fmt.Sprintf("Unable to redact the examples of the pattern %s: %s", pattern, err.Error()))
+		return nil
+	}
+	scanner := NewScanner()
+	var out []LogRecord
+	for _, lr := range examples {
+		mseq, _, err := ScanMessage(scanner, lr.Message, "")
+		if err == nil {
+			mseq, err = parser.Parse(mseq)
+		}
+		if err != nil {
+			logger.HandleInfo(fmt.Sprintf("An example of the pattern %s does not parse with it and was left out of the redacted examples.", pattern))
+			continue
+		}
+		lr.Message = RedactMessage(lr.Message, mseq)
+		out = append(out, lr)
+	}
+	return out
+}
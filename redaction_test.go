@@ -0,0 +1,119 @@
+package sequence
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setRedactionConfig(t *testing.T, tags ...string) {
+	saved := redaction
+	t.Cleanup(func() { redaction = saved })
+	require.NoError(t, validateRedactionConfig(tags, "0123456789abcdef"))
+}
+
+func TestValidateRedactionConfig(t *testing.T) {
+	saved := redaction
+	t.Cleanup(func() { redaction = saved })
+	t.Setenv(RedactionKeyEnv, "")
+
+	require.NoError(t, validateRedactionConfig(nil, ""))
+	require.False(t, RedactionEnabled())
+	require.Error(t, validateRedactionConfig([]string{"nosuchtag"}, "0123456789abcdef"))
+	require.Error(t, validateRedactionConfig([]string{"srcuser"}, "short"))
+	t.Setenv(RedactionKeyEnv, "0123456789abcdef")
+	require.NoError(t, validateRedactionConfig([]string{"srcuser"}, ""))
+	require.True(t, RedactionEnabled())
+}
+
+func TestPseudonym(t *testing.T) {
+	setRedactionConfig(t, "srcuser")
+	var tests = []struct {
+		value string
+		tt    TokenType
+		check func(string) bool
+	}{
+		{"10.1.2.3", TokenIPv4, func(s string) bool { return net.ParseIP(s).To4() != nil }},
+		{"fe80::1", TokenIPv6, func(s string) bool { return net.ParseIP(s) != nil }},
+		{"-1024", TokenInteger, func(s string) bool { return len(s) == 5 && s[0] == '-' && s[1] != '0' }},
+		{"3.14", TokenFloat, func(s string) bool { return len(s) == 4 && s[1] == '.' }},
+		{"00:1a:2b:3c:4d:5e", TokenMac, func(s string) bool { _, err := net.ParseMAC(s); return err == nil }},
+		{"bob@corp.com", TokenString, func(s string) bool { return strings.HasSuffix(s, "@example.invalid") }},
+		{"bob", TokenString, func(s string) bool { return strings.HasPrefix(s, "anon") }},
+		{"550e8400-e29b-41d4-a716-446655440000", TokenUUID, isUUID},
+		{"E3B0C44298FC1C149AFBF4C8996FB924", TokenHex, func(s string) bool { return scanIdentifierType(s) == TokenHex && strings.ToUpper(s) == s }},
+		{"dGhpcyBpcyBhIHNlc3Npb24gdG9rZW4=", TokenBase64, func(s string) bool { return scanIdentifierType(s) == TokenBase64 && len(s) == 32 }},
+		{"10.20.0.0/16", TokenIPv4CIDR, func(s string) bool { return scanAddressType(s) == TokenIPv4CIDR && strings.HasSuffix(s, "/16") }},
+		{"2001:db8:1::/48", TokenIPv6CIDR, func(s string) bool { return scanAddressType(s) == TokenIPv6CIDR && strings.HasSuffix(s, "/48") }},
+		{"10.1.2.3:22", TokenHostPort, func(s string) bool { return scanAddressType(s) == TokenHostPort && strings.HasSuffix(s, ":22") }},
+		{"[fe80::1%eth0]:443", TokenHostPort, func(s string) bool { return scanAddressType(s) == TokenHostPort && s[0] == '[' }},
+		{"db.corp.com:5432", TokenHostPort, func(s string) bool { return scanAddressType(s) == TokenHostPort && strings.HasSuffix(s, ":5432") }},
+	}
+	for _, tc := range tests {
+		p := pseudonym(tc.value, tc.tt)
+		require.NotEqual(t, tc.value, p, tc.value)
+		require.True(t, tc.check(p), "%s gave %s", tc.value, p)
+		require.Equal(t, p, pseudonym(tc.value, tc.tt), tc.value)
+	}
+	require.True(t, isPseudonym(pseudonym("bob", TokenString), TokenString))
+	require.True(t, isPseudonym(pseudonym("10.1.2.3", TokenIPv4), TokenIPv4))
+	require.False(t, isPseudonym("bob", TokenString))
+	require.True(t, isPseudonym(pseudonym("db.corp.com:5432", TokenHostPort), TokenHostPort))
+	require.False(t, isPseudonym("db.corp.com:5432", TokenHostPort))
+
+	//another key gives another pseudonym
+	p := pseudonym("bob", TokenString)
+	redaction.key = []byte("fedcba9876543210")
+	require.NotEqual(t, p, pseudonym("bob", TokenString))
+}
+
+func scanIdentifierType(s string) TokenType {
+	if l, tt := scanIdentifier(s, 0); l == len(s) {
+		return tt
+	}
+	return TokenUnknown
+}
+
+func scanAddressType(s string) TokenType {
+	if l, tt := scanAddress(s, 0); l == len(s) {
+		return tt
+	}
+	return TokenUnknown
+}
+
+func TestRedactMessage(t *testing.T) {
+	seq := Sequence{
+		{Type: TokenLiteral, Value: "user"},
+		{Type: TokenString, Tag: config.tagIDs["srcuser"], Value: "bob"},
+		{Type: TokenLiteral, Value: "from"},
+		{Type: TokenIPv4, Tag: config.tagIDs["srcip"], Value: "10.1.2.3"},
+	}
+	msg := "user bob from 10.1.2.3"
+	require.Equal(t, msg, RedactMessage(msg, seq))
+
+	setRedactionConfig(t, "srcuser")
+	redacted := RedactMessage(msg, seq)
+	require.Equal(t, "user "+pseudonym("bob", TokenString)+" from 10.1.2.3", redacted)
+	//a redacted message is not redacted again
+	seq[1].Value = pseudonym("bob", TokenString)
+	require.Equal(t, redacted, RedactMessage(redacted, seq))
+}
+
+func TestRedactExamples(t *testing.T) {
+	SetLogger(NewLogger(filepath.Join(t.TempDir(), "log.txt"), "error"))
+	pattern := "user %srcuser% logged in from %srcip%"
+	list := []LogRecord{{Message: "user bob logged in from 10.1.2.3"}, {Message: "disk full"}}
+	require.Equal(t, list, RedactExamples(pattern, "", list))
+
+	setRedactionConfig(t, "srcuser", "srcip")
+	out := RedactExamples(pattern, "", list)
+	//the example that does not parse with the pattern is left out
+	require.Len(t, out, 1)
+	require.NotContains(t, out[0].Message, "bob")
+	require.NotContains(t, out[0].Message, "10.1.2.3")
+	//the redacted example still parses with the pattern and redacting it again changes nothing
+	require.Equal(t, out, RedactExamples(pattern, "", out))
+}
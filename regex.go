@@ -0,0 +1,227 @@
+package sequence
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//The regular expressions for each token type, these are written in the syntax
+//shared by Go, PCRE, Onigmo (Fluent Bit) and Rust (Vector), so they can be used
+//by any of the exporters.
+var tokenTypeRegex = map[TokenType]string{
+	TokenTime:      `.+?`,
+	TokenIPv4:      `\d{1,3}(?:\.\d{1,3}){3}`,
+	TokenIPv6:      `[0-9A-Fa-f]*:[0-9A-Fa-f:.]*(?:%[0-9A-Za-z_.\-]+)?`,
+	TokenInteger:   `[+-]?\d+`,
+	TokenFloat:     `[+-]?(?:\d+\.\d*|\.\d+)(?:[eE][+-]?\d+)?`,
+	TokenURI:       `[A-Za-z][A-Za-z0-9+.\-]*://\S+`,
+	TokenMac:       `[0-9A-Fa-f]{1,2}(?:[:\-][0-9A-Fa-f]{1,2}){5}`,
+	TokenString:    `\S+?`,
+	TokenMultiLine: `[\s\S]*`,
+	TokenUUID:      `[0-9A-Fa-f]{8}(?:-[0-9A-Fa-f]{4}){3}-[0-9A-Fa-f]{12}`,
+	TokenHex:       `[0-9A-Fa-f]{16,}`,
+	TokenBase64:    `[A-Za-z0-9+/]{18,}={0,2}`,
+	TokenIPv4CIDR:  `\d{1,3}(?:\.\d{1,3}){3}/\d{1,2}`,
+	TokenIPv6CIDR:  `[0-9A-Fa-f]*:[0-9A-Fa-f:.]*/\d{1,3}`,
+	TokenHostPort:  `(?:\[[^\]\s]+\]|[A-Za-z0-9\-_.]+):\d{1,5}`,
+	token__host__:  `[A-Za-z0-9\-_.]+`,
+	token__email__: `[^\s@]+@[^\s@]+`,
+}
+
+//group names can only have letters, digits and underscores
+var invalidGroupNameRegex = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+//Translates a pattern string and its tag positions, as saved in the database, to
+//an anchored regular expression, see Sequence.ToRegex.
+func PatternToRegex(pattern string, tagPositions string) (string, error) {
+	scanner := NewScanner()
+	seq, _, err := scanner.Scan(pattern, true, SplitToInt(tagPositions, ","))
+	if err != nil {
+		return "", err
+	}
+	return seq.ToRegex()
+}
+
+// ToRegex compiles a pattern sequence, as returned by scanning a pattern with isParse
+// set, to an anchored regular expression. Each tag becomes a named group, using the tag
+// name or the token type name when the token has no tag, numbered when the name is
+// used more than once in the pattern, eg string, string1.
+//
+// The tokens are separated by \s+ when IsSpaceBefore is set, when the spaces are not
+// marked, or next to a tag without a space, there may or may not be a space between the
+// tokens. A %regextime:N% tag uses
+// the regular expression N from [timesettings.regex], and the - + * meta characters
+// match the rest of the message, one or more and zero or more of the token type, in
+// the same way as the parser.
+//
+// The named groups use the (?P<name>) syntax, the exporters for tools that only
+// support (?<name>) need to convert them.
+func (this Sequence) ToRegex() (string, error) {
+	var (
+		err       error
+		sb        strings.Builder
+		prevIsTag bool
+		mtc       = make(map[string]int)
+	)
+	sb.WriteString("^")
+	for i, token := range this {
+		if vl := len(token.Value); vl >= 2 && token.Value[0] == '%' && token.Value[vl-1] == '%' {
+			if token, err = processTagToken(token); err != nil {
+				return "", err
+			}
+		}
+
+		isTag := token.Type != TokenLiteral && token.Type != TokenUnknown
+		sep := ""
+		if i > 0 {
+			if !config.markSpaces {
+				//the spaces are not kept in the pattern, so there may or may not be one
+				sep = `\s*`
+			} else if token.IsSpaceBefore {
+				sep = `\s+`
+			} else if isTag || prevIsTag {
+				//the analyzer can drop the space next to a tag when the messages are
+				//merged, the parser ignores it so the regex does too
+				sep = `\s*`
+			}
+		}
+		prevIsTag = isTag
+
+		if !isTag {
+			sb.WriteString(sep + regexp.QuoteMeta(token.Value))
+			continue
+		}
+
+		rg := tokenRegex(token)
+		switch {
+		case token.until != "":
+			//everything up to the next token, which is the until literal
+			rg += `[\s\S]*?`
+		case token.minus:
+			//the rest of the message
+			rg += `[\s\S]*`
+		case token.plus || token.star:
+			rg += `(?:\s+` + rg + `)*`
+		}
+		group := "(?P<" + regexGroupName(token, mtc) + ">" + rg + ")"
+		if token.star {
+			//the token may not be there at all, and neither is the space before it
+			sb.WriteString("(?:" + sep + group + ")?")
+		} else {
+			sb.WriteString(sep + group)
+		}
+	}
+	sb.WriteString("$")
+	return sb.String(), nil
+}
+
+//Returns the regular expression for a single token of the token's type, a time
+//tagged as regextime uses the matching expression from [timesettings.regex] and a
+//type from [tokentypes] uses its own regex.
+func tokenRegex(token Token) string {
+	if token.Type == TokenTime && token.Tag == TagRegExTime && token.Special != "" {
+		if rg, ok := GetTimeSettingsRegExValue(token.Special); ok && rg != "" {
+			if rg = pcreToRegex(rg); rg != "" {
+				return rg
+			}
+		}
+	}
+	if rg, ok := tokenTypeRegex[token.Type]; ok {
+		return rg
+	}
+	if c, ok := customType(token.Type); ok {
+		return "(?:" + c.regex + ")"
+	}
+	return `\S+?`
+}
+
+//The time regexes in the config are written for PCRE, the scanner matches times
+//regardless of case and of the padding between the parts, eg "may  5", so the regex
+//does the same. Returns an empty string if the result does not compile.
+func pcreToRegex(rg string) string {
+	return convertPCRE(rg, true)
+}
+
+//Converts a PCRE regular expression, such as the ones in [timesettings.regex], to
+//the RE2 syntax used by Go. The atomic groups become non-capturing groups and the
+//look around assertions are removed, as they are not supported, so the result can
+//match more than the original. Returns an empty string if the result does not compile.
+func PCREToRegex(rg string) string {
+	return convertPCRE(rg, false)
+}
+
+func convertPCRE(rg string, lenient bool) string {
+	var sb strings.Builder
+	if lenient {
+		sb.WriteString("(?i:")
+	}
+	for i := 0; i < len(rg); i++ {
+		switch {
+		case lenient && strings.HasPrefix(rg[i:], `\s`) && !strings.HasPrefix(rg[i+2:], "+") && !strings.HasPrefix(rg[i+2:], "*"):
+			sb.WriteString(`\s+`)
+			i++
+		case rg[i] == '\\' && i+1 < len(rg):
+			sb.WriteString(rg[i : i+2])
+			i++
+		case strings.HasPrefix(rg[i:], "(?>"):
+			sb.WriteString("(?:")
+			i += 2
+		case strings.HasPrefix(rg[i:], "(?="), strings.HasPrefix(rg[i:], "(?!"),
+			strings.HasPrefix(rg[i:], "(?<="), strings.HasPrefix(rg[i:], "(?<!"):
+			i = closingParen(rg, i)
+		default:
+			sb.WriteByte(rg[i])
+		}
+	}
+	if lenient {
+		sb.WriteString(")")
+	}
+	if _, err := regexp.Compile(sb.String()); err != nil {
+		return ""
+	}
+	return sb.String()
+}
+
+//Returns the index of the parenthesis closing the group that starts at i, skipping
+//escaped characters, character classes and nested groups.
+func closingParen(rg string, i int) int {
+	depth := 0
+	inClass := false
+	for ; i < len(rg); i++ {
+		switch c := rg[i]; {
+		case c == '\\':
+			i++
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+		case c == '[':
+			inClass = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return i
+}
+
+//Returns the name of the group for the token, numbered if the name has already
+//been used in the pattern.
+func regexGroupName(token Token, mtc map[string]int) string {
+	name := token.Type.String()
+	if token.Tag != TagUnknown {
+		name = token.Tag.String()
+	}
+	name = invalidGroupNameRegex.ReplaceAllString(name, "_")
+	if t, ok := mtc[name]; ok {
+		mtc[name] = t + 1
+		return name + strconv.Itoa(t)
+	}
+	mtc[name] = 1
+	return name
+}
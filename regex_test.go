@@ -0,0 +1,181 @@
+package sequence
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatternToRegex(t *testing.T) {
+	scanner := NewScanner()
+	var pos []int
+	msgs := []string{
+		"Failed password for root from 10.1.2.3 port 2222 ssh2",
+		"session opened for user root by (uid=988)",
+		"job alpha finished in 1.5 ms",
+		"link 00:11:22:33:44:55 is up",
+		"conn from 10.1.2.3:22 to [fe80::1%eth0]:443 via db.example.com:5432 route 10.0.0.0/8 and 2001:db8::/32",
+	}
+	for _, msg := range msgs {
+		seq, _, err := scanner.Scan(msg, false, pos)
+		require.NoError(t, err, msg)
+		analyzer := NewAnalyzer()
+		require.NoError(t, analyzer.Add(seq), msg)
+		require.NoError(t, analyzer.Finalize(), msg)
+		aseq, err := analyzer.Analyze(seq)
+		require.NoError(t, err, msg)
+		pat, tp := aseq.String()
+
+		rg, err := PatternToRegex(pat, SplitToString(tp, ","))
+		require.NoError(t, err, pat)
+		re, err := regexp.Compile(rg)
+		require.NoError(t, err, rg)
+		require.True(t, re.MatchString(msg), "%s does not match %s", rg, msg)
+	}
+
+	rg, err := PatternToRegex("%srcuser% logged in from %srcip% and %srcip%", "0,25,37")
+	require.NoError(t, err)
+	require.Equal(t, `^(?P<srcuser>\S+?)\s+logged\s+in\s+from\s+(?P<srcip>\d{1,3}(?:\.\d{1,3}){3})\s+and\s+(?P<srcip1>\d{1,3}(?:\.\d{1,3}){3})$`, rg)
+}
+
+func TestSequenceToRegexRoundTrip(t *testing.T) {
+	scanner := NewScanner()
+	analyzer := NewAnalyzer()
+	var pos []int
+	msgs := []string{
+		"Jan 15 14:07:04 testserver sudo: pam_unix(sudo:auth): conversation failed",
+		"Jan 15 14:09:11 testserver sudo: pam_unix(sudo:auth): conversation succeeded",
+		"may  5 18:07:27 dlfssrv unix: dlfs_remove(), entered fname=tempfile",
+		"may 15 18:07:29 dlfssrv unix: dlfs_remove(), entered fname=otherfile",
+		"2005-03-18 14:01:46 fw=TOPSEC priv=6 src=61.167.71.244 smac=00:04:c1:8b:d8:82",
+		"2005-03-18 14:02:01 fw=TOPSEC priv=12 src=10.0.0.1 smac=00:04:c1:8b:d8:83",
+		"Jan 15 14:07:04 web01 httpd: GET http://example.com/index.html 200 1.25",
+		"Jan 16 09:00:00 web01 httpd: POST http://example.com/login 302 0.5",
+	}
+	for _, tc := range analyzerSshTests {
+		msgs = append(msgs, tc.msg)
+	}
+	for _, tc := range analyzerKVTests {
+		msgs = append(msgs, tc.msg)
+	}
+	for _, msg := range msgs {
+		seq, _, err := scanner.Scan(msg, false, pos)
+		require.NoError(t, err, msg)
+		require.NoError(t, analyzer.Add(seq), msg)
+	}
+	require.NoError(t, analyzer.Finalize())
+
+	//every message is an example of the pattern it was analyzed to
+	regexes := make(map[string]*regexp.Regexp)
+	for _, msg := range msgs {
+		seq, _, err := scanner.Scan(msg, false, pos)
+		require.NoError(t, err, msg)
+		aseq, err := analyzer.Analyze(seq)
+		require.NoError(t, err, msg)
+		pat, tp := aseq.String()
+		re, ok := regexes[pat]
+		if !ok {
+			rg, err := PatternToRegex(pat, SplitToString(tp, ","))
+			require.NoError(t, err, pat)
+			re, err = regexp.Compile(rg)
+			require.NoError(t, err, rg)
+			regexes[pat] = re
+		}
+		require.True(t, re.MatchString(msg), "%s does not match %s", re.String(), msg)
+	}
+}
+
+func TestSequenceToRegexMeta(t *testing.T) {
+	scanner := NewScanner()
+	tests := []struct {
+		rule      string
+		pos       []int
+		match     []string
+		nomatch   []string
+		groupName string
+		group     string
+	}{
+		{
+			"user %dstuser% logged in from %srcip%",
+			[]int{5, 30},
+			[]string{"user root logged in from 10.1.2.3"},
+			[]string{"user root logged in from host", "user root logged in from 10.1.2.3 now"},
+			"srcip", "10.1.2.3",
+		},
+		{
+			"link %srcmac% down after %integer% s",
+			[]int{5, 25},
+			[]string{"link 00:11:22:33:44:55 down after 10 s"},
+			[]string{"link 00:11:22:33:44 down after 10 s", "link 00:11:22:33:44:55 down after ten s"},
+			"srcmac", "00:11:22:33:44:55",
+		},
+		{
+			"get %object:uri% done",
+			[]int{4},
+			[]string{"get http://example.com/a?b=c done"},
+			[]string{"get /a done"},
+			"object", "http://example.com/a?b=c",
+		},
+		{
+			"error : %reason:-%",
+			[]int{8},
+			[]string{"error : disk full on /dev/sda1", "error : x"},
+			[]string{"error : "},
+			"reason", "disk full on /dev/sda1",
+		},
+		{
+			"job %object:string:+% finished",
+			[]int{4},
+			[]string{"job nightly backup finished", "job backup finished"},
+			[]string{"job finished"},
+			"object", "nightly backup",
+		},
+		{
+			"job %object:string:*% finished",
+			[]int{4},
+			[]string{"job nightly backup finished", "job backup finished", "job finished"},
+			[]string{"job  "},
+			"object", "nightly backup",
+		},
+		{
+			"%srcuser% logged in at %regextime:3%",
+			[]int{0, 23},
+			[]string{"root logged in at 2005-03-18 14:01:46"},
+			[]string{"root logged in at yesterday"},
+			"regextime", "2005-03-18 14:01:46",
+		},
+	}
+	for _, tc := range tests {
+		seq, _, err := scanner.Scan(tc.rule, true, tc.pos)
+		require.NoError(t, err, tc.rule)
+		rg, err := seq.ToRegex()
+		require.NoError(t, err, tc.rule)
+		re, err := regexp.Compile(rg)
+		require.NoError(t, err, rg)
+		for _, msg := range tc.match {
+			require.True(t, re.MatchString(msg), "%s does not match %s", rg, msg)
+		}
+		for _, msg := range tc.nomatch {
+			require.False(t, re.MatchString(msg), "%s matches %s", rg, msg)
+		}
+		m := re.FindStringSubmatch(tc.match[0])
+		require.Equal(t, tc.group, m[re.SubexpIndex(tc.groupName)], rg)
+	}
+}
+
+func TestPcreToRegex(t *testing.T) {
+	tests := []struct {
+		pcre, rg string
+	}{
+		{`(?>\d\d){1,2}`, `(?i:(?:\d\d){1,2})`},
+		{`a\sb(?![0-9])`, `(?i:a\s+b)`},
+		{`\s(?!<[0-9])(?:2[0123]|[01]?[0-9])`, `(?i:\s+(?:2[0123]|[01]?[0-9]))`},
+		{`x(?<=(a|[)]))y\s+z`, `(?i:xy\s+z)`},
+		{`(?<name`, ``},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.rg, pcreToRegex(tc.pcre), tc.pcre)
+	}
+	require.Equal(t, `a\sb`, PCREToRegex(`a\sb(?![0-9])`))
+}
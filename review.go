@@ -0,0 +1,286 @@
+package sequence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ryanfaircloth/sequence-RTG/sequence/models"
+	"github.com/volatiletech/sqlboiler/boil"
+)
+
+//The states of the pattern review workflow, a pattern without a review is new.
+const (
+	ReviewStateNew        = "new"
+	ReviewStateReview     = "review"
+	ReviewStateApproved   = "approved"
+	ReviewStateDeprecated = "deprecated"
+	ReviewStateIgnored    = "ignored"
+)
+
+//The states each state can move to, new patterns can be promoted straight away
+//and a deprecated pattern can be approved again, ignored is final.
+var reviewTransitions = map[string][]string{
+	ReviewStateNew:        {ReviewStateReview, ReviewStateApproved, ReviewStateIgnored},
+	ReviewStateReview:     {ReviewStateApproved, ReviewStateIgnored},
+	ReviewStateApproved:   {ReviewStateDeprecated, ReviewStateIgnored},
+	ReviewStateDeprecated: {ReviewStateApproved, ReviewStateIgnored},
+	ReviewStateIgnored:    {},
+}
+
+//The current review state of a pattern.
+type PatternReview struct {
+	PatternId   string
+	Service     string
+	Pattern     string
+	State       string
+	Reviewer    string
+	Comment     string
+	DateUpdated time.Time
+}
+
+//A change of the review state of a pattern, as recorded in the history.
+type PatternReviewChange struct {
+	PatternId   string
+	FromState   string
+	ToState     string
+	Reviewer    string
+	Comment     string
+	DateChanged time.Time
+}
+
+//Returns true if a pattern in the from state can be moved to the to state.
+func canTransition(from string, to string) bool {
+	for _, s := range reviewTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+//Returns the review state of a pattern from its review record, a pattern ignored
+//with updateignorepatterns or the API is ignored whatever its recorded state.
+func reviewState(state sql.NullString, ignored bool) string {
+	switch {
+	case ignored:
+		return ReviewStateIgnored
+	case state.Valid:
+		return state.String
+	}
+	return ReviewStateNew
+}
+
+//The raw queries are written with ? placeholders and double quoted table names,
+//this replaces them with those of the configured database.
+func rebind(query string) string {
+	return rebindFor(config.databaseType, query)
+}
+
+//Replaces the ? placeholders and the double quoted identifiers with those of the
+//database type.
+func rebindFor(dbtype string, query string) string {
+	var prefix string
+	switch dbtype {
+	case "postgres", "psql":
+		prefix = "$"
+	case "mssql", "sqlserver":
+		prefix = "@p"
+	}
+	var sb strings.Builder
+	n := 0
+	for i := 0; i < len(query); i++ {
+		switch {
+		case query[i] == '?' && prefix != "":
+			n++
+			sb.WriteString(prefix + strconv.Itoa(n))
+			continue
+		case query[i] == '"':
+			if end := strings.IndexByte(query[i+1:], '"'); end >= 0 {
+				sb.WriteString(quoteIdent(dbtype, query[i+1:i+1+end]))
+				i += end + 1
+				continue
+			}
+		}
+		sb.WriteByte(query[i])
+	}
+	return sb.String()
+}
+
+//Quotes the table or column name for the database type. The postgres tables are
+//created with quoted, case sensitive names and the mysql tables in lower case.
+func quoteIdent(dbtype string, name string) string {
+	switch dbtype {
+	case "postgres", "psql":
+		return `"` + name + `"`
+	case "mssql", "sqlserver":
+		return "[" + name + "]"
+	case "mysql":
+		return "`" + strings.ToLower(name) + "`"
+	}
+	return `"` + name + `"`
+}
+
+//Returns the review state of every pattern in the database with the service name
+//and the pattern, ordered by service and pattern id.
+func GetPatternReviews(db *sql.DB, ctx context.Context) ([]PatternReview, error) {
+	rows, err := db.QueryContext(ctx, rebind(`SELECT p.id, s.name, p.sequence_pattern, p.ignore_pattern, r.state, r.reviewer, r.comment, r.date_updated
+		FROM "Patterns" p INNER JOIN "Services" s ON s.id = p.service_id LEFT JOIN "PatternReviews" r ON r.pattern_id = p.id
+		ORDER BY s.name, p.id`))
+	if err != nil {
+		logger.DatabaseSelectFailed("pattern reviews", "All", err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+	var reviews []PatternReview
+	for rows.Next() {
+		var (
+			pr                       PatternReview
+			ignored                  bool
+			state, reviewer, comment sql.NullString
+			updated                  sql.NullTime
+		)
+		if err = rows.Scan(&pr.PatternId, &pr.Service, &pr.Pattern, &ignored, &state, &reviewer, &comment, &updated); err != nil {
+			logger.DatabaseSelectFailed("pattern reviews", "All", err.Error())
+			return nil, err
+		}
+		pr.State = reviewState(state, ignored)
+		pr.Reviewer = reviewer.String
+		pr.Comment = comment.String
+		pr.DateUpdated = updated.Time
+		reviews = append(reviews, pr)
+	}
+	return reviews, rows.Err()
+}
+
+//Returns the review state of each pattern keyed by the pattern id.
+func GetPatternReviewStates(db *sql.DB, ctx context.Context) (map[string]string, error) {
+	reviews, err := GetPatternReviews(db, ctx)
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]string, len(reviews))
+	for _, pr := range reviews {
+		states[pr.PatternId] = pr.State
+	}
+	return states, nil
+}
+
+//Returns the changes to the review state of the pattern, oldest first.
+func GetPatternReviewHistory(patternid string) ([]PatternReviewChange, error) {
+	db, ctx := OpenDbandSetContext()
+	defer db.Close()
+	rows, err := db.QueryContext(ctx, rebind(`SELECT pattern_id, from_state, to_state, reviewer, comment, date_changed
+		FROM "PatternReviewHistory" WHERE pattern_id = ? ORDER BY date_changed`), patternid)
+	if err != nil {
+		logger.DatabaseSelectFailed("pattern review history", patternid, err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []PatternReviewChange
+	for rows.Next() {
+		var (
+			c       PatternReviewChange
+			comment sql.NullString
+		)
+		if err = rows.Scan(&c.PatternId, &c.FromState, &c.ToState, &c.Reviewer, &comment, &c.DateChanged); err != nil {
+			logger.DatabaseSelectFailed("pattern review history", patternid, err.Error())
+			return nil, err
+		}
+		c.Comment = comment.String
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+//Moves the pattern to the review state and records the change in the history.
+//The error is sql.ErrNoRows if the pattern does not exist. Ignoring a pattern
+//also sets its ignore flag, so it is no longer exported.
+func ReviewPattern(patternid string, state string, reviewer string, comment string) error {
+	db, ctx := OpenDbandSetContext()
+	defer db.Close()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	serviceid, err := reviewPattern(ctx, tx, patternid, state, reviewer, comment)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	if serviceid != "" {
+		InvalidateParserCache(serviceid)
+	}
+	return nil
+}
+
+//Returns the id of the service of the pattern if its ignore flag was set, the parser cache
+//of the service is invalidated by the caller once the transaction is committed.
+func reviewPattern(ctx context.Context, tx *sql.Tx, patternid string, state string, reviewer string, comment string) (string, error) {
+	p, err := models.FindPattern(ctx, tx, patternid)
+	if err != nil {
+		logger.DatabaseSelectFailed("pattern", patternid, err.Error())
+		return "", err
+	}
+	var current sql.NullString
+	err = tx.QueryRowContext(ctx, rebind(`SELECT state FROM "PatternReviews" WHERE pattern_id = ?`), patternid).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		logger.DatabaseSelectFailed("pattern review", patternid, err.Error())
+		return "", err
+	}
+	from := reviewState(current, p.IgnorePattern)
+	if !canTransition(from, state) {
+		return "", fmt.Errorf("The pattern %s cannot be moved from %s to %s", patternid, from, state)
+	}
+
+	now := time.Now()
+	if current.Valid {
+		_, err = tx.ExecContext(ctx, rebind(`UPDATE "PatternReviews" SET state = ?, reviewer = ?, comment = ?, date_updated = ? WHERE pattern_id = ?`),
+			state, reviewer, comment, now, patternid)
+	} else {
+		_, err = tx.ExecContext(ctx, rebind(`INSERT INTO "PatternReviews" (pattern_id, state, reviewer, comment, date_updated) VALUES (?, ?, ?, ?, ?)`),
+			patternid, state, reviewer, comment, now)
+	}
+	if err != nil {
+		logger.DatabaseUpdateFailed("pattern review", patternid, err.Error())
+		return "", err
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	_, err = tx.ExecContext(ctx, rebind(`INSERT INTO "PatternReviewHistory" (id, pattern_id, from_state, to_state, reviewer, comment, date_changed) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		id.String(), patternid, from, state, reviewer, comment, now)
+	if err != nil {
+		logger.DatabaseInsertFailed("pattern review history", patternid, err.Error())
+		return "", err
+	}
+
+	if state == ReviewStateIgnored && !p.IgnorePattern {
+		p.IgnorePattern = true
+		if _, err = p.Update(ctx, tx, boil.Infer()); err != nil {
+			logger.DatabaseUpdateFailed("pattern", patternid, err.Error())
+			return "", err
+		}
+		return p.ServiceID, nil
+	}
+	return "", nil
+}
+
+//Returns only the patterns in the review state.
+func FilterPatternsByReviewState(pmap map[string]AnalyzerResult, states map[string]string, state string) map[string]AnalyzerResult {
+	fmap := make(map[string]AnalyzerResult)
+	for id, ar := range pmap {
+		if st, ok := states[id]; ok && st == state {
+			fmap[id] = ar
+		}
+	}
+	return fmap
+}
@@ -0,0 +1,68 @@
+package sequence
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		ok       bool
+	}{
+		{ReviewStateNew, ReviewStateReview, true},
+		{ReviewStateNew, ReviewStateApproved, true},
+		{ReviewStateNew, ReviewStateIgnored, true},
+		{ReviewStateNew, ReviewStateDeprecated, false},
+		{ReviewStateReview, ReviewStateApproved, true},
+		{ReviewStateReview, ReviewStateNew, false},
+		{ReviewStateApproved, ReviewStateDeprecated, true},
+		{ReviewStateApproved, ReviewStateApproved, false},
+		{ReviewStateDeprecated, ReviewStateApproved, true},
+		{ReviewStateIgnored, ReviewStateApproved, false},
+		{"unknown", ReviewStateApproved, false},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.ok, canTransition(tc.from, tc.to), "%s -> %s", tc.from, tc.to)
+	}
+}
+
+func TestReviewState(t *testing.T) {
+	require.Equal(t, ReviewStateNew, reviewState(sql.NullString{}, false))
+	require.Equal(t, ReviewStateIgnored, reviewState(sql.NullString{}, true))
+	require.Equal(t, ReviewStateApproved, reviewState(sql.NullString{String: ReviewStateApproved, Valid: true}, false))
+	require.Equal(t, ReviewStateIgnored, reviewState(sql.NullString{String: ReviewStateApproved, Valid: true}, true))
+}
+
+func TestRebind(t *testing.T) {
+	query := `UPDATE "PatternReviews" SET state = ? WHERE pattern_id = ?`
+	tests := []struct {
+		dbtype, query string
+	}{
+		{"sqlite3", query},
+		{"mysql", "UPDATE `patternreviews` SET state = ? WHERE pattern_id = ?"},
+		{"postgres", `UPDATE "PatternReviews" SET state = $1 WHERE pattern_id = $2`},
+		{"sqlserver", "UPDATE [PatternReviews] SET state = @p1 WHERE pattern_id = @p2"},
+	}
+	dbtype := config.databaseType
+	defer func() { config.databaseType = dbtype }()
+	for _, tc := range tests {
+		config.databaseType = tc.dbtype
+		require.Equal(t, tc.query, rebind(query), tc.dbtype)
+	}
+}
+
+func TestFilterPatternsByReviewState(t *testing.T) {
+	pmap := map[string]AnalyzerResult{
+		"a": {PatternId: "a"},
+		"b": {PatternId: "b"},
+		"c": {PatternId: "c"},
+	}
+	states := map[string]string{"a": ReviewStateApproved, "b": ReviewStateNew}
+	fmap := FilterPatternsByReviewState(pmap, states, ReviewStateApproved)
+	require.Len(t, fmap, 1)
+	require.Contains(t, fmap, "a")
+	require.Empty(t, FilterPatternsByReviewState(pmap, states, ReviewStateDeprecated))
+}
@@ -83,6 +83,137 @@ func TestMessageScanHexString(t *testing.T) {
 	}
 }
 
+func TestScannerScanIdentifiers(t *testing.T) {
+	var tests = []struct {
+		data string
+		tt   TokenType
+	}{
+		{"550e8400-e29b-41d4-a716-446655440000", TokenUUID},
+		{"550E8400-E29B-41D4-A716-446655440000.", TokenUUID},
+		{"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", TokenHex},
+		{"0x1f", TokenLiteral},
+		{"deadbeefdeadbeef", TokenLiteral},
+		{"dGhpcyBpcyBhIHNlc3Npb24gdG9rZW4=", TokenBase64},
+		{"eyJhbGciOiJIUzI1NiJ9", TokenBase64},
+		{"ConnectionTimeout2021Exceeded", TokenLiteral},
+		{"/usr/lib/x86_64/libssl3", TokenLiteral},
+		{"session2id=dGhpcyBpcyBhIHNlc3Npb24", TokenLiteral},
+	}
+	scanner := NewScanner()
+	for _, tc := range tests {
+		seq, _, err := scanner.Scan(tc.data, false, nil)
+		require.NoError(t, err, tc.data)
+		require.Equal(t, tc.tt, seq[0].Type, tc.data)
+		if tc.tt != TokenLiteral {
+			require.Equal(t, strings.TrimSuffix(tc.data, "."), seq[0].Value, tc.data)
+		}
+	}
+}
+
+func TestScannerScanAddresses(t *testing.T) {
+	var tests = []struct {
+		data  string
+		types []string
+	}{
+		{"10.0.0.0/8", []string{"ipv4cidr=10.0.0.0/8"}},
+		{"2001:db8::/32.", []string{"ipv6cidr=2001:db8::/32", "literal=."}},
+		{"fe80::1%eth0,", []string{"ipv6=fe80::1%eth0", "literal=,"}},
+		{"10.1.2.3:22", []string{"hostport=10.1.2.3:22"}},
+		{"[fe80::1%eth0]:443", []string{"hostport=[fe80::1%eth0]:443"}},
+		{"db.example.com:5432", []string{"hostport=db.example.com:5432"}},
+		//an address with host bits or a port that is too big is still split
+		{"10.1.2.3/22", []string{"ipv4=10.1.2.3", "literal=/", "integer=22"}},
+		{"10.1.2.3:70000", []string{"ipv4=10.1.2.3", "literal=:", "integer=70000"}},
+		{"inside:10.1.2.3/22", []string{"literal=inside", "literal=:", "ipv4=10.1.2.3", "literal=/", "integer=22"}},
+		{"00:1a:2b:3c:4d:5e", []string{"mac=00:1a:2b:3c:4d:5e"}},
+	}
+	scanner := NewScanner()
+	for _, tc := range tests {
+		seq, _, err := scanner.Scan(tc.data, false, nil)
+		require.NoError(t, err, tc.data)
+		var types []string
+		for _, tok := range seq {
+			types = append(types, tok.Type.String()+"="+tok.Value)
+		}
+		require.Equal(t, tc.types, types, tc.data)
+	}
+}
+
+func setTimeSettings(t *testing.T, year int, timezone string) {
+	saved := timesettings
+	t.Cleanup(func() { timesettings = saved })
+	require.NoError(t, validateTimeSettingsConfig(year, timezone))
+}
+
+func TestScannerScanTimes(t *testing.T) {
+	var tests = []struct {
+		data   string
+		types  []string
+		layout string
+	}{
+		{"2021-02-28 10:00:00 ok", []string{"time=2021-02-28 10:00:00", "literal=ok"}, "2006-01-02 15:04:05"},
+		{"Feb 29 10:00:00 ok", []string{"time=Feb 29 10:00:00", "literal=ok"}, "Jan _2 15:04:05"},
+		{"2014-08-16T12:45:03-0400", []string{"time=2014-08-16T12:45:03-0400"}, "2006-01-02T15:04:05-0700"},
+		//the first format of the same shape is the layout
+		{"Jan 02 10:00:00 ok", []string{"time=Jan 02 10:00:00", "literal=ok"}, "Jan _2 15:04:05"},
+		{"Tue Feb 02 10:00:00 CST 2021", []string{"time=Tue Feb 02 10:00:00 CST 2021"}, "Mon Jan _2 15:04:05 MST 2006"},
+		//the dates that can not be are not times
+		{"Feb 30 10:00:00", []string{"literal=Feb", "integer=30", "time=10:00:00"}, "15:04:05"},
+		{"2021-04-31", []string{"literal=2021-04-31"}, ""},
+		{"2021-02-28 25:00:00", []string{"literal=2021-02-28", "literal=25:00:00"}, ""},
+	}
+	scanner := NewScanner()
+	for _, tc := range tests {
+		seq, _, err := scanner.Scan(tc.data, false, nil)
+		require.NoError(t, err, tc.data)
+		var (
+			types  []string
+			layout string
+		)
+		for _, tok := range seq {
+			types = append(types, tok.Type.String()+"="+tok.Value)
+			if tok.Type == TokenTime {
+				layout = tok.Layout
+			}
+		}
+		require.Equal(t, tc.types, types, tc.data)
+		require.Equal(t, tc.layout, layout, tc.data)
+	}
+}
+
+func TestNormalizeTime(t *testing.T) {
+	setTimeSettings(t, 2024, "Europe/Paris")
+	var tests = []struct {
+		value, layout, rfc3339 string
+	}{
+		{"Jan 12 06:49:41", "Jan _2 15:04:05", "2024-01-12T06:49:41+01:00"},
+		{"may  2 19:00:02", "Jan _2 15:04:05", "2024-05-02T19:00:02+02:00"},
+		{"Feb 29 10:00:00", "Jan _2 15:04:05", "2024-02-29T10:00:00+01:00"},
+		{"mar 01 09:42:03.875", "Jan _2 15:04:05.000", "2024-03-01T09:42:03.875+01:00"},
+		{"16/Jan/2003:21:22:59 -0500", "_2/Jan/2006:15:04:05 -0700", "2003-01-16T21:22:59-05:00"},
+		{"2014-02-15T23:39:43.945958Z", "2006-01-02T15:04:05.999999Z", "2014-02-15T23:39:43.945958Z"},
+		{"2012-04-05 17:51:26", "2006-01-02 15:04:05", "2012-04-05T17:51:26+02:00"},
+		{"4/5/2012 17:55", "1/2/2006 15:04", "2012-04-05T17:55:00+02:00"},
+	}
+	for _, tc := range tests {
+		v, err := NormalizeTime(tc.value, tc.layout)
+		require.NoError(t, err, tc.value)
+		require.Equal(t, tc.rfc3339, v, tc.value)
+	}
+	//a time of day has no date and Feb 29 is not a date in the default year
+	_, err := NormalizeTime("10:00:00", "15:04:05")
+	require.Error(t, err)
+	setTimeSettings(t, 2023, "")
+	_, err = NormalizeTime("Feb 29 10:00:00", "Jan _2 15:04:05")
+	require.Error(t, err)
+	v, err := NormalizeTime("Feb 28 10:00:00", "Jan _2 15:04:05")
+	require.NoError(t, err)
+	require.Equal(t, "2023-02-28T10:00:00Z", v)
+
+	require.Error(t, validateTimeSettingsConfig(-1, ""))
+	require.Error(t, validateTimeSettingsConfig(0, "Mars/Olympus_Mons"))
+}
+
 func TestScannerSignature(t *testing.T) {
 	scanner := NewScanner()
 	var pos []int
@@ -335,7 +466,7 @@ var (
 		{
 			"general",
 			"Jan 12 06:49:41 irc sshd[7034]: pam_unix(sshd:auth): authentication failure; logname= uid=0 euid=0 tty=ssh ruser= rhost=218-161-81-238.hinet-ip.hinet.net  user=root", Sequence{
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "Jan 12 06:49:41", Special: "1"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "Jan 12 06:49:41", Layout: "Jan _2 15:04:05", Special: "1"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "irc"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "sshd"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "["},
@@ -377,7 +508,7 @@ var (
 		{
 			"general",
 			"Jan 12 06:49:42 irc sshd[7034]: Failed password for root from 218.161.81.238 port 4228 ssh2", Sequence{
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "Jan 12 06:49:42", Special: "1"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "Jan 12 06:49:42", Layout: "Jan _2 15:04:05", Special: "1"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "irc"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "sshd"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "["},
@@ -401,7 +532,7 @@ var (
 		{
 			"general",
 			"Jan 12 06:49:56 irc last message repeated 6 times", Sequence{
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "Jan 12 06:49:56", Special: "1"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "Jan 12 06:49:56", Layout: "Jan _2 15:04:05", Special: "1"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "irc"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "last"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "message"},
@@ -418,7 +549,7 @@ var (
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "["},
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "16/Jan/2003:21:22:59 -0500", Special: "5"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "16/Jan/2003:21:22:59 -0500", Layout: "_2/Jan/2006:15:04:05 -0700", Special: "5"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "]"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "\""},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "GET"},
@@ -437,7 +568,7 @@ var (
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "["},
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "16/Jan/2003:21:22:59 -0500", Special: "5"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "16/Jan/2003:21:22:59 -0500", Layout: "_2/Jan/2006:15:04:05 -0700", Special: "5"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "]"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "\""},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "GET"},
@@ -456,7 +587,7 @@ var (
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "["},
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "03/May/2004:01:19:07 +0000", Special: "5"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "03/May/2004:01:19:07 +0000", Layout: "_2/Jan/2006:15:04:05 -0700", Special: "5"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "]"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "\""},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "GET"},
@@ -471,7 +602,7 @@ var (
 		{
 			"general",
 			"4/5/2012 17:55,172.23.1.101,1101,172.23.0.10,139, Generic Protocol Command Decode,3, [1:2100538:17] GPL NETBIOS SMB IPC$ unicode share access ,TCP TTL:128 TOS:0x0 ID:1643 IpLen:20 DgmLen:122 DF,***AP*** Seq: 0xCEF93F32  Ack: 0xC40C0BB  n: 0xFC9C  TcpLen: 20,", Sequence{
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "4/5/2012 17:55", Special: "99"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "4/5/2012 17:55", Layout: "1/2/2006 15:04", Special: "99"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: ","},
 				Token{Type: TokenIPv4, Tag: TagUnknown, Value: "172.23.1.101"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: ","},
@@ -541,7 +672,7 @@ var (
 		{
 			"general",
 			"2012-04-05 17:51:26     Local4.Info     172.23.0.1      %ASA-6-302016: Teardown UDP connection 1315632 for inside:172.23.0.2/514 to identity:172.23.0.1/514 duration 0:09:23 bytes 7999", Sequence{
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "2012-04-05 17:51:26"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "2012-04-05 17:51:26", Layout: "2006-01-02 15:04:05"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "Local4.Info"},
 				Token{Type: TokenIPv4, Tag: TagUnknown, Value: "172.23.0.1"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "%ASA-6-302016"},
@@ -563,7 +694,7 @@ var (
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "/"},
 				Token{Type: TokenInteger, Tag: TagUnknown, Value: "514"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "duration"},
-				Token{Type: TokenTime, Tag: TagUnknown, Value: "0:09:23"},
+				Token{Type: TokenTime, Tag: TagUnknown, Value: "0:09:23", Layout: "3:04:05"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "bytes"},
 				Token{Type: TokenInteger, Tag: TagUnknown, Value: "7999"},
 			},
@@ -572,7 +703,7 @@ var (
 		{
 			"general",
 			"2012-04-05 17:54:47     Local4.Info     172.23.0.1      %ASA-6-302015: Built outbound UDP connection 1315679 for outside:193.0.14.129/53 (193.0.14.129/53) to inside:172.23.0.10/64048 (10.32.0.1/52130)", Sequence{
-				Token{Type: TokenTime, Tag: TagUnknown, Value: "2012-04-05 17:54:47"},
+				Token{Type: TokenTime, Tag: TagUnknown, Value: "2012-04-05 17:54:47", Layout: "2006-01-02 15:04:05"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "Local4.Info"},
 				Token{Type: TokenIPv4, Tag: TagUnknown, Value: "172.23.0.1"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "%ASA-6-302015"},
@@ -616,7 +747,7 @@ var (
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "time"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "="},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "\""},
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "2005-03-18 14:01:43", Special: "4"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "2005-03-18 14:01:43", Layout: "2006-01-02 15:04:05", Special: "4"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "\""},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "fw"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "="},
@@ -663,7 +794,7 @@ var (
 		{
 			"general",
 			"mar 01 09:42:03.875 pffbisvr smtp[2424]: 334 warning: denied access to command 'ehlo vishwakstg1.msn.vishwak.net' from [209.235.210.30]", Sequence{
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "mar 01 09:42:03.875", Special: "1"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "mar 01 09:42:03.875", Layout: "Jan _2 15:04:05.000", Special: "1"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "pffbisvr"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "smtp"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "["},
@@ -690,7 +821,7 @@ var (
 		{
 			"general",
 			"may  2 19:00:02 dlfssrv sendmail[18980]: taa18980: from user daemon: size is 596, class is 0, priority is 30596, and nrcpts=1, message id is <200305021400.taa18980@dlfssrv.in.ibm.com>, relay=daemon@localhost", Sequence{
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "may  2 19:00:02", Special: "1"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "may  2 19:00:02", Layout: "Jan _2 15:04:05", Special: "1"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "dlfssrv"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "sendmail"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "["},
@@ -736,7 +867,7 @@ var (
 		{
 			"general",
 			"mar 01 09:45:02.596 pffbisvr smtp[2424]: 121 statistics: duration=181.14 user=<egreetings@vishwak.com> id=zduqd sent=1440 rcvd=356 srcif=d45f49a2-b30 src=209.235.210.30/61663 cldst=192.216.179.206/25 svsrc=172.17.74.195/8423 dstif=fd3c875c-064 dst=172.17.74.52/25 op=\"to 1 recips\" arg=<vishwakstg1ojte15fo000033b4@vishwakstg1.msn.vishwak.net> result=\"250 m2004030109385301402 message accepted for delivery\" proto=smtp rule=131 (denied access to command 'ehlo vishwakstg1.msn.vishwak.net' from [209.235.210.30])", Sequence{
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "mar 01 09:45:02.596", Special: "1"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "mar 01 09:45:02.596", Layout: "Jan _2 15:04:05.000", Special: "1"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "pffbisvr"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "smtp"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "["},
@@ -829,7 +960,7 @@ var (
 		{
 			"general",
 			"2015-02-11 11:04:40 H=(amoricanexpress.com) [64.20.195.132]:10246 F=<fxC4480@amoricanexpress.com> rejected RCPT <SCRUBBED@SCRUBBED.com>: Sender verify failed", Sequence{
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2015-02-11 11:04:40", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2015-02-11 11:04:40", Layout: "2006-01-02 15:04:05", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "H", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "(", isKey: false, isValue: false},
@@ -860,7 +991,7 @@ var (
 		{
 			"general",
 			"Jan 31 21:42:59 mail postfix/anvil[14606]: statistics: max connection rate 1/60s for (smtp:5.5.5.5) at Jan 31 21:39:37", Sequence{
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:42:59", isKey: false, isValue: false, Special: "1"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:42:59", Layout: "Jan _2 15:04:05", isKey: false, isValue: false, Special: "1"},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "mail", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "postfix/anvil", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false},
@@ -880,14 +1011,14 @@ var (
 				Token{Tag: TagUnknown, Type: TokenIPv4, Value: "5.5.5.5", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: ")", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "at", isKey: false, isValue: false},
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:39:37", isKey: false, isValue: false, Special: "1"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:39:37", Layout: "Jan _2 15:04:05", isKey: false, isValue: false, Special: "1"},
 			},
 		},
 
 		{
 			"general",
 			"Jan 31 21:42:59 mail postfix/anvil[14606]: statistics: max connection count 1 for (smtp:5.5.5.5) at Jan 31 21:39:37", Sequence{
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:42:59", isKey: false, isValue: false, Special: "1"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:42:59", Layout: "Jan _2 15:04:05", isKey: false, isValue: false, Special: "1"},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "mail", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "postfix/anvil", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false},
@@ -907,14 +1038,14 @@ var (
 				Token{Tag: TagUnknown, Type: TokenIPv4, Value: "5.5.5.5", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: ")", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "at", isKey: false, isValue: false},
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:39:37", isKey: false, isValue: false, Special: "1"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:39:37", Layout: "Jan _2 15:04:05", isKey: false, isValue: false, Special: "1"},
 			},
 		},
 
 		{
 			"general",
 			"Jan 31 21:42:59 mail postfix/anvil[14606]: statistics: max cache size 1 at Jan 31 21:39:37", Sequence{
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:42:59", isKey: false, isValue: false, Special: "1"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:42:59", Layout: "Jan _2 15:04:05", isKey: false, isValue: false, Special: "1"},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "mail", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "postfix/anvil", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false},
@@ -928,7 +1059,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "size", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenInteger, Value: "1", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "at", isKey: false, isValue: false},
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:39:37", isKey: false, isValue: false, Special: "1"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:39:37", Layout: "Jan _2 15:04:05", isKey: false, isValue: false, Special: "1"},
 			},
 		},
 
@@ -936,7 +1067,7 @@ var (
 		{
 			"general",
 			"Feb 06 13:37:00 box sshd[4388]: Accepted publickey for cryptix from dead:beef:1234:5678:223:32ff:feb1:2e50 port 58251 ssh2: RSA de:ad:be:ef:74:a6:bb:45:45:52:71:de:b2:12:34:56", Sequence{
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Feb 06 13:37:00", isKey: false, isValue: false, Special: "1"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Feb 06 13:37:00", Layout: "Jan _2 15:04:05", isKey: false, isValue: false, Special: "1"},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "box", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "sshd", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false},
@@ -962,7 +1093,7 @@ var (
 		{
 			"general",
 			"2015-01-21 21:41:27 4515 [Note] - '::' resolves to '::';", Sequence{
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2015-01-21 21:41:27", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2015-01-21 21:41:27", Layout: "2006-01-02 15:04:05", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenInteger, Value: "4515", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "Note", isKey: false, isValue: false},
@@ -984,7 +1115,7 @@ var (
 		{
 			"general",
 			"2015-01-21 21:41:27 4515 [Note] Server socket created on IP: '::'.", Sequence{
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2015-01-21 21:41:27", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2015-01-21 21:41:27", Layout: "2006-01-02 15:04:05", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenInteger, Value: "4515", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "Note", isKey: false, isValue: false},
@@ -1009,7 +1140,7 @@ var (
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "["},
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "16/Jan/2003:21:22:59 -0500", Special: "5"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "16/Jan/2003:21:22:59 -0500", Layout: "_2/Jan/2006:15:04:05 -0700", Special: "5"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "]"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "\""},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "GET"},
@@ -1028,7 +1159,7 @@ var (
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "["},
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "03/May/2004:01:19:07 +0000", Special: "5"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "03/May/2004:01:19:07 +0000", Layout: "_2/Jan/2006:15:04:05 -0700", Special: "5"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "]"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "\""},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "GET"},
@@ -1047,7 +1178,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "-", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "-", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false},
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "12/Jul/2013:15:56:54 +0000", isKey: false, isValue: false, Special: "5"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "12/Jul/2013:15:56:54 +0000", Layout: "_2/Jan/2006:15:04:05 -0700", isKey: false, isValue: false, Special: "5"},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "]", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "GET", isKey: false, isValue: false},
@@ -1066,7 +1197,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "Chef Client/0.10.2 (ruby-1.8.7-p302; ohai-0.6.4; x86_64-linux; +http://opscode.com)", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "127.0.0.1:9460", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenHostPort, Value: "127.0.0.1:9460", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenInteger, Value: "200", isKey: false, isValue: false},
@@ -1084,10 +1215,10 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "some_node.example.com", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2013-07-12T15:56:40Z", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2013-07-12T15:56:40Z", Layout: "2006-01-02T15:04:05Z", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "2jmj7l5rSw0yVb/vlWAYkK/YBwk=", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenBase64, Value: "2jmj7l5rSw0yVb/vlWAYkK/YBwk=", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenInteger, Value: "985", isKey: false, isValue: false},
 			},
@@ -1100,7 +1231,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "-", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "-", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false},
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "03/May/2004:01:00:04 +0000", isKey: false, isValue: false, Special: "5"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "03/May/2004:01:00:04 +0000", Layout: "_2/Jan/2006:15:04:05 -0700", isKey: false, isValue: false, Special: "5"},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "]", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "GET", isKey: false, isValue: false},
@@ -1115,14 +1246,10 @@ var (
 		{
 			"general",
 			`2014-02-15T23:39:43.945958Z my-test-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/HTTP/1.1"`, Sequence{
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-02-15T23:39:43.945958Z", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-02-15T23:39:43.945958Z", Layout: "2006-01-02T15:04:05.999999Z", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "my-test-loadbalancer", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenIPv4, Value: "192.168.131.39", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenLiteral, Value: ":", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenInteger, Value: "2817", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenIPv4, Value: "10.0.0.1", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenLiteral, Value: ":", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenInteger, Value: "80", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenHostPort, Value: "192.168.131.39:2817", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenHostPort, Value: "10.0.0.1:80", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenFloat, Value: "0.000073", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenFloat, Value: "0.001048", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenFloat, Value: "0.000057", isKey: false, isValue: false},
@@ -1142,7 +1269,7 @@ var (
 			"general",
 			"Feb 06 15:56:09 higgs sshd[902]: Server listening on 0.0.0.0 port 22.",
 			Sequence{
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Feb 06 15:56:09", isKey: false, isValue: false, Special: "1"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Feb 06 15:56:09", Layout: "Jan _2 15:04:05", isKey: false, isValue: false, Special: "1"},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "higgs", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "sshd", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false},
@@ -1247,22 +1374,22 @@ var (
 			`{"EventTime":"2014-08-16T12:45:03-0400","URI":"myuri","uri_payload":{"value":[{"open":"2014-08-16T13:00:00.000+0000","close":"2014-08-16T23:00:00.000+0000","isOpen":true,"date":"2014-08-16"}],"Count":1}}`, Sequence{
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "EventTime", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16T12:45:03-0400", isKey: false, isValue: true},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16T12:45:03-0400", Layout: "2006-01-02T15:04:05-0700", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "URI", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "myuri", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "uri_payload.value.0.open", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16T13:00:00.000+0000", isKey: false, isValue: true},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16T13:00:00.000+0000", Layout: "2006-01-02T15:04:05.999-0700", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "uri_payload.value.0.close", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16T23:00:00.000+0000", isKey: false, isValue: true},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16T23:00:00.000+0000", Layout: "2006-01-02T15:04:05.999-0700", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "uri_payload.value.0.isOpen", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "true", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "uri_payload.value.0.date", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16", isKey: false, isValue: true},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16", Layout: "2006-01-02", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "uri_payload.Count", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenInteger, Value: "1", isKey: false, isValue: true},
@@ -1297,7 +1424,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "Alice", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "eventTime", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-03-06T21:22:54Z", isKey: false, isValue: true},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-03-06T21:22:54Z", Layout: "2006-01-02T15:04:05Z", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "eventSource", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "ec2.amazonaws.com", isKey: false, isValue: true},
@@ -1360,7 +1487,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "rhendriks", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "eventTime", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-01-31T12:00:00Z", isKey: false, isValue: true},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-01-31T12:00:00Z", Layout: "2006-01-02T15:04:05Z", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "eventSource", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "ec2.amazonaws.com", isKey: false, isValue: true},
@@ -1405,7 +1532,7 @@ var (
 			`{"Version": "2012-10-17", "Statement": [{"Sid": "Put bucket policy needed for audit logging", "Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::193672423079:user/logs"}, "Action": "s3:PutObject", "Resource": "arn:aws:s3:::AuditLogs/*"}, {"Sid": "Get bucket policy needed for audit logging", "Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::193672423079:user/logs"}, "Action": "s3:GetBucketAcl", "Resource": "arn:aws:s3:::AuditLogs"} ] }`, Sequence{
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "Version", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2012-10-17", isKey: false, isValue: true},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2012-10-17", Layout: "2006-01-02", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "Statement.0.Sid", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "Put bucket policy needed for audit logging", isKey: false, isValue: true},
@@ -1443,7 +1570,7 @@ var (
 		{
 			"general",
 			"Jan 12 06:49:41 irc sshd[7034]: pam_unix(sshd:auth): authentication failure; logname= uid=0 euid=0 tty=ssh ruser= rhost=218-161-81-238.hinet-ip.hinet.net  user=root", Sequence{
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "Jan 12 06:49:41", Special: "1"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "Jan 12 06:49:41", Layout: "Jan _2 15:04:05", Special: "1"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "irc", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "sshd", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "["},
@@ -1485,7 +1612,7 @@ var (
 		{
 			"general",
 			"Jan 12 06:49:42 irc sshd[7034]: Failed password for root from 218.161.81.238 port 4228 ssh2", Sequence{
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "Jan 12 06:49:42", Special: "1"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "Jan 12 06:49:42", Layout: "Jan _2 15:04:05", Special: "1"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "irc", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "sshd", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "["},
@@ -1509,7 +1636,7 @@ var (
 		{
 			"general",
 			"Jan 12 06:49:56 irc last message repeated 6 times", Sequence{
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "Jan 12 06:49:56", Special: "1"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "Jan 12 06:49:56", Layout: "Jan _2 15:04:05", Special: "1"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "irc", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "last", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "message", IsSpaceBefore: true},
@@ -1526,7 +1653,7 @@ var (
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "[", IsSpaceBefore: true},
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "16/Jan/2003:21:22:59 -0500", Special: "5"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "16/Jan/2003:21:22:59 -0500", Layout: "_2/Jan/2006:15:04:05 -0700", Special: "5"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "]"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "\"", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "GET"},
@@ -1545,7 +1672,7 @@ var (
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "[", IsSpaceBefore: true},
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "16/Jan/2003:21:22:59 -0500", Special: "5"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "16/Jan/2003:21:22:59 -0500", Layout: "_2/Jan/2006:15:04:05 -0700", Special: "5"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "]"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "\"", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "GET"},
@@ -1564,7 +1691,7 @@ var (
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "[", IsSpaceBefore: true},
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "03/May/2004:01:19:07 +0000", Special: "5"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "03/May/2004:01:19:07 +0000", Layout: "_2/Jan/2006:15:04:05 -0700", Special: "5"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "]"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "\"", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "GET"},
@@ -1579,7 +1706,7 @@ var (
 		{
 			"general",
 			"4/5/2012 17:55,172.23.1.101,1101,172.23.0.10,139, Generic Protocol Command Decode,3, [1:2100538:17] GPL NETBIOS SMB IPC$ unicode share access ,TCP TTL:128 TOS:0x0 ID:1643 IpLen:20 DgmLen:122 DF,***AP*** Seq: 0xCEF93F32  Ack: 0xC40C0BB  n: 0xFC9C  TcpLen: 20,", Sequence{
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "4/5/2012 17:55", Special: "99"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "4/5/2012 17:55", Layout: "1/2/2006 15:04", Special: "99"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: ","},
 				Token{Type: TokenIPv4, Tag: TagUnknown, Value: "172.23.1.101"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: ","},
@@ -1649,7 +1776,7 @@ var (
 		{
 			"general",
 			"2012-04-05 17:51:26     Local4.Info     172.23.0.1      %ASA-6-302016: Teardown UDP connection 1315632 for inside:172.23.0.2/514 to identity:172.23.0.1/514 duration 0:09:23 bytes 7999", Sequence{
-				Token{Type: TokenTime, Tag: TagUnknown, Value: "2012-04-05 17:51:26"},
+				Token{Type: TokenTime, Tag: TagUnknown, Value: "2012-04-05 17:51:26", Layout: "2006-01-02 15:04:05"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "Local4.Info", IsSpaceBefore: true},
 				Token{Type: TokenIPv4, Tag: TagUnknown, Value: "172.23.0.1", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "%ASA-6-302016", IsSpaceBefore: true},
@@ -1671,7 +1798,7 @@ var (
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "/"},
 				Token{Type: TokenInteger, Tag: TagUnknown, Value: "514"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "duration", IsSpaceBefore: true},
-				Token{Type: TokenTime, Tag: TagUnknown, Value: "0:09:23", IsSpaceBefore: true},
+				Token{Type: TokenTime, Tag: TagUnknown, Value: "0:09:23", Layout: "3:04:05", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "bytes", IsSpaceBefore: true},
 				Token{Type: TokenInteger, Tag: TagUnknown, Value: "7999", IsSpaceBefore: true},
 			},
@@ -1680,7 +1807,7 @@ var (
 		{
 			"general",
 			"2012-04-05 17:54:47     Local4.Info     172.23.0.1      %ASA-6-302015: Built outbound UDP connection 1315679 for outside:193.0.14.129/53 (193.0.14.129/53) to inside:172.23.0.10/64048 (10.32.0.1/52130)", Sequence{
-				Token{Type: TokenTime, Tag: TagUnknown, Value: "2012-04-05 17:54:47"},
+				Token{Type: TokenTime, Tag: TagUnknown, Value: "2012-04-05 17:54:47", Layout: "2006-01-02 15:04:05"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "Local4.Info", IsSpaceBefore: true},
 				Token{Type: TokenIPv4, Tag: TagUnknown, Value: "172.23.0.1", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "%ASA-6-302015", IsSpaceBefore: true},
@@ -1724,7 +1851,7 @@ var (
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "time", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "="},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "\""},
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "2005-03-18 14:01:43", Special: "3"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "2005-03-18 14:01:43", Layout: "2006-01-02 15:04:05", Special: "3"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "\""},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "fw", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "="},
@@ -1771,7 +1898,7 @@ var (
 		{
 			"general",
 			"mar 01 09:42:03.875 pffbisvr smtp[2424]: 334 warning: denied access to command 'ehlo vishwakstg1.msn.vishwak.net' from [209.235.210.30]", Sequence{
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "mar 01 09:42:03.875", Special: "1"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "mar 01 09:42:03.875", Layout: "Jan _2 15:04:05.000", Special: "1"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "pffbisvr", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "smtp", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "["},
@@ -1798,7 +1925,7 @@ var (
 		{
 			"general",
 			"may  2 19:00:02 dlfssrv sendmail[18980]: taa18980: from user daemon: size is 596, class is 0, priority is 30596, and nrcpts=1, message id is <200305021400.taa18980@dlfssrv.in.ibm.com>, relay=daemon@localhost", Sequence{
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "may  2 19:00:02", Special: "1"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "may  2 19:00:02", Layout: "Jan _2 15:04:05", Special: "1"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "dlfssrv", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "sendmail", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "["},
@@ -1844,7 +1971,7 @@ var (
 		{
 			"general",
 			"mar 01 09:45:02.596 pffbisvr smtp[2424]: 121 statistics: duration=181.14 user=<egreetings@vishwak.com> id=zduqd sent=1440 rcvd=356 srcif=d45f49a2-b30 src=209.235.210.30/61663 cldst=192.216.179.206/25 svsrc=172.17.74.195/8423 dstif=fd3c875c-064 dst=172.17.74.52/25 op=\"to 1 recips\" arg=<vishwakstg1ojte15fo000033b4@vishwakstg1.msn.vishwak.net> result=\"250 m2004030109385301402 message accepted for delivery\" proto=smtp rule=131 (denied access to command 'ehlo vishwakstg1.msn.vishwak.net' from [209.235.210.30])", Sequence{
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "mar 01 09:45:02.596", Special: "1"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "mar 01 09:45:02.596", Layout: "Jan _2 15:04:05.000", Special: "1"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "pffbisvr", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "smtp", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "["},
@@ -1937,7 +2064,7 @@ var (
 		{
 			"general",
 			"2015-02-11 11:04:40 H=(amoricanexpress.com) [64.20.195.132]:10246 F=<fxC4480@amoricanexpress.com> rejected RCPT <SCRUBBED@SCRUBBED.com>: Sender verify failed", Sequence{
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2015-02-11 11:04:40", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2015-02-11 11:04:40", Layout: "2006-01-02 15:04:05", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "H", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "(", isKey: false, isValue: false},
@@ -1968,7 +2095,7 @@ var (
 		{
 			"general",
 			"Jan 31 21:42:59 mail postfix/anvil[14606]: statistics: max connection rate 1/60s for (smtp:5.5.5.5) at Jan 31 21:39:37", Sequence{
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:42:59", isKey: false, isValue: false, Special: "1"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:42:59", Layout: "Jan _2 15:04:05", isKey: false, isValue: false, Special: "1"},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "mail", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "postfix/anvil", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false},
@@ -1988,14 +2115,14 @@ var (
 				Token{Tag: TagUnknown, Type: TokenIPv4, Value: "5.5.5.5", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: ")", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "at", isKey: false, isValue: false, IsSpaceBefore: true},
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:39:37", isKey: false, isValue: false, IsSpaceBefore: true, Special: "1"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:39:37", Layout: "Jan _2 15:04:05", isKey: false, isValue: false, IsSpaceBefore: true, Special: "1"},
 			},
 		},
 
 		{
 			"general",
 			"Jan 31 21:42:59 mail postfix/anvil[14606]: statistics: max cache size 1 at Jan 31 21:39:37", Sequence{
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:42:59", isKey: false, isValue: false, Special: "1"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:42:59", Layout: "Jan _2 15:04:05", isKey: false, isValue: false, Special: "1"},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "mail", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "postfix/anvil", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false},
@@ -2009,7 +2136,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "size", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenInteger, Value: "1", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "at", isKey: false, isValue: false, IsSpaceBefore: true},
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:39:37", isKey: false, isValue: false, IsSpaceBefore: true, Special: "1"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Jan 31 21:39:37", Layout: "Jan _2 15:04:05", isKey: false, isValue: false, IsSpaceBefore: true, Special: "1"},
 			},
 		},
 
@@ -2017,7 +2144,7 @@ var (
 		{
 			"general",
 			"Feb 06 13:37:00 box sshd[4388]: Accepted publickey for cryptix from dead:beef:1234:5678:223:32ff:feb1:2e50 port 58251 ssh2: RSA de:ad:be:ef:74:a6:bb:45:45:52:71:de:b2:12:34:56", Sequence{
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Feb 06 13:37:00", isKey: false, isValue: false, Special: "1"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Feb 06 13:37:00", Layout: "Jan _2 15:04:05", isKey: false, isValue: false, Special: "1"},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "box", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "sshd", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false},
@@ -2043,7 +2170,7 @@ var (
 		{
 			"general",
 			"2015-01-21 21:41:27 4515 [Note] - '::' resolves to '::';", Sequence{
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2015-01-21 21:41:27", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2015-01-21 21:41:27", Layout: "2006-01-02 15:04:05", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenInteger, Value: "4515", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "Note", isKey: false, isValue: false},
@@ -2065,7 +2192,7 @@ var (
 		{
 			"general",
 			"2015-01-21 21:41:27 4515 [Note] Server socket created on IP: '::'.", Sequence{
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2015-01-21 21:41:27", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2015-01-21 21:41:27", Layout: "2006-01-02 15:04:05", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenInteger, Value: "4515", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "Note", isKey: false, isValue: false},
@@ -2090,7 +2217,7 @@ var (
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "[", IsSpaceBefore: true},
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "16/Jan/2003:21:22:59 -0500", Special: "5"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "16/Jan/2003:21:22:59 -0500", Layout: "_2/Jan/2006:15:04:05 -0700", Special: "5"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "]"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "\"", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "GET"},
@@ -2109,7 +2236,7 @@ var (
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "-", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "[", IsSpaceBefore: true},
-				Token{Type: TokenTime, Tag: TagRegExTime, Value: "03/May/2004:01:19:07 +0000", Special: "5"},
+				Token{Type: TokenTime, Tag: TagRegExTime, Value: "03/May/2004:01:19:07 +0000", Layout: "_2/Jan/2006:15:04:05 -0700", Special: "5"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "]"},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "\"", IsSpaceBefore: true},
 				Token{Type: TokenLiteral, Tag: TagUnknown, Value: "GET"},
@@ -2128,7 +2255,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "-", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "-", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false, IsSpaceBefore: true},
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "12/Jul/2013:15:56:54 +0000", isKey: false, isValue: false, Special: "5"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "12/Jul/2013:15:56:54 +0000", Layout: "_2/Jan/2006:15:04:05 -0700", isKey: false, isValue: false, Special: "5"},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "]", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "GET", isKey: false, isValue: false},
@@ -2147,7 +2274,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "Chef Client/0.10.2 (ruby-1.8.7-p302; ohai-0.6.4; x86_64-linux; +http://opscode.com)", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false, IsSpaceBefore: true},
-				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "127.0.0.1:9460", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenHostPort, Value: "127.0.0.1:9460", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenInteger, Value: "200", isKey: false, isValue: false},
@@ -2165,10 +2292,10 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "some_node.example.com", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false, IsSpaceBefore: true},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2013-07-12T15:56:40Z", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2013-07-12T15:56:40Z", Layout: "2006-01-02T15:04:05Z", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false, IsSpaceBefore: true},
-				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "2jmj7l5rSw0yVb/vlWAYkK/YBwk=", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenBase64, Value: "2jmj7l5rSw0yVb/vlWAYkK/YBwk=", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenInteger, Value: "985", isKey: false, isValue: false, IsSpaceBefore: true},
 			},
@@ -2181,7 +2308,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "-", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "-", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false, IsSpaceBefore: true},
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "03/May/2004:01:00:04 +0000", isKey: false, isValue: false, Special: "5"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "03/May/2004:01:00:04 +0000", Layout: "_2/Jan/2006:15:04:05 -0700", isKey: false, isValue: false, Special: "5"},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "]", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "GET", isKey: false, isValue: false},
@@ -2196,14 +2323,10 @@ var (
 		{
 			"general",
 			`2014-02-15T23:39:43.945958Z my-test-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/HTTP/1.1"`, Sequence{
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-02-15T23:39:43.945958Z", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-02-15T23:39:43.945958Z", Layout: "2006-01-02T15:04:05.999999Z", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "my-test-loadbalancer", isKey: false, isValue: false, IsSpaceBefore: true},
-				Token{Tag: TagUnknown, Type: TokenIPv4, Value: "192.168.131.39", isKey: false, isValue: false, IsSpaceBefore: true},
-				Token{Tag: TagUnknown, Type: TokenLiteral, Value: ":", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenInteger, Value: "2817", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenIPv4, Value: "10.0.0.1", isKey: false, isValue: false, IsSpaceBefore: true},
-				Token{Tag: TagUnknown, Type: TokenLiteral, Value: ":", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenInteger, Value: "80", isKey: false, isValue: false},
+				Token{Tag: TagUnknown, Type: TokenHostPort, Value: "192.168.131.39:2817", isKey: false, isValue: false, IsSpaceBefore: true},
+				Token{Tag: TagUnknown, Type: TokenHostPort, Value: "10.0.0.1:80", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenFloat, Value: "0.000073", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenFloat, Value: "0.001048", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenFloat, Value: "0.000057", isKey: false, isValue: false, IsSpaceBefore: true},
@@ -2223,7 +2346,7 @@ var (
 			"general",
 			"Feb 06 15:56:09 higgs sshd[902]: Server listening on 0.0.0.0 port 22.",
 			Sequence{
-				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Feb 06 15:56:09", isKey: false, isValue: false, Special: "1"},
+				Token{Tag: TagRegExTime, Type: TokenTime, Value: "Feb 06 15:56:09", Layout: "Jan _2 15:04:05", isKey: false, isValue: false, Special: "1"},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "higgs", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "sshd", isKey: false, isValue: false, IsSpaceBefore: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "[", isKey: false, isValue: false},
@@ -2452,7 +2575,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: ":", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16T12:45:03-0400", isKey: false, isValue: true},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16T12:45:03-0400", Layout: "2006-01-02T15:04:05-0700", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: ",", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
@@ -2479,7 +2602,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: ":", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16T13:00:00.000+0000", isKey: false, isValue: true},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16T13:00:00.000+0000", Layout: "2006-01-02T15:04:05.999-0700", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: ",", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
@@ -2487,7 +2610,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: ":", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16T23:00:00.000+0000", isKey: false, isValue: true},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16T23:00:00.000+0000", Layout: "2006-01-02T15:04:05.999-0700", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: ",", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
@@ -2501,7 +2624,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: ":", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16", isKey: false, isValue: true},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-08-16", Layout: "2006-01-02", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "\"", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "}", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "]", isKey: false, isValue: false},
@@ -2544,7 +2667,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "Alice", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "eventTime", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-03-06T21:22:54Z", isKey: false, isValue: true},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-03-06T21:22:54Z", Layout: "2006-01-02T15:04:05Z", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "eventSource", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "ec2.amazonaws.com", isKey: false, isValue: true},
@@ -2607,7 +2730,7 @@ var (
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "rhendriks", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "eventTime", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-01-31T12:00:00Z", isKey: false, isValue: true},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2014-01-31T12:00:00Z", Layout: "2006-01-02T15:04:05Z", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "eventSource", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "ec2.amazonaws.com", isKey: false, isValue: true},
@@ -2652,7 +2775,7 @@ var (
 			`{"Version": "2012-10-17", "Statement": [{"Sid": "Put bucket policy needed for audit logging", "Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::193672423079:user/logs"}, "Action": "s3:PutObject", "Resource": "arn:aws:s3:::AuditLogs/*"}, {"Sid": "Get bucket policy needed for audit logging", "Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::193672423079:user/logs"}, "Action": "s3:GetBucketAcl", "Resource": "arn:aws:s3:::AuditLogs"} ] }`, Sequence{
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "Version", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
-				Token{Tag: TagUnknown, Type: TokenTime, Value: "2012-10-17", isKey: false, isValue: true},
+				Token{Tag: TagUnknown, Type: TokenTime, Value: "2012-10-17", Layout: "2006-01-02", isKey: false, isValue: true},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "Statement.0.Sid", isKey: true, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "=", isKey: false, isValue: false},
 				Token{Tag: TagUnknown, Type: TokenLiteral, Value: "Put bucket policy needed for audit logging", isKey: false, isValue: true},
@@ -17,6 +17,7 @@ package sequence
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -112,6 +113,113 @@ func (this Sequence) Signature() string {
 	return sig
 }
 
+// Fields returns the values of a parsed sequence by field name, the name is the tag of the
+// token, or its type when it has no tag, and is numbered when it is used more than once,
+// eg string, string1. Literals are not included.
+func (this Sequence) Fields() map[string]string {
+	fields := make(map[string]string)
+	for i, name := range this.fieldNames() {
+		if name != "" {
+			fields[name] = this[i].Value
+		}
+	}
+	return fields
+}
+
+// Times returns the time values of a parsed sequence in RFC 3339 by field name, named as in
+// Fields. The times that can not be normalized, such as a time of day with no date, are not
+// included.
+func (this Sequence) Times() map[string]string {
+	times := make(map[string]string)
+	for i, name := range this.fieldNames() {
+		if name == "" || this[i].Type != TokenTime {
+			continue
+		}
+		if t, err := NormalizeTime(this[i].Value, this[i].Layout); err == nil {
+			times[name] = t
+		}
+	}
+	return times
+}
+
+// fieldNames returns the field name of each token of the sequence, or an empty string for
+// the literals.
+func (this Sequence) fieldNames() []string {
+	names := make([]string, len(this))
+	mtc := make(map[string]int)
+	for i, token := range this {
+		if token.Type == TokenLiteral || token.Type == TokenUnknown {
+			continue
+		}
+		name := token.Type.String()
+		if token.Tag != TagUnknown {
+			name = token.Tag.String()
+		}
+		if t, ok := mtc[name]; ok {
+			mtc[name] = t + 1
+			name += strconv.Itoa(t)
+		} else {
+			mtc[name] = 1
+		}
+		names[i] = name
+	}
+	return names
+}
+
+// splitAddresses returns the sequence with the host:port tokens that have no tag split into
+// the host, a colon and the port, and the cidrs into the address, a slash and the prefix
+// length when cidr is set, as the scanner returned them before it recognised the addresses.
+// The host of an [ipv6] address keeps its brackets as literals. It returns false when there
+// is nothing to split.
+func splitAddresses(seq Sequence, cidr bool) (Sequence, bool) {
+	var (
+		out   Sequence
+		split bool
+	)
+	for i, token := range seq {
+		var parts Sequence
+		switch {
+		case token.Tag != TagUnknown:
+		case token.Type == TokenHostPort:
+			host, port, ht := splitHostPort(token.Value)
+			switch ht {
+			case TokenIPv6:
+				parts = Sequence{{Type: TokenLiteral, Value: "["}, {Type: TokenIPv6, Value: host}, {Type: TokenLiteral, Value: "]"}}
+			case TokenIPv4:
+				parts = Sequence{{Type: TokenIPv4, Value: host}}
+			case token__host__:
+				parts = Sequence{{Type: TokenLiteral, Value: host}}
+			}
+			if parts != nil {
+				parts = append(parts, Token{Type: TokenLiteral, Value: ":"}, Token{Type: TokenInteger, Value: port})
+			}
+		case cidr && (token.Type == TokenIPv4CIDR || token.Type == TokenIPv6CIDR):
+			j := strings.LastIndexByte(token.Value, '/')
+			tt := TokenIPv4
+			if token.Type == TokenIPv6CIDR {
+				tt = TokenIPv6
+			}
+			parts = Sequence{{Type: tt, Value: token.Value[:j]}, {Type: TokenLiteral, Value: "/"}, {Type: TokenInteger, Value: token.Value[j+1:]}}
+		}
+		if parts == nil {
+			if split {
+				out = append(out, token)
+			}
+			continue
+		}
+		if !split {
+			out = append(make(Sequence, 0, len(seq)+2*len(parts)), seq[:i]...)
+			split = true
+		}
+		parts[0].IsSpaceBefore = token.IsSpaceBefore
+		out = append(out, parts...)
+	}
+	if !split {
+		return seq, false
+	}
+	return out, true
+}
+
 // Longstring returns a multi-line representation of the tokens in the sequence
 func (this Sequence) PrintTokens() string {
 	var str string
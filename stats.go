@@ -0,0 +1,188 @@
+package sequence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence/models"
+)
+
+//The intervals the match counts of the patterns are saved for, set with
+//matchCountInterval in the config.
+const (
+	MatchCountIntervalHour = "hour"
+	MatchCountIntervalDay  = "day"
+)
+
+//The trends reported by GetPatternTrends.
+const (
+	TrendNew      = "new"
+	TrendGrowing  = "growing"
+	TrendVanished = "vanished"
+)
+
+//The matches of a pattern in the current window and the window before it.
+type PatternTrend struct {
+	PatternId       string
+	Service         string
+	Pattern         string
+	Trend           string
+	Current         int64
+	Previous        int64
+	DateCreated     time.Time
+	DateLastMatched time.Time
+}
+
+//Returns the start of the interval the time falls in, the intervals are in UTC.
+func intervalStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	if interval == MatchCountIntervalDay {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return t.Truncate(time.Hour)
+}
+
+//Returns the start of the interval after the one the time falls in.
+func nextIntervalStart(t time.Time, interval string) time.Time {
+	if interval == MatchCountIntervalDay {
+		return intervalStart(t, interval).AddDate(0, 0, 1)
+	}
+	return intervalStart(t, interval).Add(time.Hour)
+}
+
+//Adds the matches to the count of the pattern for the interval of the time passed.
+func recordMatchCount(ctx context.Context, tx *sql.Tx, patternid string, count int64, t time.Time) {
+	start := intervalStart(t, config.matchCountInterval)
+	res, err := tx.ExecContext(ctx, rebind(`UPDATE "PatternMatchCounts" SET match_count = match_count + ? WHERE pattern_id = ? AND interval_start = ?`), count, patternid, start)
+	if err == nil {
+		if n, _ := res.RowsAffected(); n > 0 {
+			return
+		}
+		_, err = tx.ExecContext(ctx, rebind(`INSERT INTO "PatternMatchCounts" (pattern_id, interval_start, match_count) VALUES (?, ?, ?)`), patternid, start, count)
+	}
+	if err != nil {
+		logger.DatabaseUpdateFailed("pattern match counts", patternid, err.Error())
+	}
+}
+
+//Returns the start of the oldest interval kept with the retention in days, false if
+//all the intervals are kept.
+func matchCountCutoff(now time.Time, retention int, interval string) (time.Time, bool) {
+	if retention <= 0 {
+		return time.Time{}, false
+	}
+	return intervalStart(now.AddDate(0, 0, -retention), interval), true
+}
+
+//Deletes the match counts of the intervals that are older than matchCountRetention.
+func pruneMatchCounts(ctx context.Context, tx *sql.Tx, now time.Time) {
+	cutoff, ok := matchCountCutoff(now, config.matchCountRetention, config.matchCountInterval)
+	if !ok {
+		return
+	}
+	if _, err := tx.ExecContext(ctx, rebind(`DELETE FROM "PatternMatchCounts" WHERE interval_start < ?`), cutoff); err != nil {
+		logger.DatabaseUpdateFailed("pattern match counts", "interval_start", err.Error())
+	}
+}
+
+//Returns the sum of the match counts of each pattern for the intervals that start
+//from the start time and before the end time.
+func getMatchCounts(db *sql.DB, ctx context.Context, from time.Time, to time.Time) (map[string]int64, error) {
+	rows, err := db.QueryContext(ctx, rebind(`SELECT pattern_id, SUM(match_count) FROM "PatternMatchCounts" WHERE interval_start >= ? AND interval_start < ? GROUP BY pattern_id`), from, to)
+	if err != nil {
+		logger.DatabaseSelectFailed("pattern match counts", "interval_start", err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var (
+			id    string
+			count int64
+		)
+		if err = rows.Scan(&id, &count); err != nil {
+			logger.DatabaseSelectFailed("pattern match counts", "interval_start", err.Error())
+			return nil, err
+		}
+		counts[id] = count
+	}
+	return counts, rows.Err()
+}
+
+//Returns the trend of a pattern. A pattern is new if it was created in the current
+//window, growing if it has at least growth times the matches of the previous window
+//and vanished if it was matched in the previous window but not in the current one.
+func classifyTrend(current int64, previous int64, created time.Time, windowStart time.Time, growth float64) string {
+	switch {
+	case !created.Before(windowStart):
+		return TrendNew
+	case previous > 0 && current == 0:
+		return TrendVanished
+	case previous > 0 && float64(current) >= growth*float64(previous):
+		return TrendGrowing
+	}
+	return ""
+}
+
+//Sorts the trends by new, growing and vanished, and then by the most matches in either
+//window, so the vanished patterns with the most matches are listed first.
+func sortTrends(trends []PatternTrend) {
+	order := map[string]int{TrendNew: 0, TrendGrowing: 1, TrendVanished: 2}
+	max := func(t PatternTrend) int64 {
+		if t.Current > t.Previous {
+			return t.Current
+		}
+		return t.Previous
+	}
+	sort.SliceStable(trends, func(i, j int) bool {
+		if trends[i].Trend != trends[j].Trend {
+			return order[trends[i].Trend] < order[trends[j].Trend]
+		}
+		if max(trends[i]) != max(trends[j]) {
+			return max(trends[i]) > max(trends[j])
+		}
+		return trends[i].PatternId < trends[j].PatternId
+	})
+}
+
+//Returns the new, growing and vanished patterns, comparing the match counts of the
+//window up to now with the window before it. The windows start at the start of an
+//interval, so a window shorter than the interval covers the whole interval.
+func GetPatternTrends(window time.Duration, growth float64) ([]PatternTrend, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("The window must be greater than 0")
+	}
+	db, ctx := OpenDbandSetContext()
+	defer db.Close()
+	now := time.Now()
+	start := intervalStart(now.Add(-window), config.matchCountInterval)
+	prevStart := intervalStart(start.Add(-window), config.matchCountInterval)
+	current, err := getMatchCounts(db, ctx, start, nextIntervalStart(now, config.matchCountInterval))
+	if err != nil {
+		return nil, err
+	}
+	previous, err := getMatchCounts(db, ctx, prevStart, start)
+	if err != nil {
+		return nil, err
+	}
+	patterns, err := models.Patterns().All(ctx, db)
+	if err != nil {
+		logger.DatabaseSelectFailed("patterns", "All", err.Error())
+		return nil, err
+	}
+	services := getServicesFromDatabase(db, ctx)
+	var trends []PatternTrend
+	for _, p := range patterns {
+		trend := classifyTrend(current[p.ID], previous[p.ID], p.DateCreated, start, growth)
+		if trend == "" {
+			continue
+		}
+		trends = append(trends, PatternTrend{PatternId: p.ID, Service: services[p.ServiceID], Pattern: p.SequencePattern, Trend: trend,
+			Current: current[p.ID], Previous: previous[p.ID], DateCreated: p.DateCreated, DateLastMatched: p.DateLastMatched})
+	}
+	sortTrends(trends)
+	return trends, nil
+}
@@ -0,0 +1,63 @@
+package sequence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervalStart(t *testing.T) {
+	tm := time.Date(2021, 3, 14, 15, 9, 26, 535, time.FixedZone("EST", -5*3600))
+	require.Equal(t, time.Date(2021, 3, 14, 20, 0, 0, 0, time.UTC), intervalStart(tm, MatchCountIntervalHour))
+	require.Equal(t, time.Date(2021, 3, 14, 0, 0, 0, 0, time.UTC), intervalStart(tm, MatchCountIntervalDay))
+	require.Equal(t, time.Date(2021, 3, 14, 21, 0, 0, 0, time.UTC), nextIntervalStart(tm, MatchCountIntervalHour))
+	require.Equal(t, time.Date(2021, 3, 15, 0, 0, 0, 0, time.UTC), nextIntervalStart(tm, MatchCountIntervalDay))
+}
+
+func TestClassifyTrend(t *testing.T) {
+	start := time.Date(2021, 3, 14, 0, 0, 0, 0, time.UTC)
+	old := start.Add(-time.Hour * 48)
+	tests := []struct {
+		current, previous int64
+		created           time.Time
+		trend             string
+	}{
+		{5, 0, start.Add(time.Hour), TrendNew},
+		{5, 0, start, TrendNew},
+		{0, 100, old, TrendVanished},
+		{200, 100, old, TrendGrowing},
+		{150, 100, old, ""},
+		{5, 0, old, ""},
+		{0, 0, old, ""},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.trend, classifyTrend(tc.current, tc.previous, tc.created, start, 2), "%d %d %v", tc.current, tc.previous, tc.created)
+	}
+}
+
+func TestSortTrends(t *testing.T) {
+	trends := []PatternTrend{
+		{PatternId: "a", Trend: TrendVanished, Previous: 10},
+		{PatternId: "b", Trend: TrendGrowing, Current: 20, Previous: 5},
+		{PatternId: "c", Trend: TrendVanished, Previous: 500},
+		{PatternId: "d", Trend: TrendNew, Current: 3},
+	}
+	sortTrends(trends)
+	var ids []string
+	for _, tr := range trends {
+		ids = append(ids, tr.PatternId)
+	}
+	require.Equal(t, []string{"d", "b", "c", "a"}, ids)
+}
+
+func TestMatchCountCutoff(t *testing.T) {
+	now := time.Date(2021, 3, 14, 15, 9, 26, 0, time.UTC)
+	_, ok := matchCountCutoff(now, 0, MatchCountIntervalHour)
+	require.False(t, ok)
+	cutoff, ok := matchCountCutoff(now, 30, MatchCountIntervalHour)
+	require.True(t, ok)
+	require.Equal(t, time.Date(2021, 2, 12, 15, 0, 0, 0, time.UTC), cutoff)
+	cutoff, _ = matchCountCutoff(now, 30, MatchCountIntervalDay)
+	require.Equal(t, time.Date(2021, 2, 12, 0, 0, 0, 0, time.UTC), cutoff)
+}
@@ -0,0 +1,339 @@
+package sequence
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	//maximum size of a single syslog message, both for UDP datagrams
+	//and for octet counted TCP frames
+	syslogMaxMessageSize = 64 * 1024
+	//RFC3164 timestamp layout, eg: Jan  2 15:04:05
+	rfc3164TimeLayout = "Jan _2 15:04:05"
+	//RFC3164 limits the tag to 32 alphanumeric characters
+	rfc3164MaxTagLength = 32
+	syslogNilValue      = "-"
+)
+
+var (
+	ErrSyslogEmpty     = errors.New("syslog: empty message")
+	ErrSyslogPriority  = errors.New("syslog: invalid priority")
+	ErrSyslogHeader    = errors.New("syslog: invalid header")
+	ErrSyslogFrameSize = errors.New("syslog: invalid octet count")
+)
+
+//Parses a single syslog message in either RFC5424 or RFC3164 format.
+//The APP-NAME (RFC5424) or PROGRAM from the tag (RFC3164) becomes the
+//service and the MSG part becomes the message of the returned LogRecord.
+//If no service can be found it is set to none, in line with the file readers.
+func ParseSyslogMessage(data string) (LogRecord, error) {
+	data = strings.TrimRight(data, "\r\n\x00")
+	if len(strings.TrimSpace(data)) == 0 {
+		return LogRecord{}, ErrSyslogEmpty
+	}
+	rest, err := stripSyslogPriority(data)
+	if err != nil {
+		return LogRecord{}, err
+	}
+	//RFC5424 messages have a version number straight after the priority
+	if len(rest) > 1 && rest[0] >= '1' && rest[0] <= '9' && rest[1] == ' ' {
+		return parseRFC5424(rest[2:])
+	}
+	return parseRFC3164(rest), nil
+}
+
+//Removes the <PRI> part of the message, the priority itself is not used.
+func stripSyslogPriority(data string) (string, error) {
+	if data[0] != '<' {
+		return "", ErrSyslogPriority
+	}
+	end := strings.IndexByte(data, '>')
+	//the priority is at most 3 digits
+	if end < 2 || end > 4 {
+		return "", ErrSyslogPriority
+	}
+	pri, err := strconv.Atoi(data[1:end])
+	if err != nil || pri > 191 {
+		return "", ErrSyslogPriority
+	}
+	return data[end+1:], nil
+}
+
+//HEADER = TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP STRUCTURED-DATA [SP MSG]
+func parseRFC5424(data string) (LogRecord, error) {
+	var fields [5]string
+	rest := data
+	for i := range fields {
+		sp := strings.IndexByte(rest, ' ')
+		if sp <= 0 {
+			return LogRecord{}, ErrSyslogHeader
+		}
+		fields[i], rest = rest[:sp], rest[sp+1:]
+	}
+	rest, err := skipStructuredData(rest)
+	if err != nil {
+		return LogRecord{}, err
+	}
+	if len(rest) > 0 && rest[0] == ' ' {
+		rest = rest[1:]
+	}
+	//the message may start with a UTF-8 byte order mark
+	rest = strings.TrimPrefix(rest, "\ufeff")
+	svc := fields[2]
+	if svc == syslogNilValue || svc == "" {
+		svc = "none"
+	}
+	return LogRecord{Service: svc, Message: rest}, nil
+}
+
+//Structured data is either the nil value or one or more [id param="value"]
+//elements where ] " and \ can be escaped inside the values.
+func skipStructuredData(data string) (string, error) {
+	if strings.HasPrefix(data, syslogNilValue) {
+		return data[1:], nil
+	}
+	i := 0
+	for i < len(data) && data[i] == '[' {
+		inQuote := false
+		closed := false
+		for i++; i < len(data); i++ {
+			c := data[i]
+			if c == '\\' && inQuote {
+				i++
+				continue
+			}
+			if c == '"' {
+				inQuote = !inQuote
+			} else if c == ']' && !inQuote {
+				closed = true
+				i++
+				break
+			}
+		}
+		if !closed {
+			return "", ErrSyslogHeader
+		}
+	}
+	if i == 0 {
+		return "", ErrSyslogHeader
+	}
+	return data[i:], nil
+}
+
+//RFC3164 is loosely followed by most senders so we are lenient here, the
+//timestamp and hostname are both optional, TAG[PID]: MSG is expected after them.
+func parseRFC3164(data string) LogRecord {
+	rest := data
+	if len(rest) >= len(rfc3164TimeLayout) {
+		if _, err := time.Parse(rfc3164TimeLayout, rest[:len(rfc3164TimeLayout)]); err == nil {
+			rest = strings.TrimLeft(rest[len(rfc3164TimeLayout):], " ")
+			//the hostname is the next word, unless that word is already the tag
+			if sp := strings.IndexByte(rest, ' '); sp > 0 && !strings.ContainsAny(rest[:sp], ":[") {
+				rest = rest[sp+1:]
+			}
+		}
+	}
+	tag, msg, ok := splitRFC3164Tag(rest)
+	if !ok {
+		return LogRecord{Service: "none", Message: rest}
+	}
+	return LogRecord{Service: tag, Message: msg}
+}
+
+//Returns the program name from the tag and the remaining message,
+//eg: sshd[1234]: Accepted password returns sshd and Accepted password
+func splitRFC3164Tag(data string) (string, string, bool) {
+	end := strings.IndexAny(data, "[: ")
+	if end <= 0 || end > rfc3164MaxTagLength {
+		return "", "", false
+	}
+	tag := data[:end]
+	rest := data[end:]
+	if rest[0] == '[' {
+		cb := strings.IndexByte(rest, ']')
+		if cb < 0 {
+			return "", "", false
+		}
+		rest = rest[cb+1:]
+	}
+	if len(rest) == 0 || (rest[0] != ':' && rest[0] != ' ') {
+		return "", "", false
+	}
+	if rest[0] == ':' {
+		rest = rest[1:]
+	} else if !strings.HasPrefix(data[end:], "[") {
+		//a plain word followed by a space is not a tag
+		return "", "", false
+	}
+	return tag, strings.TrimLeft(rest, " "), true
+}
+
+//Reads one syslog frame from a TCP stream, RFC6587 octet counting
+//(MSG-LEN SP SYSLOG-MSG) is used when the frame starts with a digit,
+//otherwise the frame is terminated by a new line.
+func ReadSyslogFrame(r *bufio.Reader) (string, error) {
+	b, err := r.Peek(1)
+	if err != nil {
+		return "", err
+	}
+	if b[0] >= '0' && b[0] <= '9' {
+		ls, err := r.ReadString(' ')
+		if err != nil {
+			return "", err
+		}
+		n, err := strconv.Atoi(ls[:len(ls)-1])
+		if err != nil || n <= 0 || n > syslogMaxMessageSize {
+			return "", ErrSyslogFrameSize
+		}
+		buf := make([]byte, n)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+		return string(buf), nil
+	}
+	line, err := r.ReadString('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+//Receives syslog messages on UDP and/or TCP and passes the
+//parsed log records to the Records channel.
+type SyslogServer struct {
+	Records chan LogRecord
+
+	mu        sync.Mutex
+	udp       net.PacketConn
+	tcp       net.Listener
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+func NewSyslogServer(bufferSize int) *SyslogServer {
+	return &SyslogServer{
+		Records: make(chan LogRecord, bufferSize),
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+//Starts listening for syslog datagrams on the address, eg: :514
+func (this *SyslogServer) ListenUDP(addr string) error {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("Unable to listen for syslog on udp %s: %s", addr, err)
+	}
+	this.mu.Lock()
+	this.udp = pc
+	this.mu.Unlock()
+	this.wg.Add(1)
+	go func() {
+		defer this.wg.Done()
+		buf := make([]byte, syslogMaxMessageSize)
+		for {
+			n, _, err := pc.ReadFrom(buf)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					logger.HandleError(fmt.Sprintf("Error reading syslog udp datagram: %s", err))
+				}
+				return
+			}
+			this.handle(string(buf[:n]))
+		}
+	}()
+	return nil
+}
+
+//Starts accepting syslog connections on the address, eg: :601
+func (this *SyslogServer) ListenTCP(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("Unable to listen for syslog on tcp %s: %s", addr, err)
+	}
+	this.mu.Lock()
+	this.tcp = l
+	this.mu.Unlock()
+	this.wg.Add(1)
+	go func() {
+		defer this.wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					logger.HandleError(fmt.Sprintf("Error accepting syslog tcp connection: %s", err))
+				}
+				return
+			}
+			this.mu.Lock()
+			this.conns[conn] = struct{}{}
+			this.mu.Unlock()
+			this.wg.Add(1)
+			go this.serveConn(conn)
+		}
+	}()
+	return nil
+}
+
+func (this *SyslogServer) serveConn(conn net.Conn) {
+	defer this.wg.Done()
+	defer func() {
+		this.mu.Lock()
+		delete(this.conns, conn)
+		this.mu.Unlock()
+		conn.Close()
+	}()
+	r := bufio.NewReaderSize(conn, syslogMaxMessageSize)
+	for {
+		frame, err := ReadSyslogFrame(r)
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				logger.HandleError(fmt.Sprintf("Error reading syslog frame from %s: %s", conn.RemoteAddr(), err))
+			}
+			return
+		}
+		this.handle(frame)
+	}
+}
+
+func (this *SyslogServer) handle(data string) {
+	r, err := ParseSyslogMessage(data)
+	if err != nil {
+		if err != ErrSyslogEmpty {
+			logger.HandleDebug(fmt.Sprintf("Discarding syslog message %q: %s", data, err))
+		}
+		return
+	}
+	//check for an empty message and discard
+	if len(strings.TrimSpace(r.Message)) == 0 {
+		return
+	}
+	this.Records <- r
+}
+
+//Stops the listeners, closes any open connections and then the Records channel.
+func (this *SyslogServer) Close() {
+	this.closeOnce.Do(func() {
+		this.mu.Lock()
+		if this.udp != nil {
+			this.udp.Close()
+		}
+		if this.tcp != nil {
+			this.tcp.Close()
+		}
+		for c := range this.conns {
+			c.Close()
+		}
+		this.mu.Unlock()
+		this.wg.Wait()
+		close(this.Records)
+	})
+}
@@ -94,9 +94,9 @@ func convertToXml(document xPatternDB) string {
 	return string(y)
 }
 
-func addToRuleset(pattern sequence.AnalyzerResult, document xPatternDB) xPatternDB {
+func addToRuleset(pattern sequence.AnalyzerResult, document xPatternDB, vmsg string) xPatternDB {
 	//build the rule as XML
-	rule := buildRuleXML(pattern)
+	rule := buildRuleXML(pattern, vmsg)
 	//get the ruleset name for the example
 	//it will be the service value
 	rs := pattern.Service.Name
@@ -127,7 +127,7 @@ func addToRuleset(pattern sequence.AnalyzerResult, document xPatternDB) xPattern
 	return document
 }
 
-func buildRuleXML(result sequence.AnalyzerResult) xRule {
+func buildRuleXML(result sequence.AnalyzerResult, vmsg string) xRule {
 	rule := xRule{}
 	count := xRuleValue{Name: "seq-matches", Value: strconv.Itoa(result.ExampleCount)}
 	rule.Values.Values = append(rule.Values.Values, count)
@@ -137,6 +137,9 @@ func buildRuleXML(result sequence.AnalyzerResult) xRule {
 	rule.Values.Values = append(rule.Values.Values, dlm)
 	dcs := xRuleValue{Name: "seq-complexity", Value: fmt.Sprintf("%.2f", result.ComplexityScore)}
 	rule.Values.Values = append(rule.Values.Values, dcs)
+	if vmsg != "" {
+		rule.Values.Values = append(rule.Values.Values, xRuleValue{Name: "seq-validation", Value: vmsg})
+	}
 	var p xPattern
 	var e xExample
 	var t xTestMessage
@@ -155,7 +158,7 @@ func buildRuleXML(result sequence.AnalyzerResult) xRule {
 		}
 		rule.Examples.Examples = append(rule.Examples.Examples, e)
 	}
-	p.Pattern = replaceTags(result.Pattern)
+	p.Pattern = replaceTags(result.Pattern, result.FieldNamesByIndex())
 	rule.Patterns = append(rule.Patterns, p)
 
 	//create a new UUID
@@ -30,6 +30,7 @@ type yRuleValues struct {
 	Seqmatches      int     `yaml:"seq-matches"`
 	DateCreated     string  `yaml:"seq-created"`
 	DateLastMatched string  `yaml:"seq-last-match"`
+	Validation      string  `yaml:"seq-validation,omitempty"`
 }
 
 // This represents a ruleset section in the sys-log ng yaml file
@@ -55,7 +56,7 @@ func saveAsYaml(oFile *os.File, db yPatternDB) error {
 	return y
 }
 
-func addToYaml(pattern sequence.AnalyzerResult, db yPatternDB) yPatternDB {
+func addToYaml(pattern sequence.AnalyzerResult, db yPatternDB, vmsg string) yPatternDB {
 	//do we have a special case where it belongs to more that one service
 	rsName := pattern.Service.Name
 	rsID := pattern.Service.ID
@@ -67,19 +68,19 @@ func addToYaml(pattern sequence.AnalyzerResult, db yPatternDB) yPatternDB {
 	}
 
 	//every pattern should be unique
-	r := buildRule(pattern, rsName)
+	r := buildRule(pattern, rsName, vmsg)
 	db.Rules[r.ID] = r
 
 	return db
 }
 
-func buildRule(result sequence.AnalyzerResult, rsName string) yRule {
+func buildRule(result sequence.AnalyzerResult, rsName string, vmsg string) yRule {
 	rule := yRule{}
 	rule.Values.Seqmatches = result.ExampleCount
 	//get the ruleset from the example (service)
 	rule.Ruleset = rsName
 	rule.RuleClass = "sequence"
-	rule.Patterns = append(rule.Patterns, replaceTags(result.Pattern))
+	rule.Patterns = append(rule.Patterns, replaceTags(result.Pattern, result.FieldNamesByIndex()))
 	for _, ex := range result.Examples {
 		m, err := extractTestValuesForTokens(ex.Message, result)
 		if err != nil {
@@ -93,6 +94,7 @@ func buildRule(result sequence.AnalyzerResult, rsName string) yRule {
 	rule.Values.DateCreated = result.DateCreated.Format("2006-01-02")
 	rule.Values.DateLastMatched = result.DateLastMatched.Format("2006-01-02")
 	rule.Values.Complexity = math.Round(result.ComplexityScore*100) / 100
+	rule.Values.Validation = vmsg
 	//create a new UUID
 	rule.ID = result.PatternId
 	return rule
@@ -2,6 +2,8 @@
 // for use with Syslog-ng's patterndb parser. The transformation is solid, but not perfect and this is designed to assist
 // a system administrator to create the patterns, not to be a full automation of the process.
 // The outputs for patterndb have been tested with a live patterndb and pass at a rate close to 80% with the pdb test tool.
+// To catch the ones that fail, each rule is checked against its own examples during the export with an emulation of the
+// patterndb parsers, the rules that fail are flagged or left out depending on the validation setting and listed in a report.
 // The variable names usually need a bit of review as they can be string, string1 etc as the tool can detect a variable, but not what the variable is eg:server name.
 package syslog_ng_pattern_db
 
@@ -24,7 +26,9 @@ var (
 		delstr  map[string]string
 		cfield  map[string]string
 	}
-	logger *sequence.StandardLogger
+	//flag, exclude or off
+	validation string
+	logger     *sequence.StandardLogger
 )
 
 // Allows the user to set the logger to a global instance.
@@ -35,7 +39,8 @@ func SetLogger(log *sequence.StandardLogger) {
 func readConfig(file string) error {
 	var configInfo struct {
 		Patterndb struct {
-			Tags struct {
+			Validation string
+			Tags       struct {
 				General         map[string]string
 				DelimitedString map[string]string
 				Fieldname       map[string]string
@@ -50,13 +55,21 @@ func readConfig(file string) error {
 	tags.delstr = configInfo.Patterndb.Tags.DelimitedString
 	tags.cfield = configInfo.Patterndb.Tags.Fieldname
 
+	validation = strings.ToLower(configInfo.Patterndb.Validation)
+	switch validation {
+	case "":
+		validation = "flag"
+	case "flag", "exclude", "off":
+	default:
+		return fmt.Errorf("the patterndb validation setting must be flag, exclude or off, not %s", configInfo.Patterndb.Validation)
+	}
 	return nil
 }
 
 // this replaces the sequence tags with the syslog-ng tags
 // first we replace the easy ones that are surrounded by spaces
 // then we deal with the compound ones
-func replaceTags(pattern string) string {
+func replaceTags(pattern string, names map[int]string) string {
 	if len(pattern) < 1 {
 		return pattern
 	}
@@ -67,12 +80,15 @@ func replaceTags(pattern string) string {
 	s := strings.Fields(pattern)
 	var new []string
 	mtc := make(map[string]int)
+	//the index of the next tag in the pattern, for the field name overrides
+	idx := 0
 
 	for _, p := range s {
-		if val, ok := tags.general[p]; ok {
-			p, mtc = getUpdatedTag(p, mtc, val, "")
+		if val, ok := generalTag(p); ok {
+			p, mtc = getUpdatedTag(p, mtc, val, "", names[idx])
+			idx++
 		} else {
-			p, mtc = getSpecial(p, mtc)
+			p, mtc, idx = getSpecial(p, mtc, names, idx)
 		}
 		//reconstruct
 		new = append(new, p)
@@ -105,7 +121,9 @@ func replaceTags(pattern string) string {
 	return result
 }
 
-func getUpdatedTag(p string, mtc map[string]int, tag string, del string) (string, map[string]int) {
+// The name is the field name set for the tag in the database, it replaces the
+// generated field name, which is still counted so the other fields keep their numbers.
+func getUpdatedTag(p string, mtc map[string]int, tag string, del string, name string) (string, map[string]int) {
 	tok := ""
 	xchars := len(del)
 	if xchars == 2 {
@@ -122,15 +140,17 @@ func getUpdatedTag(p string, mtc map[string]int, tag string, del string) (string
 	if t, ok := mtc[tok]; ok {
 		fieldname = fieldname + strconv.Itoa(t)
 		mtc[tok] = t + 1
-		p = strings.Replace(tag, "[fieldname]", fieldname, 1)
 	} else {
 		mtc[tok] = 1
-		p = strings.Replace(tag, "[fieldname]", fieldname, 1)
 	}
+	if name != "" {
+		fieldname = name
+	}
+	p = strings.Replace(tag, "[fieldname]", fieldname, 1)
 	return p, mtc
 }
 
-func getSpecial(p string, mtc map[string]int) (string, map[string]int) {
+func getSpecial(p string, mtc map[string]int, names map[int]string, idx int) (string, map[string]int, int) {
 	var (
 		last              = -1
 		fieldname, del, s string
@@ -146,6 +166,8 @@ func getSpecial(p string, mtc map[string]int) (string, map[string]int) {
 
 	for i, off := range offsets {
 		if i%2 == 0 && i < len(offsets)-1 {
+			//only the tags are counted, a literal can have a pair of % too
+			tagged := true
 			s, del, fieldname, last = getWithDelimiters(p, off, offsets[i+1]+1, last)
 			fieldname = checkForCustomFieldName(fieldname)
 			//check if a time regex tag, this needs some manipulation
@@ -165,25 +187,50 @@ func getSpecial(p string, mtc map[string]int) (string, map[string]int) {
 					fieldname = sequence.TagRegExTime.String()
 				}
 				if val, ok := tags.delstr[del]; ok {
-					val, mtc = getUpdatedTag(s, mtc, val, del)
+					val, mtc = getUpdatedTag(s, mtc, val, del, names[idx])
 					k = strings.Replace(k, s, val, 1)
 				} else {
 					//this means we have a custom delimiter instead of a space
 					if val, ok := tags.delstr["default"]; ok {
-						val, mtc = getUpdatedTag(s, mtc, val, del)
+						val, mtc = getUpdatedTag(s, mtc, val, del, names[idx])
 						val = strings.Replace(val, "[del]", del, 1)
 						k = strings.Replace(k, s, val, 1)
+					} else {
+						tagged = false
 					}
 				}
 			} else {
-				if val, ok := tags.general[s]; ok {
-					val, mtc = getUpdatedTag(s, mtc, val, del)
+				if val, ok := generalTag(s); ok {
+					val, mtc = getUpdatedTag(s, mtc, val, del, names[idx])
 					k = strings.Replace(k, s, val, 1)
+				} else {
+					tagged = false
 				}
 			}
+			if tagged {
+				idx++
+			}
 		}
 	}
-	return k, mtc
+	return k, mtc, idx
+}
+
+// Returns the patterndb parser for a sequence tag, from the config or, for a token type from the
+// [tokentypes] section of the config or a tag of that type, a PCRE parser with its regex. A regex
+// with an @ can not be put in a parser so it is left as it is.
+func generalTag(p string) (string, bool) {
+	if val, ok := tags.general[p]; ok {
+		return val, true
+	}
+	if rg, ok := sequence.CustomTokenTypeRegex(strings.Trim(p, "%")); ok && !strings.Contains(rg, "@") {
+		return "@PCRE:[fieldname]:" + rg + "@", true
+	}
+	return "", false
+}
+
+func isCustomType(name string) bool {
+	_, ok := sequence.CustomTokenTypeRegex(name)
+	return ok
 }
 
 func checkForCustomFieldName(f string) string {
@@ -197,7 +244,9 @@ func getWithDelimiters(p string, start, end int, last int) (string, string, stri
 	fieldname := p[start+1 : end-1]
 	before := ""
 	//integer and ip fields are not considered strings so can bypass this
-	if fieldname == "integer" || fieldname == "srcip" || fieldname == "dstip" || fieldname == "float" || fieldname == "ipv6" || fieldname == "srcmac" || fieldname == "dstmac" {
+	if fieldname == "integer" || fieldname == "srcip" || fieldname == "dstip" || fieldname == "float" || fieldname == "ipv6" || fieldname == "srcmac" || fieldname == "dstmac" ||
+		fieldname == "uuid" || fieldname == "hex" || fieldname == "base64" ||
+		fieldname == "ipv4cidr" || fieldname == "ipv6cidr" || fieldname == "hostport" || isCustomType(fieldname) {
 		return p[start:end], "", fieldname, end - 1
 	}
 	if start > 0 && end < len(p) {
@@ -274,6 +323,25 @@ func OutputToFiles(outformat string, outfile string, config string, complexityle
 	}
 
 	logger.HandleInfo(fmt.Sprintf("Found %d patterns for output", len(patmap)))
+	//check the rules against their examples before anything is written
+	failed := validatePatterns(patmap)
+	if len(failed) > 0 {
+		logger.HandleInfo(fmt.Sprintf("%d of %d patterns failed the patterndb validation", len(failed), len(patmap)))
+		if err = writeValidationReport(outfile, len(patmap), failed); err != nil {
+			return count, top5, err
+		}
+		if validation == "exclude" {
+			//the map can be keyed by pattern id or by pattern, and it belongs to the caller
+			kept := make(map[string]sequence.AnalyzerResult, len(patmap))
+			for key, result := range patmap {
+				kept[key] = result
+			}
+			for _, vr := range failed {
+				delete(kept, vr.Key)
+			}
+			patmap = kept
+		}
+	}
 	count = len(patmap)
 	outformats := strings.Split(outformat, ",")
 	//open the output files for saving data and add any headers
@@ -319,16 +387,21 @@ func OutputToFiles(outformat string, outfile string, config string, complexityle
 		}
 	}
 	//add the patterns and examples
-	for _, result := range patmap {
+	for id, result := range patmap {
+		vmsg := getValidationMessage(failed, id)
 		for _, fmat := range outformats {
 			if fmat == "" || fmat == "txt" {
-				fmt.Fprintf(txtFile, "# %s\n %s\n# %d log messages matched\n# %s\n\n", result.PatternId, result.Pattern, result.ExampleCount, result.Examples[0].Message)
+				fmt.Fprintf(txtFile, "# %s\n %s\n# %d log messages matched\n# %s\n", result.PatternId, result.Pattern, result.ExampleCount, result.Examples[0].Message)
+				if vmsg != "" {
+					fmt.Fprintf(txtFile, "# validation: %s\n", vmsg)
+				}
+				fmt.Fprintf(txtFile, "\n")
 			}
 			if fmat == "yaml" {
-				yPattDB = addToYaml(result, yPattDB)
+				yPattDB = addToYaml(result, yPattDB, vmsg)
 			}
 			if fmat == "xml" {
-				xPattDB = addToRuleset(result, xPattDB)
+				xPattDB = addToRuleset(result, xPattDB, vmsg)
 			}
 		}
 	}
@@ -352,6 +425,68 @@ func OutputToFiles(outformat string, outfile string, config string, complexityle
 	return count, top5, err
 }
 
+// Validates the translated rule of each pattern, the failed rules are returned by the key of the pattern map.
+func validatePatterns(patmap map[string]sequence.AnalyzerResult) map[string]validationResult {
+	failed := make(map[string]validationResult)
+	if validation == "off" {
+		return failed
+	}
+	for id, result := range patmap {
+		if vr := validateRule(result, replaceTags(result.Pattern, result.FieldNamesByIndex())); len(vr.Reasons) > 0 {
+			vr.Key = id
+			failed[id] = vr
+		}
+	}
+	return failed
+}
+
+// Returns the value for the rule when it is flagged, this is empty when the rule passed
+// or the failed rules are excluded from the output.
+func getValidationMessage(failed map[string]validationResult, id string) string {
+	vr, ok := failed[id]
+	if !ok || validation != "flag" {
+		return ""
+	}
+	return "failed: " + strings.Join(vr.Reasons, "; ")
+}
+
+// Writes the failed rules to the report, next to the output file as <outfile>.validation.txt,
+// or to the log when the output goes to stdout.
+func writeValidationReport(outfile string, total int, failed map[string]validationResult) error {
+	var ids []string
+	for id := range failed {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if failed[ids[i]].Service != failed[ids[j]].Service {
+			return failed[ids[i]].Service < failed[ids[j]].Service
+		}
+		return ids[i] < ids[j]
+	})
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# patterndb validation: %d of %d rules failed, action: %s\n\n", len(failed), total, validation)
+	for _, id := range ids {
+		vr := failed[id]
+		fmt.Fprintf(&sb, "# %s (%s)\n %s\n %s\n", vr.PatternId, vr.Service, vr.Pattern, vr.PdbPattern)
+		for _, r := range vr.Reasons {
+			fmt.Fprintf(&sb, "  - %s\n", r)
+		}
+		fmt.Fprintf(&sb, "\n")
+	}
+	if outfile == "" {
+		logger.HandleInfo(sb.String())
+		return nil
+	}
+	rFile, err := sequence.OpenOutputFile(outfile + ".validation.txt")
+	if err != nil {
+		return err
+	}
+	defer rFile.Close()
+	_, err = rFile.WriteString(sb.String())
+	return err
+}
+
 // This function extracts the values of the tokens for the test examples
 func extractTestValuesForTokens(message string, ar sequence.AnalyzerResult) (map[string]string, error) {
 	var (
@@ -374,20 +509,31 @@ func extractTestValuesForTokens(message string, ar sequence.AnalyzerResult) (map
 	//parse the example
 	pseq, err := parser.Parse(mseq)
 	mtc := make(map[string]int)
+	names := ar.FieldNamesByIndex()
+	//the index of the tag in the pattern, for the field name overrides
+	idx := -1
 	for _, p := range pseq {
-		if p.Type != sequence.TokenLiteral && p.Type != sequence.TokenMultiLine {
+		if p.Type == sequence.TokenLiteral {
+			continue
+		}
+		idx++
+		if p.Type != sequence.TokenMultiLine {
 			if p.Tag == 0 {
 				tok = checkForCustomFieldName(p.Type.String())
 			} else {
 				tok = checkForCustomFieldName(p.Tag.String())
 			}
+			fieldname := tok
 			if t, ok := mtc[tok]; ok {
-				m[tok+strconv.Itoa(t)] = p.Value
+				fieldname = tok + strconv.Itoa(t)
 				mtc[tok] = t + 1
 			} else {
-				m[tok] = p.Value
 				mtc[tok] = 1
 			}
+			if name, ok := names[idx]; ok {
+				fieldname = name
+			}
+			m[fieldname] = p.Value
 		}
 	}
 	return m, err
@@ -1,9 +1,13 @@
 package syslog_ng_pattern_db
 
 import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/ryanfaircloth/sequence-RTG/sequence/models"
 	"github.com/stretchr/testify/require"
 )
 
@@ -26,6 +30,10 @@ var (
 		{"%dsthost% ", "@HOSTNAME:dsthost:@"},
 		{"<%string%>,", "@QSTRING:string:<>@,"},
 		{"\"%object%\"", "@QSTRING:object:\"@"},
+		{"%uuid%,", "@PCRE:uuid:[0-9A-Fa-f]{8}(?:-[0-9A-Fa-f]{4}){3}-[0-9A-Fa-f]{12}@,"},
+		{"%hex% ", "@PCRE:hex:[0-9A-Fa-f]{16,}@"},
+		{"%ipv4cidr%,", `@PCRE:ipv4cidr:\d{1,3}(?:\.\d{1,3}){3}/\d{1,2}@,`},
+		{"%hostport% ", `@PCRE:hostport:(?:\[[^\]\s]+\]|[A-Za-z0-9\-_.]+):\d{1,5}@`},
 	}
 )
 
@@ -38,7 +46,85 @@ func loadConfigs() {
 func TestTagTransformation(t *testing.T) {
 	loadConfigs()
 	for _, tc := range tagtests {
-		tag := replaceTags(tc.data)
+		tag := replaceTags(tc.data, nil)
 		require.Equal(t, tc.result, tag, tc.data)
 	}
 }
+
+func TestTagTransformationWithFieldNames(t *testing.T) {
+	loadConfigs()
+	tests := []struct {
+		data   string
+		names  map[int]string
+		result string
+	}{
+		{"%string% logged in from %srcip%", map[int]string{0: "username"}, "@ESTRING:username: @logged in from @IPvANY:srcip@"},
+		{"%string%,%string% %string%", map[int]string{1: "group"}, "@ESTRING:string:,@@ESTRING:group: @@ESTRING:string2:@"},
+		{"user <%string%> port %integer%", map[int]string{1: "port"}, "user @QSTRING:string:<>@ port @NUMBER:port@"},
+		//the % of the literal are not a tag
+		{"load 10%-20% by %srcuser%", map[int]string{0: "account"}, "load 10%-20% by @ESTRING:account:@"},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.result, replaceTags(tc.data, tc.names), tc.data)
+	}
+}
+
+func TestExtractTestValuesWithFieldNames(t *testing.T) {
+	loadConfigs()
+	ar := sequence.AnalyzerResult{
+		Pattern:      "session opened for user %string% by %string% port %integer%",
+		TagPositions: "24,36,50",
+		FieldNames:   map[int]string{24: "username", 50: "port"},
+	}
+	m, err := extractTestValuesForTokens("session opened for user root by admin port 22", ar)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"username": "root", "string1": "admin", "port": "22"}, m)
+}
+
+func TestCustomTokenTypeTags(t *testing.T) {
+	data, err := ioutil.ReadFile("../sequence.toml")
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "sequence.toml")
+	data = append(data, "[tokentypes.elapsed]\n    regex = '\\d+(?:ms|s)'\n[tokentypes.k8spod]\n    shape = \"a+-x+-w+\"\n"...)
+	require.NoError(t, ioutil.WriteFile(file, data, 0644))
+	require.NoError(t, sequence.ReadConfig(file))
+	require.NoError(t, readConfig(file))
+	t.Cleanup(loadConfigs)
+
+	pattern := "pod %k8spod% took %elapsed%,"
+	pdb := replaceTags(pattern, nil)
+	require.Equal(t, `pod @PCRE:k8spod:[A-Za-z]+-[0-9A-Fa-f]+-[0-9A-Za-z]+@ took @PCRE:elapsed:\d+(?:ms|s)@,`, pdb)
+	ar := sequence.AnalyzerResult{PatternId: "id", Pattern: pattern, TagPositions: "4,18", Service: models.Service{Name: "svc"},
+		Examples: []sequence.LogRecord{{Service: "svc", Message: "pod web-7d4b9c6f5-x2kq9 took 35ms,"}}}
+	vr := validateRule(ar, pdb)
+	require.Empty(t, vr.Reasons)
+}
+
+func TestOutputToFilesExcludesFailedRules(t *testing.T) {
+	data, err := ioutil.ReadFile("../sequence.toml")
+	require.NoError(t, err)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "sequence.toml")
+	require.NoError(t, ioutil.WriteFile(file, bytes.Replace(data, []byte(`validation = "flag"`), []byte(`validation = "exclude"`), 1), 0644))
+	require.NoError(t, sequence.ReadConfig(file))
+	t.Cleanup(loadConfigs)
+	SetLogger(sequence.NewLogger(filepath.Join(dir, "log.txt"), "error"))
+
+	//keyed by pattern as analyzebyservice --all does
+	cmap := map[string]sequence.AnalyzerResult{
+		"%srcuser% logged in from %srcip%": {PatternId: "passes", Pattern: "%srcuser% logged in from %srcip%", TagPositions: "0,25",
+			Service: models.Service{Name: "svc"}, Examples: []sequence.LogRecord{{Service: "svc", Message: "root logged in from 10.1.2.3"}}},
+		"%string% %string%": {PatternId: "fails", Pattern: "%string% %string%", TagPositions: "0,9",
+			Service: models.Service{Name: "svc"}, Examples: []sequence.LogRecord{{Service: "svc", Message: "hello big world"}}},
+	}
+	out := filepath.Join(dir, "patterns")
+	count, _, err := OutputToFiles("xml", out, file, 0, cmap, "", "")
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	xml, err := ioutil.ReadFile(out + ".xml")
+	require.NoError(t, err)
+	require.Contains(t, string(xml), `id="passes"`)
+	require.NotContains(t, string(xml), `id="fails"`)
+	//the map of the caller is not changed
+	require.Len(t, cmap, 2)
+}
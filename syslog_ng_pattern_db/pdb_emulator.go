@@ -0,0 +1,333 @@
+package syslog_ng_pattern_db
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+)
+
+// Appended to an example to check if a rule matches more than the sequence pattern does.
+const overMatchProbe = " sequence-validation-probe"
+
+// A node of a patterndb pattern, either a literal or one of the radix parsers.
+type pdbNode struct {
+	literal string
+	parser  string
+	name    string
+	param   string
+}
+
+// The outcome of checking one rule against its stored examples.
+type validationResult struct {
+	//the key of the pattern in the map passed for output
+	Key        string
+	PatternId  string
+	Service    string
+	Pattern    string
+	PdbPattern string
+	Reasons    []string
+}
+
+// Splits a patterndb pattern into literals and parsers, @@ is an escaped @.
+func parsePdbPattern(pattern string) ([]pdbNode, error) {
+	var (
+		nodes []pdbNode
+		lit   strings.Builder
+	)
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '@' {
+			lit.WriteByte(pattern[i])
+			continue
+		}
+		if i+1 < len(pattern) && pattern[i+1] == '@' {
+			lit.WriteByte('@')
+			i++
+			continue
+		}
+		end := strings.IndexByte(pattern[i+1:], '@')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated parser at position %d", i)
+		}
+		if lit.Len() > 0 {
+			nodes = append(nodes, pdbNode{literal: lit.String()})
+			lit.Reset()
+		}
+		parts := strings.SplitN(pattern[i+1:i+1+end], ":", 3)
+		node := pdbNode{parser: parts[0]}
+		if len(parts) > 1 {
+			node.name = parts[1]
+		}
+		if len(parts) > 2 {
+			node.param = parts[2]
+		}
+		nodes = append(nodes, node)
+		i += end + 1
+	}
+	if lit.Len() > 0 {
+		nodes = append(nodes, pdbNode{literal: lit.String()})
+	}
+	return nodes, nil
+}
+
+// Matches the whole message against the pattern nodes in the same way as the
+// patterndb radix parser, each parser takes its value from the current position
+// and the literals must match exactly. Returns the values of the named parsers.
+func matchPdb(nodes []pdbNode, msg string) (map[string]string, bool, error) {
+	values := make(map[string]string)
+	pos := 0
+	for _, node := range nodes {
+		if node.parser == "" {
+			if !strings.HasPrefix(msg[pos:], node.literal) {
+				return values, false, nil
+			}
+			pos += len(node.literal)
+			continue
+		}
+		value, length, err := matchPdbParser(node, msg[pos:])
+		if err != nil {
+			return values, false, err
+		}
+		if length < 0 {
+			return values, false, nil
+		}
+		if node.name != "" {
+			values[node.name] = value
+		}
+		pos += length
+	}
+	return values, pos == len(msg), nil
+}
+
+// Returns the value of the parser at the start of s and the number of bytes it
+// consumed, which includes any delimiters, or -1 if it does not match.
+func matchPdbParser(node pdbNode, s string) (string, int, error) {
+	switch node.parser {
+	case "ANYSTRING":
+		return s, len(s), nil
+	case "ESTRING":
+		//without a delimiter the string runs to the end of the message
+		if node.param == "" {
+			return s, len(s), nil
+		}
+		if i := strings.Index(s, node.param); i >= 0 {
+			return s[:i], i + len(node.param), nil
+		}
+	case "QSTRING":
+		if node.param == "" {
+			return "", -1, fmt.Errorf("QSTRING %s has no quote characters", node.name)
+		}
+		qopen, qclose := node.param[0], node.param[len(node.param)-1]
+		if len(s) > 0 && s[0] == qopen {
+			if i := strings.IndexByte(s[1:], qclose); i >= 0 {
+				return s[1 : i+1], i + 2, nil
+			}
+		}
+	case "NUMBER":
+		if l := numberLength(s); l > 0 {
+			return s[:l], l, nil
+		}
+	case "FLOAT":
+		if l := floatLength(s); l > 0 {
+			return s[:l], l, nil
+		}
+	case "IPv4":
+		if l := ipLength(s, false); l > 0 {
+			return s[:l], l, nil
+		}
+	case "IPv6":
+		if l := ipLength(s, true); l > 0 {
+			return s[:l], l, nil
+		}
+	case "IPvANY":
+		l := ipLength(s, false)
+		if l6 := ipLength(s, true); l6 > l {
+			l = l6
+		}
+		if l > 0 {
+			return s[:l], l, nil
+		}
+	case "MACADDR":
+		if len(s) >= 17 {
+			if _, err := net.ParseMAC(s[:17]); err == nil {
+				return s[:17], 17, nil
+			}
+		}
+	case "HOSTNAME":
+		if l := runLength(s, isHostnameChar); l > 0 {
+			return s[:l], l, nil
+		}
+	case "EMAIL":
+		if l := emailLength(s); l > 0 {
+			return s[:l], l, nil
+		}
+	case "PCRE":
+		rg := sequence.PCREToRegex(node.param)
+		if rg == "" {
+			return "", -1, fmt.Errorf("PCRE %s cannot be emulated", node.name)
+		}
+		if loc := regexp.MustCompile(`^(?:` + rg + `)`).FindStringIndex(s); loc != nil {
+			return s[:loc[1]], loc[1], nil
+		}
+	default:
+		return "", -1, fmt.Errorf("the %s parser is not supported", node.parser)
+	}
+	return "", -1, nil
+}
+
+func numberLength(s string) int {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	if strings.HasPrefix(s[i:], "0x") {
+		if l := runLength(s[i+2:], isHexChar); l > 0 {
+			return i + 2 + l
+		}
+	}
+	if l := runLength(s[i:], isDigit); l > 0 {
+		return i + l
+	}
+	return 0
+}
+
+func floatLength(s string) int {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	digits := runLength(s[i:], isDigit)
+	i += digits
+	if i < len(s) && s[i] == '.' {
+		if l := runLength(s[i+1:], isDigit); l > 0 {
+			digits += l
+			i += l + 1
+		}
+	}
+	if digits == 0 {
+		return 0
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && s[j] == '-' {
+			j++
+		}
+		if l := runLength(s[j:], isDigit); l > 0 {
+			i = j + l
+		}
+	}
+	return i
+}
+
+// Returns the length of the longest valid address at the start of s.
+func ipLength(s string, v6 bool) int {
+	isChar := isIPv4Char
+	if v6 {
+		isChar = isIPv6Char
+	}
+	for l := runLength(s, isChar); l > 0; l-- {
+		ip := net.ParseIP(s[:l])
+		if ip != nil && strings.Contains(s[:l], ":") == v6 {
+			return l
+		}
+	}
+	return 0
+}
+
+func emailLength(s string) int {
+	local := runLength(s, func(c byte) bool {
+		return isAlnum(c) || strings.IndexByte("!#$%&'*+-/=?^_`{|}~.", c) >= 0
+	})
+	if local == 0 || local >= len(s) || s[local] != '@' {
+		return 0
+	}
+	domain := runLength(s[local+1:], isHostnameChar)
+	if domain == 0 {
+		return 0
+	}
+	return local + 1 + domain
+}
+
+func runLength(s string, f func(byte) bool) int {
+	i := 0
+	for i < len(s) && f(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isHexChar(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isIPv4Char(c byte) bool {
+	return isDigit(c) || c == '.'
+}
+
+func isIPv6Char(c byte) bool {
+	return isHexChar(c) || c == ':' || c == '.'
+}
+
+func isHostnameChar(c byte) bool {
+	return isAlnum(c) || c == '-' || c == '.'
+}
+
+// Checks the translated rule against each of the stored examples of the pattern.
+// The rule fails if it does not match an example, if it captures a different value
+// than the sequence parser for the test values, or if it still matches an example
+// with an extra word at the end that the sequence pattern would not.
+func validateRule(result sequence.AnalyzerResult, pdbPattern string) validationResult {
+	vr := validationResult{PatternId: result.PatternId, Service: result.Service.Name, Pattern: result.Pattern, PdbPattern: pdbPattern}
+	nodes, err := parsePdbPattern(pdbPattern)
+	if err != nil {
+		vr.Reasons = append(vr.Reasons, err.Error())
+		return vr
+	}
+	var re *regexp.Regexp
+	if rg, err := sequence.PatternToRegex(result.Pattern, result.TagPositions); err == nil {
+		re, _ = regexp.Compile(rg)
+	}
+	overMatch := false
+	for _, ex := range result.Examples {
+		values, ok, err := matchPdb(nodes, ex.Message)
+		if err != nil {
+			vr.Reasons = append(vr.Reasons, err.Error())
+			return vr
+		}
+		if !ok {
+			vr.Reasons = append(vr.Reasons, fmt.Sprintf("does not match the example: %s", ex.Message))
+			continue
+		}
+		if tv, err := extractTestValuesForTokens(ex.Message, result); err == nil {
+			var keys []string
+			for k := range tv {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				if v, ok := values[k]; ok && v != tv[k] {
+					vr.Reasons = append(vr.Reasons, fmt.Sprintf("%s is %q instead of %q in the example: %s", k, v, tv[k], ex.Message))
+				}
+			}
+		}
+		if !overMatch && re != nil {
+			probe := ex.Message + overMatchProbe
+			if _, ok, _ := matchPdb(nodes, probe); ok && !re.MatchString(probe) {
+				vr.Reasons = append(vr.Reasons, fmt.Sprintf("matches more than the pattern: %s", probe))
+				overMatch = true
+			}
+		}
+	}
+	return vr
+}
@@ -0,0 +1,78 @@
+package syslog_ng_pattern_db
+
+import (
+	"testing"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/ryanfaircloth/sequence-RTG/sequence/models"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	matchtests = []struct {
+		pattern string
+		msg     string
+		match   bool
+		values  map[string]string
+	}{
+		{"user @ESTRING:srcuser: @from @IPvANY:srcip@", "user root from 10.1.2.3", true, map[string]string{"srcuser": "root", "srcip": "10.1.2.3"}},
+		{"user @ESTRING:srcuser: @from @IPvANY:srcip@", "user root from host", false, nil},
+		{"user @ESTRING:srcuser: @from @IPvANY:srcip@", "user root from 10.1.2.3 port 22", false, nil},
+		{"addr @IPvANY:ip@ up", "addr fe80::1 up", true, map[string]string{"ip": "fe80::1"}},
+		{"took @NUMBER:integer@ ms, @FLOAT:decimal@ s", "took 12 ms, -1.5 s", true, map[string]string{"integer": "12", "decimal": "-1.5"}},
+		{"took @NUMBER:integer@ ms", "took ms", false, nil},
+		{"link @MACADDR:srcmac@ down", "link 00:04:c1:8b:d8:82 down", true, map[string]string{"srcmac": "00:04:c1:8b:d8:82"}},
+		{"to=@QSTRING:string:<>@, done", "to=<a@b.com>, done", true, map[string]string{"string": "a@b.com"}},
+		{"\"@QSTRING:object:\"@ ok", "\"\"my file\"\" ok", false, nil},
+		{"mail @EMAIL:srcemail:@ sent", "mail user@example.com sent", true, map[string]string{"srcemail": "user@example.com"}},
+		{"from @HOSTNAME:srchost@", "from mail.example.com", true, map[string]string{"srchost": "mail.example.com"}},
+		{"@@@ESTRING:string:,@x", "@abc,x", true, map[string]string{"string": "abc"}},
+		{"error: @ANYSTRING:reason@", "error: disk full", true, map[string]string{"reason": "disk full"}},
+		{"@PCRE:timestamp:(?>\\d\\d){1,2}-(?:0?[1-9]|1[0-2])-(?:[0-3]?[0-9])(?![0-9])@ done", "2020-01-15 done", true, map[string]string{"timestamp": "2020-01-15"}},
+		{"@ESTRING:string: @@ESTRING:string1:@", "a b c", true, map[string]string{"string": "a", "string1": "b c"}},
+	}
+)
+
+func TestMatchPdb(t *testing.T) {
+	for _, tc := range matchtests {
+		nodes, err := parsePdbPattern(tc.pattern)
+		require.NoError(t, err, tc.pattern)
+		values, ok, err := matchPdb(nodes, tc.msg)
+		require.NoError(t, err, tc.pattern)
+		require.Equal(t, tc.match, ok, tc.pattern+" "+tc.msg)
+		if tc.match {
+			require.Equal(t, tc.values, values, tc.pattern)
+		}
+	}
+
+	nodes, err := parsePdbPattern("@SET:x: @")
+	require.NoError(t, err)
+	_, _, err = matchPdb(nodes, "a")
+	require.Error(t, err)
+
+	_, err = parsePdbPattern("abc @ESTRING:x: ")
+	require.Error(t, err)
+}
+
+func TestValidateRule(t *testing.T) {
+	loadConfigs()
+	tests := []struct {
+		pattern, pos string
+		examples     []string
+		failed       bool
+	}{
+		{"%srcuser% logged in from %srcip%", "0,25", []string{"root logged in from 10.1.2.3", "admin logged in from 10.1.2.4"}, false},
+		//the README case, the last string would match any number of words
+		{"%string% %string%", "0,9", []string{"hello world"}, true},
+		//the example does not match the pattern
+		{"%srcuser% logged in from %srcip%", "0,25", []string{"root logged in from somewhere"}, true},
+	}
+	for _, tc := range tests {
+		ar := sequence.AnalyzerResult{PatternId: "id", Pattern: tc.pattern, TagPositions: tc.pos, Service: models.Service{Name: "svc"}}
+		for _, ex := range tc.examples {
+			ar.Examples = append(ar.Examples, sequence.LogRecord{Service: "svc", Message: ex})
+		}
+		vr := validateRule(ar, replaceTags(tc.pattern, nil))
+		require.Equal(t, tc.failed, len(vr.Reasons) > 0, "%s %v", tc.pattern, vr.Reasons)
+	}
+}
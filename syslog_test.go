@@ -0,0 +1,166 @@
+package sequence
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	syslogTests = []struct {
+		data    string
+		service string
+		message string
+	}{
+		//RFC3164
+		{
+			"<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8",
+			"su",
+			"'su root' failed for lonvick on /dev/pts/8",
+		},
+		{
+			"<38>Jan  2 06:49:42 irc sshd[7034]: Failed password for root from 218.161.81.238 port 4228 ssh2",
+			"sshd",
+			"Failed password for root from 218.161.81.238 port 4228 ssh2",
+		},
+		{
+			"<13>Feb 25 14:09:07 webserver syslogd[123] restart",
+			"syslogd",
+			"restart",
+		},
+		{
+			//no hostname
+			"<13>Feb 25 14:09:07 kernel: usb 1-1: new high-speed USB device",
+			"kernel",
+			"usb 1-1: new high-speed USB device",
+		},
+		{
+			//no timestamp or hostname
+			"<13>cron[42]: (root) CMD (run-parts /etc/cron.hourly)",
+			"cron",
+			"(root) CMD (run-parts /etc/cron.hourly)",
+		},
+		{
+			//no tag
+			"<13>Feb 25 14:09:07 host just a message without a tag",
+			"none",
+			"just a message without a tag",
+		},
+		//RFC5424
+		{
+			"<34>1 2003-10-11T22:14:15.003Z mymachine.example.com su - ID47 - 'su root' failed for lonvick on /dev/pts/8",
+			"su",
+			"'su root' failed for lonvick on /dev/pts/8",
+		},
+		{
+			"<165>1 2003-08-24T05:14:15.000003-07:00 192.0.2.1 myproc 8710 - - %% It's time to make the do-nuts.",
+			"myproc",
+			"%% It's time to make the do-nuts.",
+		},
+		{
+			"<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut=\"3\" eventSource=\"Application\" eventID=\"1011\"][examplePriority@32473 class=\"high\"] \ufeffAn application event log entry...",
+			"evntslog",
+			"An application event log entry...",
+		},
+		{
+			"<165>1 2003-10-11T22:14:15.003Z host app - - [id@1 msg=\"escaped \\] bracket\"] message after escaped sd",
+			"app",
+			"message after escaped sd",
+		},
+		{
+			"<14>1 2003-10-11T22:14:15.003Z host - - - - message without an app name",
+			"none",
+			"message without an app name",
+		},
+	}
+
+	syslogErrorTests = []struct {
+		data string
+		err  error
+	}{
+		{"", ErrSyslogEmpty},
+		{"no priority here", ErrSyslogPriority},
+		{"<999>Oct 11 22:14:15 host su: message", ErrSyslogPriority},
+		{"<34>1 2003-10-11T22:14:15.003Z host", ErrSyslogHeader},
+		{"<34>1 2003-10-11T22:14:15.003Z host app - - [unclosed sd", ErrSyslogHeader},
+	}
+)
+
+func TestSyslogParseMessage(t *testing.T) {
+	for _, tc := range syslogTests {
+		r, err := ParseSyslogMessage(tc.data)
+		require.NoError(t, err, tc.data)
+		require.Equal(t, tc.service, r.Service, tc.data)
+		require.Equal(t, tc.message, r.Message, tc.data)
+	}
+
+	for _, tc := range syslogErrorTests {
+		_, err := ParseSyslogMessage(tc.data)
+		require.Equal(t, tc.err, err, tc.data)
+	}
+}
+
+func TestSyslogReadFrame(t *testing.T) {
+	msgs := []string{
+		"<34>1 2003-10-11T22:14:15.003Z host su - ID47 - message one",
+		"<34>Oct 11 22:14:15 host su: message two\nwith a new line",
+		"<34>Oct 11 22:14:15 host su: message three",
+	}
+	//two octet counted frames followed by a new line terminated one
+	var data string
+	for _, m := range msgs[:2] {
+		data += fmt.Sprintf("%d %s", len(m), m)
+	}
+	data += msgs[2] + "\n"
+
+	r := bufio.NewReader(strings.NewReader(data))
+	for _, m := range msgs {
+		frame, err := ReadSyslogFrame(r)
+		require.NoError(t, err)
+		require.Equal(t, m, frame)
+	}
+	_, err := ReadSyslogFrame(r)
+	require.Error(t, err)
+
+	r = bufio.NewReader(strings.NewReader("99999999 <34>too long"))
+	_, err = ReadSyslogFrame(r)
+	require.Equal(t, ErrSyslogFrameSize, err)
+}
+
+func TestSyslogServer(t *testing.T) {
+	srv := NewSyslogServer(10)
+	require.NoError(t, srv.ListenUDP("127.0.0.1:0"))
+	require.NoError(t, srv.ListenTCP("127.0.0.1:0"))
+	defer srv.Close()
+
+	uc, err := net.Dial("udp", srv.udp.LocalAddr().String())
+	require.NoError(t, err)
+	defer uc.Close()
+	_, err = uc.Write([]byte(syslogTests[0].data))
+	require.NoError(t, err)
+	checkSyslogRecord(t, srv, syslogTests[0].service, syslogTests[0].message)
+
+	tc, err := net.Dial("tcp", srv.tcp.Addr().String())
+	require.NoError(t, err)
+	defer tc.Close()
+	m := syslogTests[6].data
+	_, err = fmt.Fprintf(tc, "%d %s%s\n", len(m), m, syslogTests[1].data)
+	require.NoError(t, err)
+	checkSyslogRecord(t, srv, syslogTests[6].service, syslogTests[6].message)
+	checkSyslogRecord(t, srv, syslogTests[1].service, syslogTests[1].message)
+}
+
+func checkSyslogRecord(t *testing.T, srv *SyslogServer, service, message string) {
+	select {
+	case r := <-srv.Records:
+		require.Equal(t, service, r.Service)
+		require.Equal(t, message, r.Message)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for syslog record %q", message)
+	}
+}
@@ -15,14 +15,18 @@
 package sequence
 
 import (
+	"errors"
+	"fmt"
+	"sort"
 	"strings"
+	"time"
 )
 
 type timeNode struct {
 	ntype     int
 	value     rune
 	final     TokenType
-	subtype   int
+	subtypes  []int
 	regextype string
 	children  []*timeNode
 }
@@ -81,8 +85,11 @@ func buildTimeFSM(fmts map[int][]string) *timeNode {
 			parent = child
 		}
 
+		// the formats with the same shape end on the same node, the first one is the layout
+		// of the time so it needs to be the one that parses all of them, eg Jan _2 before Jan 02
 		parent.final = TokenTime
-		parent.subtype = i
+		parent.subtypes = append(parent.subtypes, i)
+		sort.Ints(parent.subtypes)
 		parent.regextype = fm[1]
 	}
 
@@ -122,3 +129,85 @@ func timeStep(r rune, cur *timeNode) (*timeNode, bool) {
 
 	return cur, true
 }
+
+// Checks the default year and the timezone the times with no year or zone are normalized
+// with, the year 0 is the current year and the timezone is UTC when it is not set.
+func validateTimeSettingsConfig(year int, timezone string) error {
+	if year < 0 || year > 9999 {
+		return fmt.Errorf("the timesettings defaultyear must be 0 for the current year or a year up to 9999, not %d", year)
+	}
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return fmt.Errorf("the timesettings timezone %s is not a timezone: %s", timezone, err.Error())
+	}
+	timesettings.defaultYear = year
+	timesettings.location = loc
+	return nil
+}
+
+// Returns the time token of the value that ended on the final node of the fsm, with the Go
+// layout of the first format of the node. The formats of a node have the same shape, eg
+// Jan _2 and Jan 02, so only the first one is parsed, once, to check the value. The regex
+// type is the one of the node the fsm stopped on. A value that the layout does not parse
+// because the date is impossible, eg Feb 30, is not a time, a value that it does not parse
+// for another reason is a time with no layout.
+func timeToken(value string, n *timeNode, regextype string) (Token, bool) {
+	tok := Token{Type: TokenTime, Tag: TagUnknown, Special: regextype}
+	if regextype != "" {
+		tok.Tag = TagRegExTime
+	}
+	if len(n.subtypes) == 0 {
+		return tok, true
+	}
+	layout := timesettings.formats[n.subtypes[0]][0]
+	_, err := time.Parse(goLayout(layout), value)
+	if err == nil {
+		tok.Layout = layout
+		return tok, true
+	}
+	var pe *time.ParseError
+	if errors.As(err, &pe) && strings.HasSuffix(pe.Message, "out of range") {
+		return tok, false
+	}
+	return tok, true
+}
+
+// Returns the layout to parse a format with, a Z at the end of a format is UTC.
+func goLayout(layout string) string {
+	if strings.HasSuffix(layout, "Z") {
+		return layout[:len(layout)-1] + "Z07:00"
+	}
+	return layout
+}
+
+// Returns the time in RFC 3339, parsed with the Go layout of the format the scanner matched.
+// A time with no year is given the default year of the config, and a time with no zone the
+// timezone of the config. A time of day with no date can not be normalized.
+func NormalizeTime(value string, layout string) (string, error) {
+	if layout == "" {
+		return "", fmt.Errorf("the time %s did not match the layout of a format", value)
+	}
+	t, err := time.ParseInLocation(goLayout(layout), value, timesettings.location)
+	if err != nil {
+		return "", err
+	}
+	if t.Year() == 0 {
+		if !strings.Contains(layout, "Jan") {
+			return "", fmt.Errorf("the time %s has no date", value)
+		}
+		year := timesettings.defaultYear
+		if year == 0 {
+			year = time.Now().In(timesettings.location).Year()
+		}
+		// the day is checked again as Feb 29 is only a date in a leap year
+		d := time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		if d.Day() != t.Day() {
+			return "", fmt.Errorf("the time %s is not a date in %d", value, year)
+		}
+		t = d
+	}
+	return t.Format(time.RFC3339Nano), nil
+}
@@ -0,0 +1,420 @@
+package sequence
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+//A format for a shape of time found in the messages that the formats of the config do not
+//match, with the Go layout for [timesettings.formats] and the regexes for the exporters.
+type TimeFormatProposal struct {
+	Layout    string
+	RegexType string //the key of the regexes in [timesettings.regex] and [timesettings.grok], empty for a layout with no spaces
+	Regex     string //PCRE
+	Grok      string
+	Shape     string //the time with each digit replaced by 9
+	Count     int    //the number of times in the messages that parse with the layout
+	Example   string
+}
+
+//Collects the runs of digits and separators in the messages that look like times and that
+//the scanner does not scan as a time, grouped by their shape.
+type TimeAnalyzer struct {
+	scanner *Scanner
+	shapes  map[string]*timeShape
+}
+
+type timeShape struct {
+	values []string
+	count  int
+}
+
+//The most values of a shape kept to work out the order of the day and month and to check
+//the layout with.
+const timeShapeValues = 1000
+
+//A date of three numbers with the same separator, a time of day of hours and minutes with
+//seconds and a fraction, and a zone. The date and the time can be on their own or the date
+//followed by the time.
+var (
+	timeDateRegex      = `(?:\d{1,4}-\d{1,2}-\d{1,4}|\d{1,4}/\d{1,2}/\d{1,4}|\d{1,4}\.\d{1,2}\.\d{1,4})`
+	timeOfDayRegex     = `\d{1,2}:\d{2}(?::\d{2}(?:[.,]\d{1,9})?)?`
+	timeCandidateRegex = regexp.MustCompile(`(?:` + timeDateRegex + `[ T:-]` + timeOfDayRegex + `|` + timeDateRegex + `|` + timeOfDayRegex + `)(?: ?(?:Z|[+-]\d{2}:?\d{2}))?`)
+	timeZoneRegex      = regexp.MustCompile(` ?(?:Z|[+-]\d{2}:?\d{2})$`)
+	timeOfDayEndRegex  = regexp.MustCompile(timeOfDayRegex + `$`)
+	timeFieldRegex     = regexp.MustCompile(`\d+|\D+`)
+)
+
+func NewTimeAnalyzer() *TimeAnalyzer {
+	return &TimeAnalyzer{scanner: NewScanner(), shapes: make(map[string]*timeShape)}
+}
+
+//Adds the times of the message that the scanner does not scan as a time.
+func (this *TimeAnalyzer) Add(msg string) {
+	for _, loc := range timeCandidateRegex.FindAllStringIndex(msg, -1) {
+		if !isTimeCandidate(msg, loc[0], loc[1]) {
+			continue
+		}
+		value := msg[loc[0]:loc[1]]
+		if seq, _, err := this.scanner.Scan(value, false, nil); err == nil && len(seq) == 1 && seq[0].Type == TokenTime {
+			continue
+		}
+		shape := timeValueShape(value)
+		ts, ok := this.shapes[shape]
+		if !ok {
+			ts = &timeShape{}
+			this.shapes[shape] = ts
+		}
+		ts.count++
+		if len(ts.values) < timeShapeValues {
+			ts.values = append(ts.values, value)
+		}
+	}
+}
+
+//Returns true if the run is not part of a longer word or number and looks like a time, a
+//date on its own must have a four digit year so a version number is not taken for one.
+func isTimeCandidate(msg string, start int, end int) bool {
+	if start > 0 && (isAlnum(msg[start-1]) || strings.IndexByte(".:/-+", msg[start-1]) >= 0) {
+		return false
+	}
+	if end < len(msg) {
+		c := msg[end]
+		if isAlnum(c) || strings.IndexByte(":/-+", c) >= 0 || (c == '.' && end+1 < len(msg) && msg[end+1] != ' ') {
+			return false
+		}
+	}
+	value := msg[start:end]
+	if strings.Contains(value, ":") {
+		return true
+	}
+	for _, f := range timeFieldRegex.FindAllString(value, -1) {
+		if len(f) == 4 && f[0] >= '0' && f[0] <= '9' {
+			return true
+		}
+	}
+	return false
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+//Returns the value with each digit replaced by 9.
+func timeValueShape(value string) string {
+	b := []byte(value)
+	for i, c := range b {
+		if c >= '0' && c <= '9' {
+			b[i] = '9'
+		}
+	}
+	return string(b)
+}
+
+//Returns the formats for the shapes seen at least minCount times, ordered by the count. A
+//shape is left out when the order of its date can not be worked out, when its layout is
+//already in the config or when fewer than minCount of its values parse with the layout.
+func (this *TimeAnalyzer) Proposals(minCount int) []TimeFormatProposal {
+	known := make(map[string]bool, len(timesettings.formats))
+	for _, f := range timesettings.formats {
+		known[f[0]] = true
+	}
+	var list []TimeFormatProposal
+	for shape, ts := range this.shapes {
+		if ts.count < minCount {
+			continue
+		}
+		p, ok := proposeTimeFormat(ts.values)
+		if !ok || known[p.Layout] {
+			continue
+		}
+		//the values that are not dates, eg Feb 30, do not count
+		parsed := 0
+		for _, v := range ts.values {
+			if _, err := time.Parse(goLayout(p.Layout), v); err == nil {
+				parsed++
+			}
+		}
+		if parsed == 0 {
+			continue
+		}
+		p.Count = ts.count * parsed / len(ts.values)
+		if p.Count < minCount {
+			continue
+		}
+		p.Shape, p.Example = shape, ts.values[0]
+		known[p.Layout] = true
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Layout < list[j].Layout
+	})
+	//the layouts with spaces need a regex for the exporters, they get the next free keys
+	next := 0
+	for i := range list {
+		if !strings.Contains(list[i].Layout, " ") {
+			continue
+		}
+		for ; timeRegexTypeUsed(strconv.Itoa(next)); next++ {
+		}
+		list[i].RegexType = strconv.Itoa(next)
+		next++
+	}
+	return list
+}
+
+func timeRegexTypeUsed(id string) bool {
+	_, ok := timesettings.regex[id]
+	_, gok := timesettings.grok[id]
+	return ok || gok
+}
+
+//Returns the layout and regexes for the values of a shape, the zone and the time of day are
+//taken off the end and the rest is the date.
+func proposeTimeFormat(values []string) (TimeFormatProposal, bool) {
+	var (
+		p          TimeFormatProposal
+		value      = values[0]
+		zone, tod  string
+		layout     strings.Builder
+		rg, grk    strings.Builder
+		literalSep = func(s string) {
+			layout.WriteString(s)
+			rg.WriteString(regexp.QuoteMeta(s))
+			grk.WriteString(regexp.QuoteMeta(s))
+		}
+	)
+	//a zone is only taken off a time of day, eg the -10:20 of 2021/10/05-10:20 is the time
+	if zone = timeZoneRegex.FindString(value); zone != "" && strings.Contains(value[:len(value)-len(zone)], ":") {
+		value = value[:len(value)-len(zone)]
+	} else {
+		zone = ""
+	}
+	if tod = timeOfDayEndRegex.FindString(value); tod != "" {
+		value = value[:len(value)-len(tod)]
+	}
+	if value != "" {
+		//the date is followed by the separator of the time
+		sep := ""
+		if tod != "" {
+			sep, value = value[len(value)-1:], value[:len(value)-1]
+		}
+		fields := timeFieldRegex.FindAllString(value, -1)
+		if len(fields) != 5 {
+			return p, false
+		}
+		order, ok := timeDateOrder(values, fields)
+		if !ok {
+			return p, false
+		}
+		for i, role := range order {
+			if i > 0 {
+				literalSep(fields[2*i-1])
+			}
+			f := fields[2*i]
+			switch {
+			case role == 'y' && len(f) == 4:
+				layout.WriteString("2006")
+				rg.WriteString(`\d{4}`)
+				grk.WriteString("%{YEAR}")
+			case role == 'y' && len(f) == 2:
+				layout.WriteString("06")
+				rg.WriteString(`\d{2}`)
+				grk.WriteString("%{YEAR}")
+			case role == 'm' && len(f) == 2:
+				layout.WriteString("01")
+				rg.WriteString(`(?:0[1-9]|1[0-2])`)
+				grk.WriteString("%{MONTHNUM}")
+			case role == 'm' && len(f) == 1:
+				layout.WriteString("1")
+				rg.WriteString(`[1-9]`)
+				grk.WriteString("%{MONTHNUM}")
+			case role == 'd' && len(f) == 2:
+				layout.WriteString("02")
+				rg.WriteString(`(?:0[1-9]|[12][0-9]|3[01])`)
+				grk.WriteString("%{MONTHDAY}")
+			case role == 'd' && len(f) == 1:
+				layout.WriteString("2")
+				rg.WriteString(`[1-9]`)
+				grk.WriteString("%{MONTHDAY}")
+			default:
+				return p, false
+			}
+		}
+		literalSep(sep)
+	}
+	if tod != "" {
+		fields := timeFieldRegex.FindAllString(tod, -1)
+		//a one digit hour is a 12 hour clock hour in the layout, as the 24 hour one has two digits
+		if len(fields[0]) == 1 {
+			layout.WriteString("3")
+			rg.WriteString(`[0-9]`)
+		} else {
+			layout.WriteString("15")
+			rg.WriteString(`(?:[01][0-9]|2[0-3])`)
+		}
+		grk.WriteString("%{HOUR}")
+		layout.WriteString(":04")
+		rg.WriteString(`:[0-5][0-9]`)
+		grk.WriteString(":%{MINUTE}")
+		if len(fields) > 3 {
+			layout.WriteString(":05")
+			rg.WriteString(`:(?:[0-5][0-9]|60)`)
+			grk.WriteString(":%{SECOND}")
+		}
+		//the fraction is part of the grok SECOND
+		if len(fields) > 5 {
+			layout.WriteString(fields[5] + strings.Repeat("0", len(fields[6])))
+			rg.WriteString(regexp.QuoteMeta(fields[5]) + `\d{` + strconv.Itoa(len(fields[6])) + `}`)
+		}
+	}
+	if zone != "" {
+		sp := ""
+		if zone[0] == ' ' {
+			sp, zone = " ", zone[1:]
+		}
+		layout.WriteString(sp)
+		rg.WriteString(sp)
+		grk.WriteString(sp)
+		switch {
+		case zone == "Z":
+			layout.WriteString("Z")
+			rg.WriteString("Z")
+		case strings.Contains(zone, ":"):
+			layout.WriteString("-07:00")
+			rg.WriteString(`[+-]\d{2}:\d{2}`)
+		default:
+			layout.WriteString("-0700")
+			rg.WriteString(`[+-]\d{4}`)
+		}
+		grk.WriteString("%{ISO8601_TIMEZONE}")
+	}
+	p.Layout, p.Regex, p.Grok = layout.String(), rg.String(), grk.String()
+	return p, true
+}
+
+//Returns the roles of the three numbers of the dates, y for the year, m for the month and d
+//for the day. A four digit number is the year, a number over 31 is the year and a number
+//over 12 is the day, when the day and month can not be told apart the month is first. It
+//returns false when the year can not be found.
+func timeDateOrder(values []string, fields []string) ([]byte, bool) {
+	var max [3]int
+	for _, v := range values {
+		parts := timeFieldRegex.FindAllString(v, -1)
+		for i := 0; i < 3 && 2*i < len(parts); i++ {
+			if n, err := strconv.Atoi(parts[2*i]); err == nil && n > max[i] {
+				max[i] = n
+			}
+		}
+	}
+	year := -1
+	for i := 0; i < 3; i += 2 {
+		if len(fields[2*i]) == 4 || max[i] > 31 {
+			year = i
+			break
+		}
+	}
+	switch {
+	case year == 0 && max[1] > 12:
+		return []byte("ydm"), true
+	case year == 0:
+		return []byte("ymd"), true
+	case year == 2 && max[0] > 12:
+		return []byte("dmy"), true
+	case year == 2:
+		return []byte("mdy"), true
+	}
+	return nil, false
+}
+
+//Adds the proposed formats to the config file, the layouts to [timesettings.formats] after
+//the formats in the config read, and the regexes of the layouts with spaces to
+//[timesettings.regex] and [timesettings.grok]. The lines are added to the end of each
+//table so the comments and the order of the file are kept.
+func WriteTimeFormats(fname string, proposals []TimeFormatProposal) error {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	next := 0
+	for i := range timesettings.formats {
+		if i >= next {
+			next = i + 1
+		}
+	}
+	var formats, regexes, groks []string
+	for _, p := range proposals {
+		formats = append(formats, fmt.Sprintf("%d = [%q, %q]", next, p.Layout, p.RegexType))
+		next++
+		if p.RegexType != "" {
+			regexes = append(regexes, fmt.Sprintf("%q = '%s'", p.RegexType, p.Regex))
+			groks = append(groks, fmt.Sprintf("%q = '%s'", p.RegexType, p.Grok))
+		}
+	}
+	lines := strings.Split(string(data), "\n")
+	for _, t := range []struct {
+		table string
+		add   []string
+	}{{"timesettings.formats", formats}, {"timesettings.regex", regexes}, {"timesettings.grok", groks}} {
+		if len(t.add) == 0 {
+			continue
+		}
+		if lines, err = appendTomlTable(lines, t.table, t.add); err != nil {
+			return err
+		}
+	}
+	out := strings.Join(lines, "\n")
+	var check map[string]interface{}
+	if _, err = toml.Decode(out, &check); err != nil {
+		return fmt.Errorf("the config with the time formats added does not parse: %s", err.Error())
+	}
+	info, err := os.Stat(fname)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fname, []byte(out), info.Mode())
+}
+
+//Returns the lines of the toml file with the entries added after the last entry of the
+//table, with the same indentation.
+func appendTomlTable(lines []string, table string, add []string) ([]string, error) {
+	start := -1
+	for i, l := range lines {
+		if strings.TrimSpace(l) == "["+table+"]" {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return lines, fmt.Errorf("the config has no [%s] table", table)
+	}
+	last := start
+	indent := lines[start][:len(lines[start])-len(strings.TrimLeft(lines[start], " \t"))] + "    "
+	for i := start + 1; i < len(lines); i++ {
+		l := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(l, "[") {
+			break
+		}
+		if l != "" && !strings.HasPrefix(l, "#") {
+			last = i
+			indent = lines[i][:len(lines[i])-len(strings.TrimLeft(lines[i], " \t"))]
+		}
+	}
+	added := make([]string, len(add))
+	for i, a := range add {
+		added[i] = indent + a
+	}
+	out := append(append(append([]string{}, lines[:last+1]...), added...), lines[last+1:]...)
+	return out, nil
+}
@@ -0,0 +1,101 @@
+package sequence
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProposeTimeFormat(t *testing.T) {
+	var tests = []struct {
+		values []string
+		layout string
+		grok   string
+	}{
+		{[]string{"2021.10.05 10:20:30"}, "2006.01.02 15:04:05", `%{YEAR}\.%{MONTHNUM}\.%{MONTHDAY} %{HOUR}:%{MINUTE}:%{SECOND}`},
+		{[]string{"05/10/2021 10:20:30.123", "13/10/2021 10:20:30.456"}, "02/01/2006 15:04:05.000", `%{MONTHDAY}/%{MONTHNUM}/%{YEAR} %{HOUR}:%{MINUTE}:%{SECOND}`},
+		{[]string{"10/05/2021 9:20"}, "01/02/2006 3:04", `%{MONTHNUM}/%{MONTHDAY}/%{YEAR} %{HOUR}:%{MINUTE}`},
+		{[]string{"2021-10-05T10:20:30.123+02:00"}, "2006-01-02T15:04:05.000-07:00", `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}T%{HOUR}:%{MINUTE}:%{SECOND}%{ISO8601_TIMEZONE}`},
+		{[]string{"2021/10/05-10:20"}, "2006/01/02-15:04", `%{YEAR}/%{MONTHNUM}/%{MONTHDAY}-%{HOUR}:%{MINUTE}`},
+		{[]string{"10:20:30,123 Z"}, "15:04:05,000 Z", `%{HOUR}:%{MINUTE}:%{SECOND} %{ISO8601_TIMEZONE}`},
+	}
+	for _, tc := range tests {
+		p, ok := proposeTimeFormat(tc.values)
+		require.True(t, ok, tc.values[0])
+		require.Equal(t, tc.layout, p.Layout, tc.values[0])
+		require.Equal(t, tc.grok, p.Grok, tc.values[0])
+		re := regexp.MustCompile("^" + p.Regex + "$")
+		for _, v := range tc.values {
+			require.True(t, re.MatchString(v), "%s does not match %s", p.Regex, v)
+		}
+	}
+	//the year of a date with two digit numbers that are all 12 or less can not be found
+	_, ok := proposeTimeFormat([]string{"10-05-11 10:20:30"})
+	require.False(t, ok)
+}
+
+func TestTimeAnalyzer(t *testing.T) {
+	ta := NewTimeAnalyzer()
+	for _, msg := range []string{
+		"2021.10.05 10:20:30 disk full on /dev/sda1",
+		"2021.10.06 11:20:30 disk full on /dev/sdb1",
+		"2021.02.30 11:20:30 disk full on /dev/sdb1",
+		"job started 13/10/2021 10:20:30.123, version 1.2.3 from 10.1.2.3",
+		"job started 14/10/2021 10:20:30.456, version 1.2.4 from 10.1.2.4",
+		"2021-10-05 10:20:30 already a time",
+	} {
+		ta.Add(msg)
+	}
+	list := ta.Proposals(2)
+	require.Len(t, list, 2)
+	//the counts are the same so the layouts are in order, the impossible date does not count
+	require.Equal(t, "02/01/2006 15:04:05.000", list[0].Layout)
+	require.Equal(t, "6", list[0].RegexType)
+	require.Equal(t, TimeFormatProposal{Layout: "2006.01.02 15:04:05", RegexType: "7", Regex: list[1].Regex, Grok: list[1].Grok,
+		Shape: "9999.99.99 99:99:99", Count: 2, Example: "2021.10.05 10:20:30"}, list[1])
+	require.Len(t, ta.Proposals(3), 0)
+}
+
+func TestWriteTimeFormats(t *testing.T) {
+	data, err := ioutil.ReadFile("sequence.toml")
+	require.NoError(t, err)
+	fname := filepath.Join(t.TempDir(), "sequence.toml")
+	require.NoError(t, ioutil.WriteFile(fname, data, 0644))
+	t.Cleanup(func() { ReadConfig("sequence.toml") })
+
+	ta := NewTimeAnalyzer()
+	ta.Add("2021.10.05 10:20:30 disk full")
+	ta.Add("job done at 2021-10-05T10:20:30.123+02:00")
+	list := ta.Proposals(1)
+	require.Len(t, list, 2)
+	//the layout with no spaces needs no regex
+	require.Equal(t, "2006-01-02T15:04:05.000-07:00", list[0].Layout)
+	require.Equal(t, "", list[0].RegexType)
+	require.NoError(t, WriteTimeFormats(fname, list))
+	require.NoError(t, ReadConfig(fname))
+
+	//the times are scanned with the new layouts and the exporters have the regexes
+	seq, _, err := NewScanner().Scan("2021.10.05 10:20:30 disk full", false, nil)
+	require.NoError(t, err)
+	require.Equal(t, Token{Type: TokenTime, Tag: TagRegExTime, Value: "2021.10.05 10:20:30", Special: "6", Layout: "2006.01.02 15:04:05"}, seq[0])
+	rg, ok := GetTimeSettingsRegExValue("6")
+	require.True(t, ok)
+	require.Equal(t, list[1].Regex, rg)
+	grok, ok := GetTimeSettingsGrokValue("6")
+	require.True(t, ok)
+	require.Equal(t, list[1].Grok, grok)
+	seq, _, err = NewScanner().Scan("job done at 2021-10-05T10:20:30.123+02:00", false, nil)
+	require.NoError(t, err)
+	require.Equal(t, "2006-01-02T15:04:05.000-07:00", seq[3].Layout)
+	v, err := NormalizeTime(seq[3].Value, seq[3].Layout)
+	require.NoError(t, err)
+	require.Equal(t, "2021-10-05T10:20:30.123+02:00", v)
+
+	//nothing is proposed for the times that are now in the config
+	ta = NewTimeAnalyzer()
+	ta.Add("2021.10.07 10:20:30 disk full")
+	require.Empty(t, ta.Proposals(1))
+}
@@ -34,6 +34,7 @@ type Token struct {
 	Tag           TagType   // Tag determines which tag the Value should be.
 	Value         string    // Value is the extracted string from the log message.
 	Special       string    // % is reserved for the tokens, if a literal contains one it must become a string, this also stores the regex index for the RegExTimeTag
+	Layout        string    // Layout is the Go layout of the [timesettings.formats] format that a time token matched
 	IsSpaceBefore bool      // Is there token a space before this token
 
 	isValue bool // Is this token a key in k=v pair
@@ -62,6 +63,12 @@ const (
 	TokenMac                        // Token is a mac address
 	TokenString                     // Token is a string that represents multiple possible values
 	TokenMultiLine                  // Token represents every thing after the first \n in a message
+	TokenUUID                       // Token is a UUID, in the form of 8-4-4-4-12 hex digits
+	TokenHex                        // Token is a hex string of 16 or more digits, such as a hash
+	TokenBase64                     // Token is a base64 string of 20 or more characters, such as a session token
+	TokenIPv4CIDR                   // Token is an IPv4 network with its prefix length, such as 10.0.0.0/8
+	TokenIPv6CIDR                   // Token is an IPv6 network with its prefix length, such as 2001:db8::/32
+	TokenHostPort                   // Token is an IPv4 address, [IPv6] address or host name with a port
 	token__END__                    // All tag types must be inserted before this one
 	token__host__                   // Token is a host name
 	token__email__                  // Token is an email address
@@ -81,15 +88,34 @@ var tokens = [...]struct {
 	{"mac"},
 	{"string"},
 	{"multiline"},
+	{"uuid"},
+	{"hex"},
+	{"base64"},
+	{"ipv4cidr"},
+	{"ipv6cidr"},
+	{"hostport"},
 	{"token__END__"},
 	{"token__host__"},
 	{"token__email__"},
 }
 
 func (this TokenType) String() string {
+	if c, ok := customType(this); ok {
+		return c.name
+	}
 	return tokens[this].label
 }
 
+// The scanner returned the tokens of these types as literals before it recognised them,
+// so they also match the literals and strings of the patterns saved before.
+func (this TokenType) matchesLiteral() bool {
+	switch this {
+	case TokenUUID, TokenHex, TokenBase64:
+		return true
+	}
+	return this.IsCustom()
+}
+
 func (this TagType) String() string {
 	return config.tagNames[this]
 }
@@ -126,6 +152,18 @@ func name2TokenType(s string) TokenType {
 		return TokenString
 	case "multiline":
 		return TokenMultiLine
+	case "uuid":
+		return TokenUUID
+	case "hex":
+		return TokenHex
+	case "base64":
+		return TokenBase64
+	case "ipv4cidr":
+		return TokenIPv4CIDR
+	case "ipv6cidr":
+		return TokenIPv6CIDR
+	case "hostport":
+		return TokenHostPort
 	case "token__END__":
 		return token__END__
 	case "token__host__":
@@ -134,7 +172,7 @@ func name2TokenType(s string) TokenType {
 		return token__email__
 	}
 
-	return TokenUnknown
+	return customTypeByName(s)
 }
 
 func name2TagType(s string) TagType {
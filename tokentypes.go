@@ -0,0 +1,151 @@
+package sequence
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+//The token types declared in the [tokentypes] section of the config are numbered from here,
+//after the built in and internal types, in the order of their names.
+const tokenCustomStart = token__email__ + 1
+
+//A token type declared in the config, the scanner emits it for a token that matches the
+//regular expression, which is either set directly or compiled from the shape.
+type customTokenType struct {
+	name  string
+	regex string
+	re    *regexp.Regexp
+}
+
+var tokenTypeNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+//Converts a shape to a regular expression. In a shape 9 is a digit, a is a letter, x is a
+//hex digit and w is a letter or digit, a + after one of these means one or more of them
+//and every other character is itself, eg xxxxxxxx-xxxx for 8 and 4 hex digits or 9+.9+.9+
+//for a version number.
+func shapeToRegex(shape string) string {
+	var sb strings.Builder
+	for i := 0; i < len(shape); i++ {
+		switch shape[i] {
+		case '9':
+			sb.WriteString(`[0-9]`)
+		case 'a':
+			sb.WriteString(`[A-Za-z]`)
+		case 'x':
+			sb.WriteString(`[0-9A-Fa-f]`)
+		case 'w':
+			sb.WriteString(`[0-9A-Za-z]`)
+		default:
+			sb.WriteString(regexp.QuoteMeta(shape[i : i+1]))
+			continue
+		}
+		if i+1 < len(shape) && shape[i+1] == '+' {
+			sb.WriteString("+")
+			i++
+		}
+	}
+	return sb.String()
+}
+
+//Checks the token types of the config and numbers them, the names must not be the name
+//of a built in type and each type has either a regex or a shape.
+func validateTokenTypesConfig(defs map[string]struct{ Regex, Shape string }) error {
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tokentypes.custom = tokentypes.custom[:0]
+	for _, name := range names {
+		def := defs[name]
+		if !tokenTypeNameRegex.MatchString(name) {
+			return fmt.Errorf("the token type %s must start with a lower case letter and only have lower case letters, digits and underscores", name)
+		}
+		if name2TokenType(name) != TokenUnknown || name == TokenUnknown.String() {
+			return fmt.Errorf("the token type %s is already a built in token type", name)
+		}
+		if (def.Regex == "") == (def.Shape == "") {
+			return fmt.Errorf("the token type %s must have either a regex or a shape", name)
+		}
+		rg := def.Regex
+		if rg == "" {
+			rg = shapeToRegex(def.Shape)
+		}
+		re, err := regexp.Compile(`^(?:` + rg + `)`)
+		if err != nil {
+			return fmt.Errorf("the regex of the token type %s does not compile: %s", name, err.Error())
+		}
+		if re.MatchString("") {
+			return fmt.Errorf("the regex of the token type %s matches an empty string", name)
+		}
+		tokentypes.custom = append(tokentypes.custom, customTokenType{name: name, regex: rg, re: re})
+	}
+	TokenTypesCount = int(tokenCustomStart) + len(tokentypes.custom)
+	return nil
+}
+
+//Returns the token type declared in the config, if the type is one.
+func customType(tt TokenType) (customTokenType, bool) {
+	if i := int(tt - tokenCustomStart); tt >= tokenCustomStart && i < len(tokentypes.custom) {
+		return tokentypes.custom[i], true
+	}
+	return customTokenType{}, false
+}
+
+//Returns the token type declared in the config with the name, or TokenUnknown.
+func customTypeByName(name string) TokenType {
+	for i, c := range tokentypes.custom {
+		if c.name == name {
+			return tokenCustomStart + TokenType(i)
+		}
+	}
+	return TokenUnknown
+}
+
+//Returns true if the token type was declared in the [tokentypes] section of the config.
+func (this TokenType) IsCustom() bool {
+	_, ok := customType(this)
+	return ok
+}
+
+//Returns the regular expression of a token type declared in the config, the name is the
+//name of the type or of a tag of that type, so the exporters can match the tokens of the
+//types they have no parser for.
+func CustomTokenTypeRegex(name string) (string, bool) {
+	tt := customTypeByName(name)
+	if t, ok := config.tagIDs[name]; ok {
+		tt = t.TokenType()
+	}
+	if c, ok := customType(tt); ok {
+		return c.regex, true
+	}
+	return "", false
+}
+
+//Returns the length and type of the longest token at the start of the data that matches
+//one of the token types of the config, or 0 if none do. The token must end at the end of
+//the data, before a character that can not be in a literal or before a full stop that ends
+//the message or a sentence, and it can not run past the next tag position of a pattern.
+func scanCustomToken(data string, nt int) (int, TokenType) {
+	var (
+		best int
+		tt   = TokenUnknown
+	)
+	for i, c := range tokentypes.custom {
+		loc := c.re.FindStringIndex(data)
+		if loc == nil || loc[1] <= best || (nt > 0 && loc[1] > nt) {
+			continue
+		}
+		if l := loc[1]; l < len(data) {
+			r, _ := utf8.DecodeRuneInString(data[l:])
+			if isLiteral(r) && !(r == '.' && (l == len(data)-1 || data[l+1] == ' ')) {
+				continue
+			}
+		}
+		best, tt = loc[1], tokenCustomStart+TokenType(i)
+	}
+	return best, tt
+}
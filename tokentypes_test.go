@@ -0,0 +1,128 @@
+package sequence
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testTokenTypes = `
+[tokentypes.elapsed]
+    regex = '\d+(?:\.\d+)?(?:ns|us|ms|s|m|h)'
+[tokentypes.k8spod]
+    shape = "a+-x+-w+"
+`
+
+//Reads the config with the token types added and reads the plain config back after the test.
+func readConfigWithTokenTypes(t *testing.T, section string) {
+	data, err := ioutil.ReadFile("sequence.toml")
+	require.NoError(t, err)
+	fname := filepath.Join(t.TempDir(), "sequence.toml")
+	require.NoError(t, ioutil.WriteFile(fname, append(data, section...), 0644))
+	require.NoError(t, ReadConfig(fname))
+	t.Cleanup(func() { ReadConfig("sequence.toml") })
+}
+
+func TestShapeToRegex(t *testing.T) {
+	var tests = []struct {
+		shape string
+		match string
+		miss  string
+	}{
+		{"9+.9+.9+", "1.22.333", "1.2"},
+		{"xxxx-xxxx", "dead-BEEF", "dead-beeg"},
+		{"a+-w+", "web-x2kq9", "web-"},
+	}
+	for _, tc := range tests {
+		re := regexp.MustCompile("^" + shapeToRegex(tc.shape) + "$")
+		require.True(t, re.MatchString(tc.match), tc.shape)
+		require.False(t, re.MatchString(tc.miss), tc.shape)
+	}
+}
+
+func TestValidateTokenTypesConfig(t *testing.T) {
+	t.Cleanup(func() { ReadConfig("sequence.toml") })
+	type def = struct{ Regex, Shape string }
+	var tests = []map[string]def{
+		{"ipv4": {Regex: `x`}},
+		{"Elapsed": {Regex: `x`}},
+		{"elapsed": {}},
+		{"elapsed": {Regex: `x`, Shape: "9"}},
+		{"elapsed": {Regex: `(`}},
+		{"elapsed": {Regex: `\d*`}},
+	}
+	for _, tc := range tests {
+		require.Error(t, validateTokenTypesConfig(tc), "%v", tc)
+	}
+	require.NoError(t, validateTokenTypesConfig(map[string]def{"semver": {Shape: "9+.9+.9+"}, "elapsed": {Regex: `\d+ms`}}))
+	//the types are numbered in the order of their names
+	require.Equal(t, tokenCustomStart, name2TokenType("elapsed"))
+	require.Equal(t, "semver", (tokenCustomStart + 1).String())
+	require.True(t, name2TokenType("semver").IsCustom())
+	require.False(t, TokenIPv4.IsCustom())
+	require.Equal(t, int(tokenCustomStart)+2, TokenTypesCount)
+}
+
+func TestScanCustomTokenTypes(t *testing.T) {
+	readConfigWithTokenTypes(t, testTokenTypes+"[tokentypes.semver]\n    shape = \"9+.9+.9+\"\n")
+	seq, _, err := NewScanner().Scan("pod web-7d4b9c6f5-x2kq9 v1.2.3 (1.22.3) took 35ms.", false, nil)
+	require.NoError(t, err)
+	var types []string
+	for _, tok := range seq {
+		types = append(types, tok.Type.String()+"="+tok.Value)
+	}
+	require.Equal(t, []string{"literal=pod", "k8spod=web-7d4b9c6f5-x2kq9", "literal=v1.2.3", "literal=(", "semver=1.22.3",
+		"literal=)", "literal=took", "elapsed=35ms", "literal=."}, types)
+}
+
+func TestParseCustomTokenTypes(t *testing.T) {
+	readConfigWithTokenTypes(t, testTokenTypes)
+	scanner := NewScanner()
+	parser := NewParser()
+	for _, p := range []string{"pod %k8spod% took %elapsed%", "job %string% done"} {
+		seq, _, err := scanner.Scan(p, true, nil)
+		require.NoError(t, err)
+		require.NoError(t, parser.Add(seq))
+	}
+	seq, _, err := scanner.Scan("pod web-7d4b9c6f5-x2kq9 took 120ms", false, nil)
+	require.NoError(t, err)
+	pseq, err := parser.Parse(seq)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"k8spod": "web-7d4b9c6f5-x2kq9", "elapsed": "120ms"}, pseq.Fields())
+
+	//the pattern with a string saved before the type was declared still matches
+	seq, _, err = scanner.Scan("job 35ms done", false, nil)
+	require.NoError(t, err)
+	pseq, err = parser.Parse(seq)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"string": "35ms"}, pseq.Fields())
+
+	rg, err := PatternToRegex("pod %k8spod% took %elapsed%", "")
+	require.NoError(t, err)
+	require.Regexp(t, rg, "pod web-7d4b9c6f5-x2kq9 took 1.5s")
+	require.NotRegexp(t, rg, "pod web-7d4b9c6f5-x2kq9 took long")
+}
+
+func TestAnalyzeCustomTokenTypes(t *testing.T) {
+	readConfigWithTokenTypes(t, testTokenTypes)
+	atree := NewAnalyzer()
+	scanner := NewScanner()
+	msgs := []string{"pod web-7d4b9c6f5-x2kq9 took 35ms", "pod api-5f6c7d8e9-q8zz1 took 1.5s"}
+	for _, msg := range msgs {
+		seq, _, err := scanner.Scan(msg, false, nil)
+		require.NoError(t, err)
+		require.NoError(t, atree.Add(seq))
+	}
+	require.NoError(t, atree.Finalize())
+	for _, msg := range msgs {
+		seq, _, err := scanner.Scan(msg, false, nil)
+		require.NoError(t, err)
+		seq, err = atree.Analyze(seq)
+		require.NoError(t, err)
+		p, _ := seq.String()
+		require.Equal(t, "pod %k8spod% took %elapsed%", p)
+	}
+}
@@ -1,6 +1,8 @@
 package sequence
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -93,25 +95,28 @@ func BuildParser(patfile string) *Parser {
 	return parser
 }
 
+//Builds the parser for the service from the patterns in the database. The parser is
+//cached in-process and, if parsersnapshotpath is set in the config, saved to disk for
+//the current revision of the service's patterns, so it is only rebuilt when they change.
 func BuildParserFromDb(serviceid string) *Parser {
-	parser := NewParser()
-	scanner := NewScanner()
-	db, ctx := OpenDbandSetContext()
-	defer db.Close()
-	//load all patterns from the database
-	pmap := GetPatternsFromDatabaseByService(db, ctx, serviceid)
-	for _, ar := range pmap {
-		pos := SplitToInt(ar.TagPositions, ",")
-		seq, _, err := scanner.Scan(ar.Pattern, true, pos)
-		if err != nil {
-			logger.HandleError(fmt.Sprintf("%s, Service: %s, Pattern: %s", err.Error(), ar.Service.Name, ar.PatternId))
-		}
+	return getCachedParser(serviceid, func(db *sql.DB, ctx context.Context) *Parser {
+		parser := NewParser()
+		scanner := NewScanner()
+		//load all patterns from the database
+		pmap := GetPatternsFromDatabaseByService(db, ctx, serviceid)
+		for _, ar := range pmap {
+			pos := SplitToInt(ar.TagPositions, ",")
+			seq, _, err := scanner.Scan(ar.Pattern, true, pos)
+			if err != nil {
+				logger.HandleError(fmt.Sprintf("%s, Service: %s, Pattern: %s", err.Error(), ar.Service.Name, ar.PatternId))
+			}
 
-		if err := parser.Add(seq); err != nil {
-			logger.HandleError(fmt.Sprintf("%s, Service: %s, Pattern: %s", err.Error(), ar.Service.Name, ar.PatternId))
+			if err := parser.Add(seq); err != nil {
+				logger.HandleError(fmt.Sprintf("%s, Service: %s, Pattern: %s", err.Error(), ar.Service.Name, ar.PatternId))
+			}
 		}
-	}
-	return parser
+		return parser
+	})
 }
 
 //Calculate the threshold value to use when exporting patterns from the database.
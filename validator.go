@@ -28,20 +28,20 @@ func ValidateOutformat(outformat string) string {
 	//open the output files for saving data and add any headers
 	for _, fmat := range outformats {
 		if (fmat != "xml") && (fmat != "yaml") && (fmat != "txt") {
-			return "Valid values for out format are: xml,yaml or xml or yaml (for patterndb) or txt (for grok)"
+			return "Valid values for out format are: xml,yaml or xml or yaml (for patterndb) or txt (for grok, lognorm, ingest, fluentbit or vector)"
 		}
 	}
 	return ""
 }
 
 func ValidateOutsystem(outsystem string) string {
-	if (outsystem == "patterndb") || (outsystem == "grok") {
+	if (outsystem == "patterndb") || (outsystem == "grok") || (outsystem == "lognorm") || (outsystem == "ingest") || (outsystem == "fluentbit") || (outsystem == "vector") {
 		return ""
 	}
 	if outsystem == "" {
-		return "Output system is required for this method, please select either patterndb or grok"
+		return "Output system is required for this method, please select patterndb, grok, lognorm, ingest, fluentbit or vector"
 	}
-	return outsystem + " is not a supported out system type, please select either patterndb or grok"
+	return outsystem + " is not a supported out system type, please select patterndb, grok, lognorm, ingest, fluentbit or vector"
 }
 
 //
@@ -68,6 +68,13 @@ func ValidateBatchSize(batchsize int) string {
 	return ""
 }
 
+func ValidateWorkers(workers int) string {
+	if workers < 1 {
+		return "The number of workers must be one or greater"
+	}
+	return ""
+}
+
 func ValidateLogLevel(lvl string) string {
 	if len(lvl) > 0 {
 		switch lvl {
@@ -91,6 +98,21 @@ func ValidateType(dbtype string) string {
 	}
 }
 
+func ValidateDatabaseName(name string) string {
+	if databaseNameRegex.MatchString(name) {
+		return ""
+	}
+	return "Database names must start with a letter and only have letters, digits and underscores, up to 63 characters. Please adjust the name"
+}
+
+func ValidateBundleFormat(format string) string {
+	switch format {
+	case "json", "yaml":
+		return ""
+	}
+	return "Valid values for the bundle format are: json or yaml. Please use one of these values"
+}
+
 func ValidateThresholdType(thresholdType string) string {
 	switch thresholdType {
 	case "count", "percent":
@@ -130,3 +152,17 @@ func ValidateThresholdValue(thresholdType string, thresholdValue string) string
 	}
 	return ""
 }
+
+func ValidateReviewState(state string) string {
+	if _, ok := reviewTransitions[state]; ok {
+		return ""
+	}
+	return "Valid values for the review state are: new, review, approved, deprecated or ignored. Please use one of these values"
+}
+
+func ValidateFieldName(name string) string {
+	if fieldNameRegex.MatchString(name) {
+		return ""
+	}
+	return "Field names must start with a letter or underscore and only have letters, digits, underscores and dots. Please adjust the name"
+}
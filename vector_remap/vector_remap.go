@@ -0,0 +1,153 @@
+// This package is solely for the transformation and the output to file of sequence patterns found in server logs
+// as a Vector Remap Language (VRL) program. The program is used as the source of a remap transform, it routes each
+// event by its service and tries the patterns for that service with parse_regex until one matches, the captures are
+// merged into the event along with the id of the matching pattern.
+// The regular expressions are built from the pattern tokens, with a named capture for each tag, and need to be
+// reviewed before use in production, as with the other exporters.
+package vector_remap
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+)
+
+var (
+	settings struct {
+		serviceField   string
+		messageField   string
+		patternIdField string
+	}
+	logger *sequence.StandardLogger
+)
+
+// Allows the user to set the logger to a global instance.
+func SetLogger(log *sequence.StandardLogger) {
+	logger = log
+}
+
+func readConfig(file string) error {
+	var configInfo struct {
+		Vector struct {
+			ServiceField   string
+			MessageField   string
+			PatternIdField string
+		}
+	}
+	if _, err := toml.DecodeFile(file, &configInfo); err != nil {
+		return err
+	}
+
+	settings.serviceField = configInfo.Vector.ServiceField
+	settings.messageField = configInfo.Vector.MessageField
+	settings.patternIdField = configInfo.Vector.PatternIdField
+	if settings.serviceField == "" {
+		settings.serviceField = "service"
+	}
+	if settings.messageField == "" {
+		settings.messageField = "message"
+	}
+	if settings.patternIdField == "" {
+		settings.patternIdField = "sequence_pattern_id"
+	}
+	return nil
+}
+
+// This function takes patterns created by the sequence module and outputs them as a VRL program.
+// The user can pass the pattern map if no database is used or pass the map created during the analysis.
+func OutputToFiles(outfile string, config string, complexitylevel float64, cmap map[string]sequence.AnalyzerResult, thresholdType string, thresholdValue string) (int, string, error) {
+	var (
+		err    error
+		count  int
+		top5   string
+		patmap map[string]sequence.AnalyzerResult
+	)
+
+	if config == "" {
+		config = "./sequence.toml"
+	}
+	//read the config to load the field names
+	if err = readConfig(config); err != nil {
+		return count, top5, err
+	}
+	if sequence.GetUseDatabase() && cmap == nil {
+		db, ctx := sequence.OpenDbandSetContext()
+		defer db.Close()
+		//get from the config instead
+		if thresholdType == "" {
+			thresholdType = sequence.GetThresholdType()
+			thresholdValue = sequence.GetThresholdValue()
+		}
+		patmap, top5 = sequence.GetPatternsWithExamplesFromDatabase(db, ctx, complexitylevel, thresholdType, thresholdValue)
+	} else {
+		patmap = cmap
+	}
+	logger.HandleInfo(fmt.Sprintf("Found %d patterns for output", len(patmap)))
+	count = len(patmap)
+	//open the file for the program output
+	vrlFile, err := sequence.OpenOutputFile(outfile)
+	if err != nil {
+		return count, top5, err
+	}
+	defer vrlFile.Close()
+	writeProgram(vrlFile, patmap)
+	return count, top5, nil
+}
+
+// Writes the program, the services are in name order and within a service the
+// patterns with fewer tags are tried first, as they are more specific.
+func writeProgram(w io.Writer, patmap map[string]sequence.AnalyzerResult) {
+	services := make(map[string][]sequence.AnalyzerResult)
+	for _, result := range patmap {
+		services[result.Service.Name] = append(services[result.Service.Name], result)
+	}
+	var names []string
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "# Generated by sequence, use as the source of a remap transform.\n")
+	fmt.Fprintf(w, "sequence_message = string(.%s) ?? \"\"\n", settings.messageField)
+	fmt.Fprintf(w, "sequence_matched = false\n")
+	for _, name := range names {
+		results := services[name]
+		sort.Slice(results, func(i, j int) bool {
+			if results[i].ComplexityScore != results[j].ComplexityScore {
+				return results[i].ComplexityScore < results[j].ComplexityScore
+			}
+			return results[i].Pattern < results[j].Pattern
+		})
+		fmt.Fprintf(w, "\nif .%s == %s {\n", settings.serviceField, strconv.Quote(name))
+		for _, result := range results {
+			rg, err := sequence.PatternToRegex(result.Pattern, result.TagPositions)
+			if err != nil {
+				logger.HandleError(fmt.Sprintf("Unable to export pattern %s: %s", result.PatternId, err.Error()))
+				continue
+			}
+			fmt.Fprintf(w, "    # %s\n    # %d log messages matched\n", result.PatternId, result.ExampleCount)
+			if len(result.Examples) > 0 {
+				fmt.Fprintf(w, "    # %s\n", strings.Replace(result.Examples[0].Message, "\n", " ", -1))
+			}
+			fmt.Fprintf(w, "    if !sequence_matched {\n")
+			fmt.Fprintf(w, "        parsed, err = parse_regex(sequence_message, %s)\n", rawString(rg))
+			fmt.Fprintf(w, "        if err == null {\n")
+			fmt.Fprintf(w, "            . = merge(., parsed)\n")
+			fmt.Fprintf(w, "            .%s = %s\n", settings.patternIdField, strconv.Quote(result.PatternId))
+			fmt.Fprintf(w, "            sequence_matched = true\n")
+			fmt.Fprintf(w, "        }\n    }\n")
+		}
+		fmt.Fprintf(w, "}\n")
+	}
+}
+
+// Returns the regular expression as a VRL raw string, only single quotes need
+// to be escaped.
+func rawString(rg string) string {
+	return "r'" + strings.Replace(rg, "'", "\\'", -1) + "'"
+}
@@ -0,0 +1,76 @@
+package vector_remap
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ryanfaircloth/sequence-RTG/sequence"
+	"github.com/stretchr/testify/require"
+)
+
+func loadConfigs() {
+	file := "../sequence.toml"
+	sequence.ReadConfig(file)
+	readConfig(file)
+}
+
+func TestWriteProgram(t *testing.T) {
+	loadConfigs()
+	patmap := make(map[string]sequence.AnalyzerResult)
+	for _, p := range []struct {
+		svc, id, pattern, pos string
+		score                 float64
+	}{
+		{"sshd", "b", "%srcuser% logged in from %srcip%", "0,25", 0.5},
+		{"sshd", "a", "connection closed", "", 0},
+		{"app's", "c", "user's %srcuser%", "7", 0.5},
+	} {
+		ar := sequence.AnalyzerResult{PatternId: p.id, Pattern: p.pattern, TagPositions: p.pos, ExampleCount: 1, ComplexityScore: p.score}
+		ar.Service.Name = p.svc
+		patmap[p.pattern] = ar
+	}
+
+	var buf bytes.Buffer
+	writeProgram(&buf, patmap)
+	expected := `# Generated by sequence, use as the source of a remap transform.
+sequence_message = string(.message) ?? ""
+sequence_matched = false
+
+if .service == "app's" {
+    # c
+    # 1 log messages matched
+    if !sequence_matched {
+        parsed, err = parse_regex(sequence_message, r'^user\'s\s+(?P<srcuser>\S+?)$')
+        if err == null {
+            . = merge(., parsed)
+            .sequence_pattern_id = "c"
+            sequence_matched = true
+        }
+    }
+}
+
+if .service == "sshd" {
+    # a
+    # 1 log messages matched
+    if !sequence_matched {
+        parsed, err = parse_regex(sequence_message, r'^connection\s+closed$')
+        if err == null {
+            . = merge(., parsed)
+            .sequence_pattern_id = "a"
+            sequence_matched = true
+        }
+    }
+    # b
+    # 1 log messages matched
+    if !sequence_matched {
+        parsed, err = parse_regex(sequence_message, r'^(?P<srcuser>\S+?)\s+logged\s+in\s+from\s+(?P<srcip>\d{1,3}(?:\.\d{1,3}){3})$')
+        if err == null {
+            . = merge(., parsed)
+            .sequence_pattern_id = "b"
+            sequence_matched = true
+        }
+    }
+}
+`
+	require.Equal(t, expected, buf.String())
+}